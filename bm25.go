@@ -0,0 +1,115 @@
+package semanticrouter
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	// bm25K1 is the term frequency saturation of BM25.
+	bm25K1 = 1.2
+	// bm25B is the document length normalization of BM25.
+	bm25B = 0.75
+)
+
+// bm25Token matches the tokens of a text: runs of letters and digits, joined
+// by hyphens, underscores, dots or slashes so that codes such as SKU-1234-B
+// are kept whole.
+var bm25Token = regexp.MustCompile(`[\p{L}\p{N}]+(?:[-_./][\p{L}\p{N}]+)*`)
+
+// bm25Terms returns the lowercased tokens of the given text, followed by the
+// parts of the tokens joined by punctuation.
+func bm25Terms(text string) []string {
+	var terms []string
+	for _, token := range bm25Token.FindAllString(strings.ToLower(text), -1) {
+		terms = append(terms, token)
+		parts := strings.FieldsFunc(token, func(r rune) bool {
+			return strings.ContainsRune("-_./", r)
+		})
+		if len(parts) > 1 {
+			terms = append(terms, parts...)
+		}
+	}
+	return terms
+}
+
+// bm25Index is a BM25 index of the utterances of an index's entries.
+type bm25Index struct {
+	frequencies []map[string]int // frequencies holds the term frequencies of each document.
+	lengths     []int            // lengths holds the number of terms of each document.
+	documents   map[string]int   // documents holds the number of documents of each term.
+	average     float64          // average is the average number of terms of the documents.
+}
+
+// newBM25Index indexes the given documents.
+func newBM25Index(documents []string) *bm25Index {
+	idx := &bm25Index{
+		frequencies: make([]map[string]int, len(documents)),
+		lengths:     make([]int, len(documents)),
+		documents:   make(map[string]int),
+	}
+	total := 0
+	for i, document := range documents {
+		terms := bm25Terms(document)
+		idx.frequencies[i] = make(map[string]int, len(terms))
+		for _, term := range terms {
+			if idx.frequencies[i][term] == 0 {
+				idx.documents[term]++
+			}
+			idx.frequencies[i][term]++
+		}
+		idx.lengths[i] = len(terms)
+		total += len(terms)
+	}
+	if len(documents) > 0 {
+		idx.average = float64(total) / float64(len(documents))
+	}
+	return idx
+}
+
+// idf returns the inverse document frequency of the given term.
+func (idx *bm25Index) idf(term string) float64 {
+	n := float64(len(idx.lengths))
+	df := float64(idx.documents[term])
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// scores returns the BM25 score of each document for the given query,
+// normalized by the sum of the inverse document frequencies of the query's
+// terms and capped at 1, so that a document of average length containing
+// every term of the query once scores 1. Terms of the query that no document
+// contains are left out of the normalization, since no document can match
+// them.
+func (idx *bm25Index) scores(query string) []float64 {
+	scores := make([]float64, len(idx.lengths))
+	seen := make(map[string]bool)
+	norm := 0.0
+	for _, term := range bm25Terms(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		if idx.documents[term] == 0 {
+			continue
+		}
+		idf := idx.idf(term)
+		norm += idf
+		for i, frequencies := range idx.frequencies {
+			tf := float64(frequencies[term])
+			if tf == 0 {
+				continue
+			}
+			length := float64(idx.lengths[i]) / max(idx.average, 1)
+			scores[i] += idf * tf * (bm25K1 + 1) /
+				(tf + bm25K1*(1-bm25B+bm25B*length))
+		}
+	}
+	if norm == 0 {
+		return scores
+	}
+	for i := range scores {
+		scores[i] = min(scores[i]/norm, 1)
+	}
+	return scores
+}
@@ -0,0 +1,27 @@
+package semanticrouter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBM25Scores tests that the BM25 scores are normalized over the terms of
+// the query that are in the corpus.
+func TestBM25Scores(t *testing.T) {
+	a := assert.New(t)
+	idx := newBM25Index([]string{
+		"I want to return item SKU-4411-B",
+		"refund my purchase",
+		"where is my package",
+	})
+
+	scores := idx.scores("refund purchase")
+	a.Greater(scores[1], 0.9)
+	a.Zero(scores[0])
+	a.Zero(scores[2])
+
+	a.Equal(scores, idx.scores("refund purchase xylophone"),
+		"unknown terms do not lower the scores")
+	a.Equal(make([]float64, 3), idx.scores("xylophone"))
+}
@@ -0,0 +1,145 @@
+package semanticrouter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Classifier chooses the route of an utterance when the router is not
+// confident in its semantic match, typically by prompting an LLM with the
+// routes and examples of their utterances.
+type Classifier interface {
+	// Classify returns the name of the route of the given utterance among
+	// the given routes, or an empty name if none fits.
+	Classify(
+		ctx context.Context,
+		utterance string,
+		routes []Route,
+	) (string, error)
+}
+
+// WithClassifier consults the given classifier when the best score of an
+// utterance matched by Match, Resolve or Dispatch is below the given
+// threshold, including when no route matches. The score stays the semantic
+// score.
+//
+// The semantic match is kept for confident scores, so that the classifier
+// only handles the uncertain cases. Utterances matched by a rule are not
+// classified, nor are those matched by MatchPath, MatchAll or
+// MatchConversation.
+func WithClassifier(classifier Classifier, threshold float64) Option {
+	return func(r *Router) {
+		r.classifier = classifier
+		r.uncertain = threshold
+	}
+}
+
+// classify replaces the route of the given result with the choice of the
+// router's classifier if the result is uncertain.
+func (r *Router) classify(ctx context.Context, result *MatchResult) error {
+	if r.classifier == nil || (result.Route != nil && result.Score >= r.uncertain) {
+		return nil
+	}
+	routes := r.Routes
+	if filterFrom(ctx) != nil {
+		routes = nil
+		for i := range r.Routes {
+			if accepts(ctx, &r.Routes[i], nil) {
+				routes = append(routes, r.Routes[i])
+			}
+		}
+	}
+	name, err := r.classifier.Classify(ctx, result.Utterance, routes)
+	if err != nil {
+		return fmt.Errorf("error classifying utterance: %w", err)
+	}
+	result.Classified = true
+	if name == "" {
+		result.Route = nil
+		return nil
+	}
+	for i := range r.Routes {
+		if r.Routes[i].Name == name && accepts(ctx, &r.Routes[i], nil) {
+			result.Route = &r.Routes[i]
+			return nil
+		}
+	}
+	return fmt.Errorf("error classifying utterance: unknown route %s", name)
+}
+
+// classifierExamples is the number of utterances of each route given as
+// examples to the LLM of an LLMClassifier.
+const classifierExamples = 3
+
+// NoRoute is the answer of the LLM of an LLMClassifier when no route fits
+// the utterance.
+const NoRoute = "none"
+
+// LLMClassifier is a Classifier asking an LLM to extract the name of the
+// route of an utterance as the argument of a function listing the routes,
+// their descriptions and examples of their utterances.
+//
+// The LLM can answer NoRoute, or no route at all, when no route fits, so a
+// route named NoRoute cannot be chosen.
+type LLMClassifier struct {
+	LLM LLM // LLM is the LLM choosing the route.
+}
+
+// NewLLMClassifier creates a new LLMClassifier asking the given LLM.
+func NewLLMClassifier(llm LLM) *LLMClassifier {
+	return &LLMClassifier{LLM: llm}
+}
+
+// Classify asks the classifier's LLM for the route of the given utterance.
+func (c *LLMClassifier) Classify(
+	ctx context.Context,
+	utterance string,
+	routes []Route,
+) (string, error) {
+	names := make([]any, 0, len(routes)+1)
+	var description strings.Builder
+	fmt.Fprintf(
+		&description,
+		"The route of the utterance, or %s if none of the routes fits it. The routes are:",
+		NoRoute,
+	)
+	for _, route := range routes {
+		names = append(names, route.Name)
+		fmt.Fprintf(&description, "\n\n%s", route.Name)
+		if route.Description != "" {
+			fmt.Fprintf(&description, ": %s", route.Description)
+		}
+		for _, utter := range route.Utterances[:min(classifierExamples, len(route.Utterances))] {
+			fmt.Fprintf(&description, "\nExample: %s", utter.Utterance)
+		}
+	}
+	names = append(names, NoRoute)
+	function := Function{
+		Name:        "choose_route",
+		Description: "Choose the route of an utterance by the descriptions and examples of the routes.",
+		Parameters: Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"route": {
+					Type:        "string",
+					Description: description.String(),
+					Enum:        names,
+				},
+			},
+		},
+	}
+	args, err := c.LLM.Arguments(ctx, utterance, function)
+	if err != nil {
+		return "", err
+	}
+	err = function.Parameters.Validate(args)
+	if err != nil {
+		return "", err
+	}
+	name, _ := args["route"].(string)
+	if name == NoRoute {
+		return "", nil
+	}
+	return name, nil
+}
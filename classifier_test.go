@@ -0,0 +1,134 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ semanticrouter.Classifier = (*semanticrouter.LLMClassifier)(nil)
+
+// fakeClassifier is a Classifier choosing a fixed route.
+type fakeClassifier struct {
+	name       string
+	utterances []string
+}
+
+// Classify returns the fixed route name.
+func (c *fakeClassifier) Classify(
+	_ context.Context,
+	utterance string,
+	_ []semanticrouter.Route,
+) (string, error) {
+	c.utterances = append(c.utterances, utterance)
+	return c.name, nil
+}
+
+// TestClassifier tests consulting the classifier for uncertain matches only.
+func TestClassifier(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	classifier := &fakeClassifier{name: "chitchat"}
+	router := newTestRouter(t,
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		semanticrouter.WithClassifier(classifier, 0.5),
+	)
+
+	res, err := router.Resolve(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	a.Equal("noteworthy", res.Route.Name)
+	a.False(res.Classified)
+
+	res, err = router.Resolve(ctx, "how do I file my taxes?")
+	require.NoError(t, err)
+	a.Equal("chitchat", res.Route.Name)
+	a.True(res.Classified)
+	a.Equal([]string{"how do I file my taxes?"}, classifier.utterances)
+
+	res, err = newTestRouter(t,
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		semanticrouter.WithClassifier(&fakeClassifier{}, 1.5),
+	).Resolve(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	a.Nil(res.Route)
+	a.True(res.Classified)
+	a.Greater(res.Score, 0.9)
+
+	_, err = newTestRouter(t,
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		semanticrouter.WithClassifier(&fakeClassifier{name: "taxes"}, 0.5),
+	).Resolve(ctx, "how do I file my taxes?")
+	a.ErrorContains(err, "unknown route taxes")
+
+	route, score, err := router.Match(ctx, "how do I file my taxes?")
+	require.NoError(t, err)
+	require.NotNil(t, route)
+	a.Equal("chitchat", route.Name)
+	a.Less(score, 0.5)
+}
+
+// TestLLMClassifierPrompt tests that the LLM of an LLMClassifier is given
+// the descriptions and examples of the routes, and can choose none of them.
+func TestLLMClassifierPrompt(t *testing.T) {
+	a := assert.New(t)
+	noteworthy := NoteworthyRoutes
+	noteworthy.Description = "questions about the health of pets"
+	llm := &fixedLLM{args: map[string]any{"route": "noteworthy"}}
+	_, err := semanticrouter.NewLLMClassifier(llm).Classify(
+		context.Background(),
+		"my parrot stopped talking",
+		[]semanticrouter.Route{noteworthy, ChitchatRoutes},
+	)
+	require.NoError(t, err)
+	route := llm.function.Parameters.Properties["route"]
+	require.NotNil(t, route)
+	a.Equal([]any{"noteworthy", "chitchat", semanticrouter.NoRoute}, route.Enum)
+	a.Contains(route.Description, "noteworthy: questions about the health of pets")
+	a.Contains(route.Description, "Example: my cat has been limping, what should I do?")
+	a.Contains(route.Description, "\n\nchitchat\nExample: what is your favorite color?")
+	a.Contains(route.Description, "or none if none of the routes fits it")
+}
+
+// TestLLMClassifier tests choosing routes with an LLM.
+func TestLLMClassifier(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	routes := []semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes}
+	name, err := semanticrouter.NewLLMClassifier(&fixedLLM{
+		args: map[string]any{"route": "noteworthy"},
+	}).Classify(ctx, "my parrot stopped talking", routes)
+	require.NoError(t, err)
+	a.Equal("noteworthy", name)
+
+	name, err = semanticrouter.NewLLMClassifier(&fixedLLM{
+		args: map[string]any{},
+	}).Classify(ctx, "how do I file my taxes?", routes)
+	require.NoError(t, err)
+	a.Empty(name)
+
+	name, err = semanticrouter.NewLLMClassifier(&fixedLLM{
+		args: map[string]any{"route": semanticrouter.NoRoute},
+	}).Classify(ctx, "how do I file my taxes?", routes)
+	require.NoError(t, err)
+	a.Empty(name)
+
+	_, err = semanticrouter.NewLLMClassifier(&fixedLLM{
+		args: map[string]any{"route": "taxes"},
+	}).Classify(ctx, "how do I file my taxes?", routes)
+	a.Error(err)
+
+	classifier := semanticrouter.NewLLMClassifier(&fixedLLM{
+		args: map[string]any{"route": "noteworthy"},
+	})
+	router := newTestRouter(t,
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		semanticrouter.WithClassifier(classifier, 0.5),
+	)
+	out, err := router.Resolve(ctx, "how do I file my taxes?")
+	require.NoError(t, err)
+	a.Equal("noteworthy", out.Route.Name)
+	a.True(out.Classified)
+}
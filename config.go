@@ -0,0 +1,777 @@
+package semanticrouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of a configuration file.
+type Format int
+
+const (
+	// YAML is the YAML format, of files with the .yaml or .yml extension.
+	YAML Format = iota
+	// JSON is the JSON format, of files with the .json extension.
+	JSON
+	// TOML is the TOML format, of files with the .toml extension.
+	TOML
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case YAML:
+		return "yaml"
+	case JSON:
+		return "json"
+	case TOML:
+		return "toml"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// FormatOf returns the format of the file at the given path, by its
+// extension.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".json":
+		return JSON, nil
+	case ".toml":
+		return TOML, nil
+	default:
+		return 0, fmt.Errorf("unknown configuration format of %s", path)
+	}
+}
+
+// similarityOptions holds the option of each similarity function, by its
+// name in configurations.
+var similarityOptions = map[string]func(coefficient float64) Option{
+	"dot_matrix":  WithSimilarityDotMatrix,
+	"dot_product": WithDotProduct,
+	"euclidean":   WithEuclideanDistance,
+	"manhattan":   WithManhattanDistance,
+	"jaccard":     WithJaccardSimilarity,
+	"pearson":     WithPearsonCorrelation,
+	"bm25":        WithBM25,
+}
+
+// Config is the declarative configuration of a router: its routes, the
+// weights of its similarity functions, and the selection of its encoder and
+// store.
+//
+// It is read from YAML, JSON or TOML files by LoadConfig, and written by
+// Config.Marshal. The similarity functions are named dot_matrix,
+// dot_product, euclidean, manhattan, jaccard, pearson and bm25; all but
+// bm25 and dot_product weigh 1 if none is given, as with NewRouter.
+type Config struct {
+	// Encoder selects the encoder of the router, see Factories.
+	Encoder *ComponentConfig `json:"encoder,omitempty" yaml:"encoder,omitempty"`
+	// Store selects the store of the router, see Factories.
+	Store *ComponentConfig `json:"store,omitempty" yaml:"store,omitempty"`
+	// Similarity holds the weight of each similarity function, by name.
+	Similarity map[string]float64 `json:"similarity,omitempty" yaml:"similarity,omitempty"`
+	// Precision is the precision of the router, float32 or float64, float64
+	// if empty.
+	Precision string `json:"precision,omitempty" yaml:"precision,omitempty"`
+	// Workers is the number of workers of the router, see WithWorkers.
+	Workers int `json:"workers,omitempty" yaml:"workers,omitempty"`
+	// AmbiguityMargin is the ambiguity margin of the router, see
+	// WithAmbiguityMargin.
+	AmbiguityMargin float64 `json:"ambiguity_margin,omitempty" yaml:"ambiguity_margin,omitempty"`
+	// Routes are the routes of the router.
+	Routes []RouteConfig `json:"routes" yaml:"routes"`
+
+	file      string              // file is the path of the file the configuration was read from.
+	positions map[string]position // positions holds the position of each value read, by path.
+}
+
+// ComponentConfig selects an encoder or a store by type, with the options
+// given to its factory.
+type ComponentConfig struct {
+	Type    string         `json:"type" yaml:"type"`                           // Type is the type of the component.
+	Options map[string]any `json:"options,omitempty" yaml:"options,omitempty"` // Options are the options of the component.
+}
+
+// RouteConfig is the configuration of a route, see Route.
+type RouteConfig struct {
+	Name               string            `json:"name" yaml:"name"`
+	Description        string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Threshold          float64           `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	Priority           int               `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Tags               []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Metadata           map[string]any    `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Utterances         []UtteranceConfig `json:"utterances,omitempty" yaml:"utterances,omitempty"`
+	NegativeUtterances []UtteranceConfig `json:"negative_utterances,omitempty" yaml:"negative_utterances,omitempty"`
+	Rules              []RuleConfig      `json:"rules,omitempty" yaml:"rules,omitempty"`
+	Children           []RouteConfig     `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// UtteranceConfig is the configuration of an utterance, see Utterance. An
+// utterance with only a text can be written as a string.
+type UtteranceConfig struct {
+	Utterance   string         `json:"utterance" yaml:"utterance"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Tags        []string       `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// utteranceConfig is an UtteranceConfig marshaled as an object.
+type utteranceConfig UtteranceConfig
+
+// short reports whether the utterance only has a text.
+func (u UtteranceConfig) short() bool {
+	return u.Description == "" && len(u.Tags) == 0 && len(u.Metadata) == 0
+}
+
+// MarshalJSON marshals the utterance as a string if it only has a text.
+func (u UtteranceConfig) MarshalJSON() ([]byte, error) {
+	if u.short() {
+		return json.Marshal(u.Utterance)
+	}
+	return json.Marshal(utteranceConfig(u))
+}
+
+// MarshalYAML marshals the utterance as a string if it only has a text.
+func (u UtteranceConfig) MarshalYAML() (any, error) {
+	if u.short() {
+		return u.Utterance, nil
+	}
+	return utteranceConfig(u), nil
+}
+
+// RuleConfig is the configuration of a rule, see Rule. Predicates cannot be
+// configured.
+type RuleConfig struct {
+	Name     string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Phrases  []string `json:"phrases,omitempty" yaml:"phrases,omitempty"`
+	Keywords []string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+	Pattern  string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+}
+
+// position is the position of a value in a configuration file.
+type position struct {
+	line   int
+	column int
+}
+
+// LoadConfig reads and validates the configuration in the file at the given
+// path, in the format of its extension.
+//
+// The errors are ErrConfig values, joined, holding the line and column of
+// each error.
+func LoadConfig(path string) (*Config, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading configuration: %w", err)
+	}
+	return parseConfig(data, format, path)
+}
+
+// ParseConfig parses and validates the given configuration in the given
+// format, see LoadConfig.
+func ParseConfig(data []byte, format Format) (*Config, error) {
+	return parseConfig(data, format, "")
+}
+
+// parseConfig parses and validates the given configuration, read from the
+// given file.
+func parseConfig(data []byte, format Format, file string) (*Config, error) {
+	var root *yaml.Node
+	switch format {
+	case YAML, JSON:
+		if format == JSON {
+			err := checkJSON(data, file)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var doc yaml.Node
+		err := yaml.Unmarshal(data, &doc)
+		if err != nil {
+			return nil, yamlError(err, file)
+		}
+		if len(doc.Content) > 0 {
+			root = doc.Content[0]
+		}
+	case TOML:
+		var err error
+		root, err = parseTOML(data, file)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown configuration format: %s", format)
+	}
+	c := &Config{file: file, positions: make(map[string]position)}
+	if root != nil {
+		d := &configDecoder{config: c}
+		d.decode(root, reflect.ValueOf(c).Elem(), "")
+		if len(d.errs) > 0 {
+			return nil, errors.Join(d.errs...)
+		}
+	}
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkJSON returns an ErrConfig at the position of the syntax error of the
+// given JSON document, if any.
+func checkJSON(data []byte, file string) error {
+	var v any
+	err := json.Unmarshal(data, &v)
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		return nil
+	}
+	// The offset is past the invalid character.
+	offset := int(min(max(syntax.Offset-1, 0), int64(len(data))))
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return ErrConfig{Message: syntax.Error(), File: file, Line: line, Column: column}
+}
+
+// yamlLine matches the line of the errors of the YAML parser.
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlError returns the given error of the YAML parser as an ErrConfig.
+func yamlError(err error, file string) error {
+	match := yamlLine.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ErrConfig{Message: strings.TrimPrefix(err.Error(), "yaml: "), File: file}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return ErrConfig{Message: match[2], File: file, Line: line}
+}
+
+// configDecoder decodes the nodes of a configuration file into a Config,
+// recording the position of each value and the errors.
+type configDecoder struct {
+	config *Config
+	errs   []error
+}
+
+// errorf records an error at the given node and path.
+func (d *configDecoder) errorf(node *yaml.Node, path, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if path != "" {
+		message = path + ": " + message
+	}
+	d.errs = append(d.errs, ErrConfig{
+		Message: message,
+		File:    d.config.file,
+		Line:    node.Line,
+		Column:  node.Column,
+	})
+}
+
+// decode decodes the given node into the given value.
+func (d *configDecoder) decode(node *yaml.Node, v reflect.Value, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	d.config.positions[path] = position{line: node.Line, column: node.Column}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	if v.Type() == reflect.TypeOf(UtteranceConfig{}) && node.Kind == yaml.ScalarNode {
+		d.decode(node, v.FieldByName("Utterance"), path)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		d.decode(node, v.Elem(), path)
+	case reflect.Struct:
+		d.decodeStruct(node, v, path)
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			d.errorf(node, path, "expected a list")
+			return
+		}
+		v.Set(reflect.MakeSlice(v.Type(), len(node.Content), len(node.Content)))
+		for i, item := range node.Content {
+			d.decode(item, v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		d.decodeMap(node, v, path)
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			d.errorf(node, path, "expected a string")
+			return
+		}
+		v.SetString(node.Value)
+	case reflect.Float64:
+		var f float64
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") ||
+			node.Decode(&f) != nil {
+			d.errorf(node, path, "expected a number")
+			return
+		}
+		v.SetFloat(f)
+	case reflect.Int:
+		var n int
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" || node.Decode(&n) != nil {
+			d.errorf(node, path, "expected an integer")
+			return
+		}
+		v.SetInt(int64(n))
+	default:
+		err := node.Decode(v.Addr().Interface())
+		if err != nil {
+			d.errorf(node, path, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+	}
+}
+
+// decodeStruct decodes the given mapping node into the given struct, by the
+// YAML names of its fields.
+func (d *configDecoder) decodeStruct(node *yaml.Node, v reflect.Value, path string) {
+	if node.Kind != yaml.MappingNode {
+		d.errorf(node, path, "expected a mapping")
+		return
+	}
+	fields := make(map[string]int)
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if field.IsExported() && name != "" {
+			fields[name] = i
+		}
+	}
+	seen := make(map[string]int)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		child := key.Value
+		if path != "" {
+			child = path + "." + key.Value
+		}
+		field, ok := fields[key.Value]
+		if !ok {
+			d.errorf(key, path, "unknown field %q", key.Value)
+			continue
+		}
+		if line, ok := seen[key.Value]; ok {
+			d.errorf(key, path, "field %q is already defined at line %d", key.Value, line)
+			continue
+		}
+		seen[key.Value] = key.Line
+		d.decode(value, v.Field(field), child)
+	}
+}
+
+// decodeMap decodes the given mapping node into the given map.
+func (d *configDecoder) decodeMap(node *yaml.Node, v reflect.Value, path string) {
+	if node.Kind != yaml.MappingNode {
+		d.errorf(node, path, "expected a mapping")
+		return
+	}
+	if v.Type().Elem().Kind() == reflect.Interface {
+		err := node.Decode(v.Addr().Interface())
+		if err != nil {
+			d.errorf(node, path, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+		return
+	}
+	v.Set(reflect.MakeMap(v.Type()))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		child := key.Value
+		if path != "" {
+			child = path + "." + key.Value
+		}
+		if v.MapIndex(reflect.ValueOf(key.Value)).IsValid() {
+			d.errorf(key, path, "key %q is already defined", key.Value)
+			continue
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		d.decode(value, elem, child)
+		v.SetMapIndex(reflect.ValueOf(key.Value), elem)
+	}
+}
+
+// errorAt returns an ErrConfig at the position of the value at the given
+// path, if it was read from a file.
+func (c *Config) errorAt(path, format string, args ...any) error {
+	p := c.positions[path]
+	message := fmt.Sprintf(format, args...)
+	if path != "" {
+		message = path + ": " + message
+	}
+	return ErrConfig{Message: message, File: c.file, Line: p.line, Column: p.column}
+}
+
+// Validate returns the errors of the configuration, joined, or nil if it is
+// valid.
+func (c *Config) Validate() error {
+	var errs []error
+	for _, name := range sortedKeys(c.Similarity) {
+		if _, ok := similarityOptions[name]; !ok {
+			errs = append(errs, c.errorAt("similarity."+name, "unknown similarity function %q", name))
+		}
+	}
+	switch c.Precision {
+	case "", Float32.String(), Float64.String():
+	default:
+		errs = append(errs, c.errorAt("precision", "unknown precision %q", c.Precision))
+	}
+	if c.Workers < 0 {
+		errs = append(errs, c.errorAt("workers", "workers must not be negative"))
+	}
+	if c.AmbiguityMargin < 0 {
+		errs = append(errs, c.errorAt("ambiguity_margin", "ambiguity margin must not be negative"))
+	}
+	if c.Encoder != nil && c.Encoder.Type == "" {
+		errs = append(errs, c.errorAt("encoder", "encoder type is required"))
+	}
+	if c.Store != nil && c.Store.Type == "" {
+		errs = append(errs, c.errorAt("store", "store type is required"))
+	}
+	if len(c.Routes) == 0 {
+		errs = append(errs, c.errorAt("routes", "at least one route is required"))
+	}
+	c.validateRoutes(c.Routes, "routes", make(map[string]string), &errs)
+	return errors.Join(errs...)
+}
+
+// validateRoutes appends the errors of the given routes, at the given path,
+// to the given errors. The paths of the routes are recorded by name.
+func (c *Config) validateRoutes(
+	routes []RouteConfig,
+	path string,
+	names map[string]string,
+	errs *[]error,
+) {
+	for i, route := range routes {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		switch previous, ok := names[route.Name]; {
+		case route.Name == "":
+			*errs = append(*errs, c.errorAt(at, "route name is required"))
+		case ok:
+			err := c.errorAt(at+".name", "route %q is already defined", route.Name)
+			if p := c.positions[previous+".name"]; p.line > 0 {
+				err = c.errorAt(at+".name", "route %q is already defined at line %d", route.Name, p.line)
+			}
+			*errs = append(*errs, err)
+		default:
+			names[route.Name] = at
+		}
+		if route.Threshold < 0 {
+			*errs = append(*errs, c.errorAt(at+".threshold", "threshold must not be negative"))
+		}
+		if len(route.Utterances) == 0 && len(route.Rules) == 0 && len(route.Children) == 0 {
+			*errs = append(*errs, c.errorAt(at, "route %q has no utterances, rules or children", route.Name))
+		}
+		for j, utterance := range route.Utterances {
+			if strings.TrimSpace(utterance.Utterance) == "" {
+				*errs = append(*errs, c.errorAt(fmt.Sprintf("%s.utterances[%d]", at, j), "utterance is empty"))
+			}
+		}
+		for j, utterance := range route.NegativeUtterances {
+			if strings.TrimSpace(utterance.Utterance) == "" {
+				*errs = append(*errs, c.errorAt(fmt.Sprintf("%s.negative_utterances[%d]", at, j), "utterance is empty"))
+			}
+		}
+		for j, rule := range route.Rules {
+			ruleAt := fmt.Sprintf("%s.rules[%d]", at, j)
+			if len(rule.Phrases) == 0 && len(rule.Keywords) == 0 && rule.Pattern == "" {
+				*errs = append(*errs, c.errorAt(ruleAt, "rule has no phrases, keywords or pattern"))
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+			_, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				*errs = append(*errs, c.errorAt(ruleAt+".pattern", "invalid pattern: %v", err))
+			}
+		}
+		c.validateRoutes(route.Children, at+".children", names, errs)
+	}
+}
+
+// EncoderFactory creates an encoder with the given options of a
+// configuration.
+type EncoderFactory func(options map[string]any) (Encoder, error)
+
+// StoreFactory creates a store with the given options of a configuration.
+type StoreFactory func(options map[string]any) (Store, error)
+
+// Factories holds the factories creating the encoders and stores selected by
+// configurations, by type.
+type Factories struct {
+	Encoders map[string]EncoderFactory // Encoders holds the factory of each type of encoder.
+	Stores   map[string]StoreFactory   // Stores holds the factory of each type of store.
+}
+
+// NewRouter creates the router of the configuration, with the encoder and
+// store created by the given factories, and the given options in addition to
+// the options of the configuration.
+func (c *Config) NewRouter(factories Factories, opts ...Option) (*Router, error) {
+	if c.Encoder == nil {
+		return nil, c.errorAt("", "no encoder is configured")
+	}
+	if c.Store == nil {
+		return nil, c.errorAt("", "no store is configured")
+	}
+	newEncoder, ok := factories.Encoders[c.Encoder.Type]
+	if !ok {
+		return nil, c.errorAt("encoder.type", "unknown encoder %q", c.Encoder.Type)
+	}
+	newStore, ok := factories.Stores[c.Store.Type]
+	if !ok {
+		return nil, c.errorAt("store.type", "unknown store %q", c.Store.Type)
+	}
+	encoder, err := newEncoder(c.Encoder.Options)
+	if err != nil {
+		return nil, fmt.Errorf("error creating encoder %s: %w", c.Encoder.Type, err)
+	}
+	store, err := newStore(c.Store.Options)
+	if err != nil {
+		return nil, fmt.Errorf("error creating store %s: %w", c.Store.Type, err)
+	}
+	return NewRouter(c.RouteList(), encoder, store, append(c.Options(), opts...)...)
+}
+
+// Options returns the options of the router of the configuration.
+func (c *Config) Options() []Option {
+	var opts []Option
+	for _, name := range sortedKeys(c.Similarity) {
+		if option, ok := similarityOptions[name]; ok {
+			opts = append(opts, option(c.Similarity[name]))
+		}
+	}
+	if len(opts) == 0 {
+		opts = append(opts,
+			WithSimilarityDotMatrix(1.0),
+			WithEuclideanDistance(1.0),
+			WithManhattanDistance(1.0),
+			WithJaccardSimilarity(1.0),
+			WithPearsonCorrelation(1.0),
+		)
+	}
+	switch c.Precision {
+	case Float32.String():
+		opts = append(opts, WithPrecision(Float32))
+	case Float64.String():
+		opts = append(opts, WithPrecision(Float64))
+	}
+	if c.Workers > 0 {
+		opts = append(opts, WithWorkers(c.Workers))
+	}
+	if c.AmbiguityMargin > 0 {
+		opts = append(opts, WithAmbiguityMargin(c.AmbiguityMargin))
+	}
+	return opts
+}
+
+// RouteList returns the routes of the configuration.
+func (c *Config) RouteList() []Route {
+	return routesOf(c.Routes)
+}
+
+// routesOf returns the routes of the given route configurations.
+func routesOf(configs []RouteConfig) []Route {
+	if configs == nil {
+		return nil
+	}
+	routes := make([]Route, len(configs))
+	for i, config := range configs {
+		routes[i] = Route{
+			Name:               config.Name,
+			Description:        config.Description,
+			Threshold:          config.Threshold,
+			Priority:           config.Priority,
+			Tags:               config.Tags,
+			Metadata:           config.Metadata,
+			Utterances:         utterancesOf(config.Utterances),
+			NegativeUtterances: utterancesOf(config.NegativeUtterances),
+			Children:           routesOf(config.Children),
+		}
+		for _, rule := range config.Rules {
+			routes[i].Rules = append(routes[i].Rules, Rule{
+				Name:     rule.Name,
+				Phrases:  rule.Phrases,
+				Keywords: rule.Keywords,
+				Pattern:  rule.Pattern,
+			})
+		}
+	}
+	return routes
+}
+
+// utterancesOf returns the utterances of the given utterance configurations.
+func utterancesOf(configs []UtteranceConfig) []Utterance {
+	if configs == nil {
+		return nil
+	}
+	utterances := make([]Utterance, len(configs))
+	for i, config := range configs {
+		utterances[i] = Utterance{
+			Utterance:   config.Utterance,
+			Description: config.Description,
+			Tags:        config.Tags,
+			Metadata:    config.Metadata,
+		}
+	}
+	return utterances
+}
+
+// ExportConfig returns the configuration of the given router: its routes,
+// the weights of its similarity functions, its precision, workers and
+// ambiguity margin.
+//
+// The encoder and store are not selected, since the router does not know
+// their types, and neither the options without a configuration, such as
+// handlers, functions or classifiers, nor the predicates of the rules are
+// exported.
+func ExportConfig(r *Router) *Config {
+	c := &Config{
+		Workers:         r.workers,
+		AmbiguityMargin: r.margin,
+		Routes:          routeConfigsOf(r.Routes),
+	}
+	if r.precision != Float64 {
+		c.Precision = r.precision.String()
+	}
+	for _, coeff := range r.biFuncCoeffs {
+		if coeff.name == "" {
+			continue
+		}
+		if c.Similarity == nil {
+			c.Similarity = make(map[string]float64)
+		}
+		c.Similarity[coeff.name] += coeff.coefficient
+	}
+	return c
+}
+
+// routeConfigsOf returns the configurations of the given routes.
+func routeConfigsOf(routes []Route) []RouteConfig {
+	if routes == nil {
+		return nil
+	}
+	configs := make([]RouteConfig, len(routes))
+	for i, route := range routes {
+		configs[i] = RouteConfig{
+			Name:               route.Name,
+			Description:        route.Description,
+			Threshold:          route.Threshold,
+			Priority:           route.Priority,
+			Tags:               route.Tags,
+			Metadata:           route.Metadata,
+			Utterances:         utteranceConfigsOf(route.Utterances),
+			NegativeUtterances: utteranceConfigsOf(route.NegativeUtterances),
+			Children:           routeConfigsOf(route.Children),
+		}
+		for _, rule := range route.Rules {
+			if len(rule.Phrases) == 0 && len(rule.Keywords) == 0 && rule.Pattern == "" {
+				continue
+			}
+			configs[i].Rules = append(configs[i].Rules, RuleConfig{
+				Name:     rule.Name,
+				Phrases:  rule.Phrases,
+				Keywords: rule.Keywords,
+				Pattern:  rule.Pattern,
+			})
+		}
+	}
+	return configs
+}
+
+// utteranceConfigsOf returns the configurations of the given utterances.
+func utteranceConfigsOf(utterances []Utterance) []UtteranceConfig {
+	if utterances == nil {
+		return nil
+	}
+	configs := make([]UtteranceConfig, len(utterances))
+	for i, utterance := range utterances {
+		configs[i] = UtteranceConfig{
+			Utterance:   utterance.Utterance,
+			Description: utterance.Description,
+			Tags:        utterance.Tags,
+			Metadata:    utterance.Metadata,
+		}
+	}
+	return configs
+}
+
+// Marshal returns the configuration in the given format.
+func (c *Config) Marshal(format Format) ([]byte, error) {
+	switch format {
+	case YAML:
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		err := enc.Encode(c)
+		if err == nil {
+			err = enc.Close()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling configuration: %w", err)
+		}
+		return b.Bytes(), nil
+	case JSON:
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling configuration: %w", err)
+		}
+		return append(data, '\n'), nil
+	case TOML:
+		data, err := marshalTOML(c)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling configuration: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unknown configuration format: %s", format)
+	}
+}
+
+// Save writes the configuration to the file at the given path, in the format
+// of its extension.
+func (c *Config) Save(path string) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	data, err := c.Marshal(format)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("error writing configuration: %w", err)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the given map, in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
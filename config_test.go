@@ -0,0 +1,458 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configYAML is a test configuration in YAML.
+const configYAML = `# Routes of the veterinarian.
+encoder:
+  type: fixed
+store:
+  type: memory
+similarity:
+  dot_matrix: 1
+routes:
+  - name: noteworthy
+    description: questions about pets
+    threshold: 0.5
+    tags: [tenant=acme]
+    metadata:
+      team: vets
+    utterances:
+      - what is the best way to treat a dog with a cold?
+      - utterance: my cat has been limping, what should I do?
+        tags: [cats]
+    rules:
+      - name: emergency
+        keywords: [emergency]
+  - name: chitchat
+    utterances:
+      - what is your favorite color?
+      - what is your favorite animal?
+`
+
+// configJSON is the test configuration in JSON.
+const configJSON = `{
+	"encoder": {"type": "fixed"},
+	"store": {"type": "memory"},
+	"similarity": {"dot_matrix": 1},
+	"routes": [
+		{
+			"name": "noteworthy",
+			"description": "questions about pets",
+			"threshold": 0.5,
+			"tags": ["tenant=acme"],
+			"metadata": {"team": "vets"},
+			"utterances": [
+				"what is the best way to treat a dog with a cold?",
+				{"utterance": "my cat has been limping, what should I do?", "tags": ["cats"]}
+			],
+			"rules": [{"name": "emergency", "keywords": ["emergency"]}]
+		},
+		{
+			"name": "chitchat",
+			"utterances": ["what is your favorite color?", "what is your favorite animal?"]
+		}
+	]
+}`
+
+// configTOML is the test configuration in TOML.
+const configTOML = `# Routes of the veterinarian.
+encoder = { type = "fixed" }
+store.type = "memory"
+
+[similarity]
+dot_matrix = 1
+
+[[routes]]
+name = "noteworthy"
+description = "questions about pets"
+threshold = 0.5
+tags = ["tenant=acme"]
+metadata = { team = 'vets' }
+utterances = [
+  "what is the best way to treat a dog with a cold?",
+  { utterance = "my cat has been limping, what should I do?", tags = ["cats"] },
+]
+
+[[routes.rules]]
+name = "emergency"
+keywords = ["emergency"]
+
+[[routes]]
+name = "chitchat"
+utterances = ["what is your favorite color?", "what is your favorite animal?"]
+`
+
+// configFactories creates the encoders and stores of the test
+// configurations.
+var configFactories = semanticrouter.Factories{
+	Encoders: map[string]semanticrouter.EncoderFactory{
+		"fixed": func(map[string]any) (semanticrouter.Encoder, error) {
+			return &batchEncoder{vectors: dispatchVectors}, nil
+		},
+	},
+	Stores: map[string]semanticrouter.StoreFactory{
+		"memory": func(map[string]any) (semanticrouter.Store, error) {
+			return memory.NewStore(), nil
+		},
+	},
+}
+
+// TestParseConfig tests parsing the same configuration in every format.
+func TestParseConfig(t *testing.T) {
+	for format, data := range map[semanticrouter.Format]string{
+		semanticrouter.YAML: configYAML,
+		semanticrouter.JSON: configJSON,
+		semanticrouter.TOML: configTOML,
+	} {
+		t.Run(format.String(), func(t *testing.T) {
+			a := assert.New(t)
+			cfg, err := semanticrouter.ParseConfig([]byte(data), format)
+			require.NoError(t, err)
+			a.Equal("fixed", cfg.Encoder.Type)
+			a.Equal("memory", cfg.Store.Type)
+			a.Equal(map[string]float64{"dot_matrix": 1}, cfg.Similarity)
+			require.Len(t, cfg.Routes, 2)
+			route := cfg.Routes[0]
+			a.Equal("noteworthy", route.Name)
+			a.Equal("questions about pets", route.Description)
+			a.InDelta(0.5, route.Threshold, 1e-9)
+			a.Equal([]string{"tenant=acme"}, route.Tags)
+			a.Equal(map[string]any{"team": "vets"}, route.Metadata)
+			a.Equal([]semanticrouter.UtteranceConfig{
+				{Utterance: "what is the best way to treat a dog with a cold?"},
+				{Utterance: "my cat has been limping, what should I do?", Tags: []string{"cats"}},
+			}, route.Utterances)
+			a.Equal([]semanticrouter.RuleConfig{
+				{Name: "emergency", Keywords: []string{"emergency"}},
+			}, route.Rules)
+
+			router, err := cfg.NewRouter(configFactories)
+			require.NoError(t, err)
+			res, err := router.Resolve(context.Background(), "my dog is sneezing")
+			require.NoError(t, err)
+			require.NotNil(t, res.Route)
+			a.Equal("noteworthy", res.Route.Name)
+			a.Equal("vets", res.Route.Metadata["team"])
+			res, err = router.Resolve(context.Background(), "this is an emergency")
+			require.NoError(t, err)
+			require.NotNil(t, res.Rule)
+			a.Equal("emergency", res.Rule.Name)
+		})
+	}
+}
+
+// TestParseConfigTOMLValues tests that the values of TOML configurations keep
+// their types, dates and times included.
+func TestParseConfigTOMLValues(t *testing.T) {
+	cfg, err := semanticrouter.ParseConfig([]byte(`[[routes]]
+name = "a"
+threshold = 1e-1
+utterances = ["b"]
+metadata = { since = 2024-05-27T07:32:00Z, day = 2024-05-27, weight = 2.0, count = 3, on = true }
+`), semanticrouter.TOML)
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.InDelta(0.1, cfg.Routes[0].Threshold, 1e-9)
+	metadata := cfg.Routes[0].Metadata
+	a.Equal(time.Date(2024, time.May, 27, 7, 32, 0, 0, time.UTC), metadata["since"])
+	a.Equal("2024-05-27", metadata["day"])
+	a.Equal(2.0, metadata["weight"])
+	a.Equal(3, metadata["count"])
+	a.Equal(true, metadata["on"])
+}
+
+// TestParseConfigErrors tests that the errors of configurations hold their
+// line and column.
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format semanticrouter.Format
+		data   string
+		errors []string
+	}{
+		{
+			name:   "yaml syntax",
+			format: semanticrouter.YAML,
+			data:   "routes:\n  - name: a\n   utterances: [b\n",
+			errors: []string{"1: did not find expected '-' indicator"},
+		},
+		{
+			name:   "json syntax",
+			format: semanticrouter.JSON,
+			data:   "{\n  \"routes\": [\n    {\"name\": \"a\",}\n  ]\n}",
+			errors: []string{"3:18: invalid character '}'"},
+		},
+		{
+			name:   "toml syntax",
+			format: semanticrouter.TOML,
+			data:   "[[routes]]\nname = \"a\"\nutterances = [\"b\" \"c\"]\n",
+			errors: []string{"3:19: array elements must be separated by commas"},
+		},
+		{
+			name:   "toml duplicate key",
+			format: semanticrouter.TOML,
+			data:   "[[routes]]\nname = \"a\"\nname = \"b\"\n",
+			errors: []string{"3:1: key name is already defined"},
+		},
+		{
+			name:   "types",
+			format: semanticrouter.YAML,
+			data:   "workers: many\nroutes:\n  - name: a\n    threshold: high\n    utterances: b\n",
+			errors: []string{
+				"1:10: workers: expected an integer",
+				"4:16: routes[0].threshold: expected a number",
+				"5:17: routes[0].utterances: expected a list",
+			},
+		},
+		{
+			name:   "toml types",
+			format: semanticrouter.TOML,
+			data: `workers = "many"
+
+[[routes]]
+name = "a"
+threshold = "high"
+utterance = ["b"]
+
+[[routes.children]]
+name = "c"
+utterances = "d"
+`,
+			errors: []string{
+				"1:11: workers: expected an integer",
+				"5:13: routes[0].threshold: expected a number",
+				`6:1: routes[0]: unknown field "utterance"`,
+				"10:14: routes[0].children[0].utterances: expected a list",
+			},
+		},
+		{
+			name:   "unknown field",
+			format: semanticrouter.YAML,
+			data:   "routes:\n  - name: a\n    utterance: [b]\n",
+			errors: []string{`3:5: routes[0]: unknown field "utterance"`},
+		},
+		{
+			name:   "validation",
+			format: semanticrouter.YAML,
+			data: `similarity:
+  cosine: 1
+routes:
+  - name: a
+    threshold: -1
+    utterances: [b, ""]
+  - name: a
+    rules: [{pattern: "("}]
+  - description: nameless
+`,
+			errors: []string{
+				`2:11: similarity.cosine: unknown similarity function "cosine"`,
+				"5:16: routes[0].threshold: threshold must not be negative",
+				"6:21: routes[0].utterances[1]: utterance is empty",
+				`7:11: routes[1].name: route "a" is already defined at line 4`,
+				"8:23: routes[1].rules[0].pattern: invalid pattern",
+				"9:5: routes[2]: route name is required",
+				`9:5: routes[2]: route "" has no utterances, rules or children`,
+			},
+		},
+		{
+			name:   "toml validation",
+			format: semanticrouter.TOML,
+			data: `[similarity]
+cosine = 1
+
+[[routes]]
+name = "a"
+threshold = -1.5
+utterances = ["b", ""]
+
+[[routes]]
+name = "a"
+rules = [{ pattern = "(" }]
+
+[[routes]]
+description = "nameless"
+`,
+			errors: []string{
+				`2:10: similarity.cosine: unknown similarity function "cosine"`,
+				"6:13: routes[0].threshold: threshold must not be negative",
+				"7:20: routes[0].utterances[1]: utterance is empty",
+				`10:8: routes[1].name: route "a" is already defined at line 5`,
+				"11:22: routes[1].rules[0].pattern: invalid pattern",
+				"13:3: routes[2]: route name is required",
+			},
+		},
+		{
+			name:   "empty",
+			format: semanticrouter.JSON,
+			data:   "{}",
+			errors: []string{"routes: at least one route is required"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := semanticrouter.ParseConfig([]byte(tt.data), tt.format)
+			require.Error(t, err)
+			var cfgErr semanticrouter.ErrConfig
+			require.True(t, errors.As(err, &cfgErr), "error %v is not an ErrConfig", err)
+			for _, message := range tt.errors {
+				assert.Contains(t, err.Error(), message)
+			}
+		})
+	}
+}
+
+// TestLoadConfig tests that errors of configuration files hold the path of
+// the file.
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "routes.yml")
+	cfg, err := semanticrouter.ParseConfig([]byte(configYAML), semanticrouter.YAML)
+	require.NoError(t, err)
+	cfg.Routes[1].Threshold = -2
+	require.NoError(t, cfg.Save(path))
+
+	_, err = semanticrouter.LoadConfig(path)
+	require.Error(t, err)
+	assert.Regexp(t, `^.*routes\.yml:\d+:\d+: routes\[1\]\.threshold: threshold must not be negative$`, err.Error())
+
+	_, err = semanticrouter.LoadConfig(filepath.Join(dir, "routes.ini"))
+	assert.Error(t, err)
+}
+
+// TestExportConfig tests that the configuration exported from a router
+// creates an equivalent router, in every format.
+func TestExportConfig(t *testing.T) {
+	routes := []semanticrouter.Route{
+		{
+			Name:        "noteworthy",
+			Description: "questions about pets",
+			Threshold:   0.5,
+			Priority:    2,
+			Tags:        []string{"tenant=acme"},
+			Metadata:    map[string]any{"team": "vets"},
+			Utterances: []semanticrouter.Utterance{
+				{Utterance: "what is the best way to treat a dog with a cold?"},
+				{
+					Utterance:   "my cat has been limping, what should I do?",
+					Description: "a \"limping\" cat",
+					Tags:        []string{"cats"},
+				},
+			},
+			Rules: []semanticrouter.Rule{
+				{Name: "emergency", Pattern: `(?i)\bemergency\b`},
+				{Name: "predicate", Predicate: func(string) bool { return false }},
+			},
+		},
+		ChitchatRoutes,
+	}
+	router, err := semanticrouter.NewRouter(
+		routes,
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+		semanticrouter.WithBM25(0.5),
+		semanticrouter.WithPrecision(semanticrouter.Float32),
+		semanticrouter.WithWorkers(2),
+		semanticrouter.WithAmbiguityMargin(0.1),
+	)
+	require.NoError(t, err)
+	cfg := semanticrouter.ExportConfig(router)
+	a := assert.New(t)
+	a.Equal(map[string]float64{"dot_matrix": 1, "bm25": 0.5}, cfg.Similarity)
+	a.Equal("float32", cfg.Precision)
+	a.Equal(2, cfg.Workers)
+	a.InDelta(0.1, cfg.AmbiguityMargin, 1e-9)
+	require.Len(t, cfg.Routes, 2)
+	a.Len(cfg.Routes[0].Rules, 1)
+
+	cfg.Encoder = &semanticrouter.ComponentConfig{Type: "fixed"}
+	cfg.Store = &semanticrouter.ComponentConfig{Type: "memory", Options: map[string]any{"name": "test"}}
+	for _, format := range []semanticrouter.Format{
+		semanticrouter.YAML,
+		semanticrouter.JSON,
+		semanticrouter.TOML,
+	} {
+		t.Run(format.String(), func(t *testing.T) {
+			data, err := cfg.Marshal(format)
+			require.NoError(t, err)
+			parsed, err := semanticrouter.ParseConfig(data, format)
+			require.NoError(t, err, string(data))
+			assert.Equal(t, cfg.Encoder, parsed.Encoder)
+			assert.Equal(t, cfg.Store, parsed.Store)
+			assert.Equal(t, cfg.Similarity, parsed.Similarity)
+			assert.Equal(t, cfg.Precision, parsed.Precision)
+			assert.Equal(t, cfg.Workers, parsed.Workers)
+			assert.InDelta(t, cfg.AmbiguityMargin, parsed.AmbiguityMargin, 1e-9)
+			assert.Equal(t, cfg.Routes, parsed.Routes)
+			router, err := parsed.NewRouter(configFactories)
+			require.NoError(t, err)
+			route, _, err := router.Match(context.Background(), "my dog is sneezing")
+			require.NoError(t, err)
+			require.NotNil(t, route)
+			assert.Equal(t, "noteworthy", route.Name)
+		})
+	}
+}
+
+// TestExportConfigThreshold tests that route thresholds above 1, which
+// combined scores can exceed, survive an export and parse round trip.
+func TestExportConfigThreshold(t *testing.T) {
+	noteworthy := NoteworthyRoutes
+	noteworthy.Threshold = 1.5
+	cfg := semanticrouter.ExportConfig(newTestRouter(t, []semanticrouter.Route{noteworthy}))
+	for _, format := range []semanticrouter.Format{
+		semanticrouter.YAML,
+		semanticrouter.JSON,
+		semanticrouter.TOML,
+	} {
+		t.Run(format.String(), func(t *testing.T) {
+			data, err := cfg.Marshal(format)
+			require.NoError(t, err)
+			parsed, err := semanticrouter.ParseConfig(data, format)
+			require.NoError(t, err, string(data))
+			require.Len(t, parsed.Routes, 1)
+			assert.InDelta(t, 1.5, parsed.Routes[0].Threshold, 1e-9)
+		})
+	}
+}
+
+// TestConfigFactories tests that the encoders and stores of configurations
+// must have factories.
+func TestConfigFactories(t *testing.T) {
+	cfg, err := semanticrouter.ParseConfig([]byte(configYAML), semanticrouter.YAML)
+	require.NoError(t, err)
+	_, err = cfg.NewRouter(semanticrouter.Factories{})
+	require.Error(t, err)
+	assert.Equal(t, `3:9: encoder.type: unknown encoder "fixed"`, err.Error())
+}
+
+// TestExportConfigPrecision tests that the default precision, float64, is
+// not exported and that an empty precision keeps it.
+func TestExportConfigPrecision(t *testing.T) {
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{NoteworthyRoutes},
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	cfg := semanticrouter.ExportConfig(router)
+	assert.Empty(t, cfg.Precision)
+	cfg.Encoder = &semanticrouter.ComponentConfig{Type: "fixed"}
+	cfg.Store = &semanticrouter.ComponentConfig{Type: "memory"}
+	router, err = cfg.NewRouter(configFactories)
+	require.NoError(t, err)
+	assert.Empty(t, semanticrouter.ExportConfig(router).Precision)
+}
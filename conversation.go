@@ -0,0 +1,221 @@
+package semanticrouter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const (
+	// DefaultWindow is the default number of previous turns of a
+	// Conversation taken into account.
+	DefaultWindow = 5
+	// DefaultDecay is the default factor by which the weight of the
+	// embedding of a previous turn of a Conversation decays per turn.
+	DefaultDecay = 0.5
+)
+
+// Turn is a turn of a Conversation.
+type Turn struct {
+	Utterance string // Utterance is the utterance of the turn.
+	Route     string // Route is the name of the route matched, empty if none.
+}
+
+// Conversation keeps a window of the recent turns of a conversation, so that
+// Router.MatchConversation can match follow-ups such as "yes, do that" in
+// their context.
+//
+// A Conversation is safe for concurrent use, but is bound to the precision
+// and encoder of the router it is matched with.
+type Conversation struct {
+	window int
+	decay  float64
+	prior  float64
+
+	mu         sync.Mutex
+	turns      []Turn
+	embeddings [][]float64
+}
+
+// ConversationOption is a function that configures a Conversation.
+type ConversationOption func(*Conversation)
+
+// WithWindow sets the number of previous turns taken into account.
+func WithWindow(window int) ConversationOption {
+	return func(c *Conversation) {
+		c.window = window
+	}
+}
+
+// WithDecay sets the factor, between 0 and 1, by which the weight of the
+// embedding of a previous turn decays per turn: the embedding of the k-th
+// previous turn is weighted by decay^k against the current utterance's.
+func WithDecay(decay float64) ConversationOption {
+	return func(c *Conversation) {
+		c.decay = decay
+	}
+}
+
+// WithPrior sets the bonus added to the score of the route matched by the
+// previous turn.
+func WithPrior(prior float64) ConversationOption {
+	return func(c *Conversation) {
+		c.prior = prior
+	}
+}
+
+// NewConversation creates a new empty Conversation.
+func NewConversation(opts ...ConversationOption) *Conversation {
+	c := &Conversation{
+		window: DefaultWindow,
+		decay:  DefaultDecay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Turns returns the turns in the window, oldest first.
+func (c *Conversation) Turns() []Turn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	turns := make([]Turn, len(c.turns))
+	copy(turns, c.turns)
+	return turns
+}
+
+// Reset forgets the turns of the conversation.
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.turns = nil
+	c.embeddings = nil
+}
+
+// add appends a turn to the conversation, dropping the turns outside the
+// window.
+func (c *Conversation) add(turn Turn, embedding []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.turns = append(c.turns, turn)
+	c.embeddings = append(c.embeddings, embedding)
+	if drop := len(c.turns) - max(c.window, 0); drop > 0 {
+		c.turns = c.turns[drop:]
+		c.embeddings = c.embeddings[drop:]
+	}
+}
+
+// history returns the embeddings and the last route of the turns in the
+// window.
+func (c *Conversation) history() ([][]float64, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.turns) == 0 {
+		return nil, ""
+	}
+	return c.embeddings, c.turns[len(c.turns)-1].Route
+}
+
+// MatchConversation returns the route that matches the given utterance in
+// the context of the given conversation, and adds the utterance to the
+// conversation as a new turn.
+//
+// The utterance is matched with the weighted average of its embedding and
+// of the embeddings of the previous turns in the conversation's window, the
+// k-th previous turn weighted by decay^k. The conversation's prior is added
+// to the score of the route matched by the previous turn. Utterances matched
+// by a rule are not encoded and do not weigh on the following turns.
+//
+// The hierarchy of the routes is not descended: only the top-level routes
+// are scored, by their own utterances, see MatchPath.
+func (r *Router) MatchConversation(
+	ctx context.Context,
+	conversation *Conversation,
+	utterance string,
+) (bestRoute *Route, bestScore float64, err error) {
+	var best int
+	var embedding []float64
+	if path, rule := r.matchRules(ctx, r.Routes, utterance); rule != nil {
+		bestRoute = path[len(path)-1]
+		conversation.add(Turn{Utterance: utterance, Route: bestRoute.Name}, nil)
+		return bestRoute, 1, nil
+	}
+	switch {
+	case r.index32 != nil:
+		best, bestScore, embedding, err = matchConversation(
+			ctx, r, r.index32, r.codec32(), conversation, utterance,
+		)
+	case r.index64 != nil:
+		best, bestScore, embedding, err = matchConversation(
+			ctx, r, r.index64, r.codec64(), conversation, utterance,
+		)
+	default:
+		return nil, 0.0, fmt.Errorf("router was not created with NewRouter")
+	}
+	if err != nil {
+		return nil, 0.0, err
+	}
+	turn := Turn{Utterance: utterance}
+	if best >= 0 {
+		bestRoute = &r.Routes[best]
+		turn.Route = bestRoute.Name
+	}
+	conversation.add(turn, embedding)
+	return bestRoute, bestScore, nil
+}
+
+// matchConversation returns the index of the best matching route of the
+// given utterance in the context of the given conversation, and the
+// utterance's embedding.
+func matchConversation[T Float](
+	ctx context.Context,
+	r *Router,
+	idx *index[T],
+	codec embeddingCodec[T],
+	conversation *Conversation,
+	utterance string,
+) (int, float64, []float64, error) {
+	encoding, err := codec.query(ctx, utterance)
+	if err != nil {
+		return -1, 0.0, nil, ErrEncoding{
+			Message: fmt.Sprintf(
+				"error encoding utterance: %s",
+				utterance,
+			),
+		}
+	}
+	history, previous := conversation.history()
+	query := make([]T, len(encoding))
+	copy(query, encoding)
+	weight, total := 1.0, 1.0
+	for i := len(history) - 1; i >= 0; i-- {
+		weight *= conversation.decay
+		if len(history[i]) != len(query) {
+			continue
+		}
+		for j, v := range history[i] {
+			query[j] += T(weight * v)
+		}
+		total += weight
+	}
+	for j := range query {
+		query[j] /= T(total)
+	}
+	scores, _, err := idx.scores(ctx, query, utterance, r.biFuncCoeffs, codec.pick, r.workers, len(r.Routes))
+	if err != nil {
+		return -1, 0.0, nil, err
+	}
+	if previous != "" {
+		for i := range r.Routes {
+			if r.Routes[i].Name == previous {
+				scores[i] += conversation.prior
+			}
+		}
+	}
+	best, score, err := r.pickRoute(scores, r.Routes, utterance)
+	if err != nil {
+		return -1, score, nil, err
+	}
+	return best, score, Convert[float64](encoding), nil
+}
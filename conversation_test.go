@@ -0,0 +1,95 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMatchConversation tests matching follow-ups in the context of the
+// previous turns of a conversation.
+func TestMatchConversation(t *testing.T) {
+	vectors := map[string][]float64{
+		"yes, do that": {0.45, 0.5, 0.74},
+	}
+	for k, v := range dispatchVectors {
+		vectors[k] = v
+	}
+	for _, precision := range []semanticrouter.Precision{
+		semanticrouter.Float32,
+		semanticrouter.Float64,
+	} {
+		t.Run(precision.String(), func(t *testing.T) {
+			a := assert.New(t)
+			ctx := context.Background()
+			router, err := semanticrouter.NewRouter(
+				[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+				&batchEncoder{vectors: vectors},
+				memory.NewStore(),
+				semanticrouter.WithSimilarityDotMatrix(1.0),
+				semanticrouter.WithPrecision(precision),
+			)
+			require.NoError(t, err)
+
+			route, _, err := router.Match(ctx, "yes, do that")
+			require.NoError(t, err)
+			a.Equal("chitchat", route.Name)
+
+			conversation := semanticrouter.NewConversation(semanticrouter.WithWindow(2))
+			route, _, err = router.MatchConversation(ctx, conversation, "my dog is sneezing")
+			require.NoError(t, err)
+			a.Equal("noteworthy", route.Name)
+			route, _, err = router.MatchConversation(ctx, conversation, "yes, do that")
+			require.NoError(t, err)
+			a.Equal("noteworthy", route.Name)
+			_, _, err = router.MatchConversation(ctx, conversation, "how do I file my taxes?")
+			require.NoError(t, err)
+			a.Equal([]semanticrouter.Turn{
+				{Utterance: "yes, do that", Route: "noteworthy"},
+				{Utterance: "how do I file my taxes?", Route: "noteworthy"},
+			}, conversation.Turns())
+
+			conversation.Reset()
+			a.Empty(conversation.Turns())
+			route, _, err = router.MatchConversation(ctx, conversation, "yes, do that")
+			require.NoError(t, err)
+			a.Equal("chitchat", route.Name)
+		})
+	}
+}
+
+// TestMatchConversationPrior tests favouring the route of the previous turn.
+func TestMatchConversationPrior(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	vectors := map[string][]float64{
+		"yes, do that": {0.45, 0.5, 0.74},
+	}
+	for k, v := range dispatchVectors {
+		vectors[k] = v
+	}
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		&batchEncoder{vectors: vectors},
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	conversation := semanticrouter.NewConversation(
+		semanticrouter.WithDecay(0),
+		semanticrouter.WithPrior(0.2),
+	)
+	_, _, err = router.MatchConversation(ctx, conversation, "my dog is sneezing")
+	require.NoError(t, err)
+	route, score, err := router.MatchConversation(ctx, conversation, "yes, do that")
+	require.NoError(t, err)
+	a.Equal("noteworthy", route.Name)
+	alone, _, err := router.Match(ctx, "yes, do that")
+	require.NoError(t, err)
+	a.Equal("chitchat", alone.Name)
+	a.Greater(score, 0.5)
+}
@@ -0,0 +1,106 @@
+package semanticrouter
+
+import (
+	"context"
+	"fmt"
+)
+
+// MatchResult is the result of matching an utterance against a router's
+// routes.
+type MatchResult struct {
+	Utterance string  // Utterance is the matched utterance.
+	Route     *Route  // Route is the best matching route, or nil if none matched.
+	Score     float64 // Score is the similarity score of the best matching route.
+	// Matched is the utterance of the route scoring best against the
+	// utterance, with its description, metadata and tags, nil if the route
+	// was not matched by its utterances.
+	Matched *Utterance
+	// Span is the part of the utterance matched by MatchAll.
+	Span Span
+	// Rule is the rule of the route that matched the utterance, nil if the
+	// route was matched semantically.
+	Rule *Rule
+	// Classified reports whether the route was chosen by the router's
+	// classifier rather than by the semantic match scored by Score.
+	Classified bool
+	// Arguments holds the arguments of the function of a dynamic route,
+	// extracted from the utterance.
+	Arguments map[string]any
+}
+
+// Handler handles an utterance matched to a route, returning the result of
+// the dispatch.
+type Handler func(ctx context.Context, result MatchResult) (any, error)
+
+// Middleware wraps a Handler, running code before and after it.
+type Middleware func(next Handler) Handler
+
+// WithFallback sets the handler dispatched to when no route matches an
+// utterance, or when the matched route has no handler.
+func WithFallback(handler Handler) Option {
+	return func(r *Router) {
+		r.fallback = handler
+	}
+}
+
+// WithMiddleware adds middleware wrapping every handler dispatched to,
+// including the fallback.
+//
+// The first middleware given is the outermost: it runs first before the
+// handler and last after it.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(r *Router) {
+		r.middleware = append(r.middleware, middleware...)
+	}
+}
+
+// Dispatch resolves the given utterance and invokes the handler of the best
+// matching route, wrapped in the router's middleware.
+//
+// If no route matches, or the matched route has no handler, the fallback
+// handler is invoked instead. Without a fallback, ErrNoRouteFound or
+// ErrNoHandler is returned. An error returned by a handler is wrapped in an
+// ErrHandler.
+func (r *Router) Dispatch(
+	ctx context.Context,
+	utterance string,
+) (any, error) {
+	result, err := r.Resolve(ctx, utterance)
+	if err != nil {
+		return nil, err
+	}
+	route := result.Route
+	handler := r.fallback
+	if route != nil && route.Handler != nil {
+		handler = route.Handler
+	}
+	if handler == nil {
+		if route == nil {
+			return nil, ErrNoRouteFound{
+				Message:   "no route found",
+				Utterance: utterance,
+			}
+		}
+		return nil, ErrNoHandler{
+			Message: fmt.Sprintf("route %s has no handler", route.Name),
+			Route:   route.Name,
+		}
+	}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	out, err := handler(ctx, result)
+	if err != nil {
+		name := ""
+		if route != nil {
+			name = route.Name
+		}
+		return out, ErrHandler{
+			Message:   fmt.Sprintf("error handling utterance: %s", utterance),
+			Route:     name,
+			Utterance: utterance,
+			Err:       err,
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,114 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDispatch tests dispatching utterances to the handlers of their routes.
+func TestDispatch(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	noteworthy := NoteworthyRoutes
+	noteworthy.Handler = func(_ context.Context, res semanticrouter.MatchResult) (any, error) {
+		return "vet: " + res.Utterance, nil
+	}
+	router := newTestRouter(t, []semanticrouter.Route{noteworthy, ChitchatRoutes})
+	out, err := router.Dispatch(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	a.Equal("vet: my dog is sneezing", out)
+
+	_, err = router.Dispatch(ctx, "what is your favorite color?")
+	var noHandler semanticrouter.ErrNoHandler
+	require.ErrorAs(t, err, &noHandler)
+	a.Equal("chitchat", noHandler.Route)
+
+	_, err = router.Dispatch(ctx, "how do I file my taxes?")
+	var noRoute semanticrouter.ErrNoRouteFound
+	require.ErrorAs(t, err, &noRoute)
+	a.Equal("how do I file my taxes?", noRoute.Utterance)
+}
+
+// TestDispatchFallback tests dispatching to the fallback handler.
+func TestDispatchFallback(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	fallback := func(_ context.Context, res semanticrouter.MatchResult) (any, error) {
+		if res.Route == nil {
+			return "fallback: no route", nil
+		}
+		return "fallback: " + res.Route.Name, nil
+	}
+	router := newTestRouter(t,
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		semanticrouter.WithFallback(fallback),
+	)
+	for utterance, expected := range map[string]string{
+		"my dog is sneezing":           "fallback: noteworthy",
+		"what is your favorite color?": "fallback: chitchat",
+		"how do I file my taxes?":      "fallback: no route",
+	} {
+		out, err := router.Dispatch(ctx, utterance)
+		require.NoError(t, err)
+		a.Equal(expected, out, utterance)
+	}
+}
+
+// TestDispatchMiddleware tests that middleware wraps the dispatched handler,
+// the first given outermost.
+func TestDispatchMiddleware(t *testing.T) {
+	a := assert.New(t)
+	var calls []string
+	trace := func(name string) semanticrouter.Middleware {
+		return func(next semanticrouter.Handler) semanticrouter.Handler {
+			return func(ctx context.Context, res semanticrouter.MatchResult) (any, error) {
+				calls = append(calls, "before "+name)
+				out, err := next(ctx, res)
+				calls = append(calls, "after "+name)
+				return out, err
+			}
+		}
+	}
+	noteworthy := NoteworthyRoutes
+	noteworthy.Handler = func(_ context.Context, res semanticrouter.MatchResult) (any, error) {
+		calls = append(calls, "handler "+res.Route.Name)
+		return res.Score, nil
+	}
+	router := newTestRouter(t,
+		[]semanticrouter.Route{noteworthy, ChitchatRoutes},
+		semanticrouter.WithMiddleware(trace("outer"), trace("inner")),
+	)
+	out, err := router.Dispatch(context.Background(), "my dog is sneezing")
+	require.NoError(t, err)
+	a.Greater(out, 0.9)
+	a.Equal([]string{
+		"before outer",
+		"before inner",
+		"handler noteworthy",
+		"after inner",
+		"after outer",
+	}, calls)
+}
+
+// TestDispatchHandlerError tests that handler errors are wrapped in an
+// ErrHandler.
+func TestDispatchHandlerError(t *testing.T) {
+	a := assert.New(t)
+	errVet := errors.New("vet is closed")
+	noteworthy := NoteworthyRoutes
+	noteworthy.Handler = func(context.Context, semanticrouter.MatchResult) (any, error) {
+		return nil, errVet
+	}
+	router := newTestRouter(t, []semanticrouter.Route{noteworthy, ChitchatRoutes})
+	_, err := router.Dispatch(context.Background(), "my dog is sneezing")
+	a.ErrorIs(err, errVet)
+	var handlerErr semanticrouter.ErrHandler
+	require.ErrorAs(t, err, &handlerErr)
+	a.Equal("noteworthy", handlerErr.Route)
+	a.Equal("my dog is sneezing", handlerErr.Utterance)
+}
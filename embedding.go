@@ -0,0 +1,86 @@
+package semanticrouter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Float is the constraint satisfied by the element types of embeddings.
+type Float interface {
+	~float32 | ~float64
+}
+
+// Precision is the element type the router keeps embeddings in.
+type Precision int
+
+const (
+	// Float64 keeps embeddings as float64 values. It is the default
+	// precision.
+	Float64 Precision = iota
+	// Float32 keeps embeddings as float32 values, halving their memory
+	// compared to Float64. Scores may differ from Float64 by rounding
+	// errors, and embeddings are stored natively as float32 if the store
+	// implements Float32Store.
+	Float32
+)
+
+// String returns the name of the precision.
+func (p Precision) String() string {
+	switch p {
+	case Float32:
+		return "float32"
+	case Float64:
+		return "float64"
+	default:
+		return fmt.Sprintf("Precision(%d)", int(p))
+	}
+}
+
+// WithPrecision sets the precision the router keeps embeddings in, Float64
+// by default.
+func WithPrecision(precision Precision) Option {
+	return func(r *Router) {
+		r.precision = precision
+	}
+}
+
+// Convert returns a copy of the given embedding with elements of type To.
+func Convert[To, From Float](embedding []From) []To {
+	if embedding == nil {
+		return nil
+	}
+	result := make([]To, len(embedding))
+	for i, v := range embedding {
+		result[i] = To(v)
+	}
+	return result
+}
+
+// Float32Bytes returns the little-endian IEEE 754 encoding of the given
+// float32 embedding, four bytes per element.
+//
+// It is a compact encoding for stores, about a third of the size of the
+// JSON encoding of the same values.
+func Float32Bytes(embedding []float32) []byte {
+	result := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(result[4*i:], math.Float32bits(v))
+	}
+	return result
+}
+
+// Float32sFromBytes decodes an embedding encoded with Float32Bytes.
+func Float32sFromBytes(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf(
+			"error decoding embedding: length %d is not a multiple of 4",
+			len(data),
+		)
+	}
+	result := make([]float32, len(data)/4)
+	for i := range result {
+		result[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return result, nil
+}
@@ -3,8 +3,6 @@ package semanticrouter
 import (
 	"context"
 	"io"
-
-	"gonum.org/v1/gonum/mat"
 )
 
 // Encoder represents a encoding driver in the semantic router.
@@ -15,6 +13,38 @@ type Encoder interface {
 	Encode(ctx context.Context, utterance string) ([]float64, error)
 }
 
+// BatchEncoder is an optional interface an Encoder can implement to encode
+// many utterances with a single request to the embedding provider.
+//
+// The returned slice must hold one embedding per utterance, in the same order
+// as the given utterances.
+type BatchEncoder interface {
+	EncodeBatch(ctx context.Context, utterances []string) ([][]float64, error)
+}
+
+// QueryDocumentEncoder is an optional interface an Encoder can implement when
+// its model embeds stored documents and incoming queries differently, for
+// example with a different input type or prompt.
+//
+// The router encodes the utterances of its routes with EncodeDocuments and the
+// utterances given to Match with EncodeQuery.
+type QueryDocumentEncoder interface {
+	EncodeQuery(ctx context.Context, query string) ([]float64, error)
+	EncodeDocuments(ctx context.Context, documents []string) ([][]float64, error)
+}
+
+// Float32Encoder is an optional interface an Encoder can implement when its
+// model produces float32 embeddings, so that they reach a router with the
+// Float32 precision without being converted to float64 and back.
+//
+// The returned slice must hold one embedding per utterance, in the same order
+// as the given utterances. If the encoder also implements
+// QueryDocumentEncoder, the router uses EncodeQuery and EncodeDocuments
+// instead.
+type Float32Encoder interface {
+	EncodeFloat32(ctx context.Context, utterances []string) ([][]float32, error)
+}
+
 // Store is an interface that defines a method, Store, which takes a []float64
 // and stores it in a some sort of data store, and a method, Get, which takes a
 // string and returns a []float64 from the data store.
@@ -38,6 +68,27 @@ type Getter interface {
 	Get(ctx context.Context, key string) ([]float64, error)
 }
 
+// Float32Store is an optional interface a Store can implement to store float32
+// embeddings natively.
+//
+// A router with the Float32 precision stores and loads embeddings with
+// SetFloat32 and GetFloat32 if its store implements this interface. The
+// embedding of the utterance given to SetFloat32 is ignored.
+type Float32Store interface {
+	SetFloat32(ctx context.Context, utterance Utterance, embedding []float32) error
+	GetFloat32(ctx context.Context, key string) ([]float32, error)
+}
+
+// UtteranceStore is an optional interface a Store can implement to return
+// the utterances set in it with their description, metadata and tags.
+//
+// The embedding of the returned utterance is set in float64 precision,
+// whichever precision it was set in. If the key does not exist, it returns an
+// error.
+type UtteranceStore interface {
+	GetUtterance(ctx context.Context, key string) (Utterance, error)
+}
+
 // Option is a function that configures a Router.
 type Option func(*Router)
 
@@ -46,4 +97,4 @@ type Option func(*Router)
 // It also returns an error if there is an error during the comparison.
 //
 // It is used to compare the similarity between two vectors.
-type handler func(queryVec *mat.VecDense, indexVec *mat.VecDense) (float64, error)
+type handler[T Float] func(queryVec, indexVec []T) (float64, error)
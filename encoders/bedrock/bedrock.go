@@ -0,0 +1,252 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+const (
+	// TitanEmbedTextV1 is the Amazon Titan Text Embeddings model.
+	TitanEmbedTextV1 = "amazon.titan-embed-text-v1"
+	// TitanEmbedTextV2 is the Amazon Titan Text Embeddings V2 model.
+	TitanEmbedTextV2 = "amazon.titan-embed-text-v2:0"
+	// CohereEmbedEnglishV3 is the Cohere Embed English model.
+	CohereEmbedEnglishV3 = "cohere.embed-english-v3"
+	// CohereEmbedMultilingualV3 is the Cohere Embed Multilingual model.
+	CohereEmbedMultilingualV3 = "cohere.embed-multilingual-v3"
+	// MaxCohereBatchSize is the maximum number of texts a Cohere model embeds
+	// per request.
+	MaxCohereBatchSize = 96
+)
+
+// Encoder is an encoder using embedding models hosted on Amazon Bedrock.
+//
+// The request and response format is chosen from the model ID: models
+// containing "cohere.embed" use the Cohere format and all other models use the
+// Amazon Titan format.
+type Encoder struct {
+	// Client is the Bedrock runtime client.
+	Client *bedrockruntime.Client
+	// Model is the ID or ARN of the embedding model to use.
+	Model string
+	// Dimensions is the number of dimensions of the output embeddings. It is
+	// only supported by Titan Text Embeddings V2 and left to the model
+	// default if zero.
+	Dimensions int
+	// Normalize requests normalized embeddings. It is only supported by
+	// Titan Text Embeddings V2.
+	Normalize bool
+	// InputType is the Cohere input type, such as search_document or
+	// search_query.
+	InputType string
+	// Truncate is how Cohere models handle inputs that are too long: NONE,
+	// START or END. It is left to the model default if empty.
+	Truncate string
+}
+
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithDimensions sets the number of dimensions of Titan V2 embeddings.
+func WithDimensions(dimensions int) Option {
+	return func(e *Encoder) {
+		e.Dimensions = dimensions
+	}
+}
+
+// WithNormalize sets whether Titan V2 embeddings are normalized.
+func WithNormalize(normalize bool) Option {
+	return func(e *Encoder) {
+		e.Normalize = normalize
+	}
+}
+
+// WithInputType sets the Cohere input type.
+func WithInputType(inputType string) Option {
+	return func(e *Encoder) {
+		e.InputType = inputType
+	}
+}
+
+// WithTruncate sets how Cohere models handle inputs that are too long.
+func WithTruncate(truncate string) Option {
+	return func(e *Encoder) {
+		e.Truncate = truncate
+	}
+}
+
+// NewEncoder creates a new Encoder.
+//
+// Cohere models default to the search_document input type.
+func NewEncoder(
+	client *bedrockruntime.Client,
+	model string,
+	opts ...Option,
+) *Encoder {
+	e := &Encoder{
+		Client:    client,
+		Model:     model,
+		InputType: "search_document",
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// titanRequest is the body of a Titan embeddings request.
+type titanRequest struct {
+	InputText  string `json:"inputText"`
+	Dimensions int    `json:"dimensions,omitempty"`
+	Normalize  bool   `json:"normalize,omitempty"`
+}
+
+// titanResponse is the body of a Titan embeddings response.
+type titanResponse struct {
+	Embedding           []float64 `json:"embedding"`
+	InputTextTokenCount int       `json:"inputTextTokenCount"`
+}
+
+// cohereRequest is the body of a Cohere embeddings request.
+type cohereRequest struct {
+	Texts     []string `json:"texts"`
+	InputType string   `json:"input_type"`
+	Truncate  string   `json:"truncate,omitempty"`
+}
+
+// cohereResponse is the body of a Cohere embeddings response.
+type cohereResponse struct {
+	ID         string      `json:"id"`
+	Embeddings [][]float64 `json:"embeddings"`
+	Texts      []string    `json:"texts"`
+}
+
+// Encode encodes a query string into a Bedrock embedding.
+func (e *Encoder) Encode(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	if e.isCohere() {
+		embeddings, err := e.embedCohere(ctx, []string{query}, e.InputType)
+		if err != nil {
+			return nil, err
+		}
+		return embeddings[0], nil
+	}
+	return e.embedTitan(ctx, query)
+}
+
+// EncodeBatch encodes the given utterances into Bedrock embeddings.
+//
+// Cohere models embed up to MaxCohereBatchSize utterances per request, while
+// Titan models embed one utterance per request.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	result := make([][]float64, 0, len(utterances))
+	if e.isCohere() {
+		for start := 0; start < len(utterances); start += MaxCohereBatchSize {
+			end := min(start+MaxCohereBatchSize, len(utterances))
+			embeddings, err := e.embedCohere(ctx, utterances[start:end], e.InputType)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, embeddings...)
+		}
+		return result, nil
+	}
+	for _, utterance := range utterances {
+		embedding, err := e.embedTitan(ctx, utterance)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, embedding)
+	}
+	return result, nil
+}
+
+// isCohere reports whether the encoder's model uses the Cohere format.
+func (e *Encoder) isCohere() bool {
+	return strings.Contains(e.Model, "cohere.embed")
+}
+
+// embedTitan embeds a single text with a Titan model.
+func (e *Encoder) embedTitan(
+	ctx context.Context,
+	text string,
+) ([]float64, error) {
+	var resp titanResponse
+	err := e.invoke(ctx, titanRequest{
+		InputText:  text,
+		Dimensions: e.Dimensions,
+		Normalize:  e.Normalize,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embedding) == 0 {
+		return nil, fmt.Errorf("error creating embedding: empty embedding")
+	}
+	return resp.Embedding, nil
+}
+
+// embedCohere embeds the given texts with a Cohere model.
+func (e *Encoder) embedCohere(
+	ctx context.Context,
+	texts []string,
+	inputType string,
+) ([][]float64, error) {
+	var resp cohereResponse
+	err := e.invoke(ctx, cohereRequest{
+		Texts:     texts,
+		InputType: inputType,
+		Truncate:  e.Truncate,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf(
+			"error creating embeddings: got %d embeddings for %d texts",
+			len(resp.Embeddings),
+			len(texts),
+		)
+	}
+	return resp.Embeddings, nil
+}
+
+// invoke invokes the encoder's model with the given request body and decodes
+// the response body into out.
+func (e *Encoder) invoke(
+	ctx context.Context,
+	body any,
+	out any,
+) error {
+	if e.Client == nil {
+		return fmt.Errorf("bedrock client is nil")
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error marshaling request: %w", err)
+	}
+	output, err := e.Client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(e.Model),
+		Body:        payload,
+		ContentType: aws.String("application/json"),
+		Accept:      aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("error invoking model %s: %w", e.Model, err)
+	}
+	err = json.Unmarshal(output.Body, out)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return nil
+}
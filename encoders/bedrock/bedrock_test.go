@@ -0,0 +1,203 @@
+package bedrock_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/bedrock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder      = (*bedrock.Encoder)(nil)
+	_ semanticrouter.BatchEncoder = (*bedrock.Encoder)(nil)
+)
+
+const (
+	region    = "us-east-1"
+	accessKey = "AKIDEXAMPLE"
+	secretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// invocation is a request received by the Bedrock stand-in.
+type invocation struct {
+	Model string
+	Body  map[string]any
+}
+
+// newBedrockServer returns a stand-in for the Bedrock runtime that verifies the
+// SigV4 signature of each request and replays the given recorded response.
+func newBedrockServer(
+	t *testing.T,
+	status int,
+	fixture string,
+	invocations *[]invocation,
+) *bedrockruntime.Client {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPost, r.Method)
+		verifySignature(t, r, payload)
+		model, err := url.PathUnescape(
+			strings.TrimSuffix(strings.TrimPrefix(r.URL.EscapedPath(), "/model/"), "/invoke"),
+		)
+		assert.NoError(t, err)
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(payload, &decoded))
+		*invocations = append(*invocations, invocation{Model: model, Body: decoded})
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			w.Header().Set("X-Amzn-ErrorType", "ValidationException")
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return bedrockruntime.New(bedrockruntime.Options{
+		Region:           region,
+		BaseEndpoint:     aws.String(srv.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		HTTPClient:       srv.Client(),
+		RetryMaxAttempts: 1,
+	})
+}
+
+// verifySignature re-signs the received request with the test credentials and
+// checks that the signature matches the one sent by the client.
+func verifySignature(t *testing.T, r *http.Request, payload []byte) {
+	t.Helper()
+	auth := r.Header.Get("Authorization")
+	if !assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ")) {
+		return
+	}
+	signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, auth, "Credential="+accessKey+"/"+signedAt.Format("20060102")+"/"+region+"/bedrock/aws4_request")
+	signed := map[string]bool{}
+	for _, part := range strings.Split(auth, ", ") {
+		if headers, ok := strings.CutPrefix(part, "SignedHeaders="); ok {
+			for _, header := range strings.Split(headers, ";") {
+				signed[header] = true
+			}
+		}
+	}
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), bytes.NewReader(payload))
+	if !assert.NoError(t, err) {
+		return
+	}
+	for name, values := range r.Header {
+		if signed[strings.ToLower(name)] {
+			req.Header[name] = values
+		}
+	}
+	hash := sha256.Sum256(payload)
+	err = v4.NewSigner().SignHTTP(
+		context.Background(),
+		aws.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey},
+		req,
+		hex.EncodeToString(hash[:]),
+		"bedrock",
+		region,
+		signedAt,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, auth, req.Header.Get("Authorization"))
+}
+
+// TestEncoderTitan tests encoding with a Titan model.
+func TestEncoderTitan(t *testing.T) {
+	a := assert.New(t)
+	var invocations []invocation
+	client := newBedrockServer(t, http.StatusOK, "titan_v2.json", &invocations)
+	encoder := bedrock.NewEncoder(
+		client,
+		bedrock.TitanEmbedTextV2,
+		bedrock.WithDimensions(256),
+		bedrock.WithNormalize(true),
+	)
+	result, err := encoder.Encode(context.Background(), "how's the weather today?")
+	a.NoError(err)
+	a.Len(result, 8)
+	a.InDelta(-0.09497993, result[0], 1e-9)
+	a.Equal([]invocation{{
+		Model: bedrock.TitanEmbedTextV2,
+		Body: map[string]any{
+			"inputText":  "how's the weather today?",
+			"dimensions": float64(256),
+			"normalize":  true,
+		},
+	}}, invocations)
+}
+
+// TestEncoderTitanBatch tests that Titan models embed one utterance per
+// request.
+func TestEncoderTitanBatch(t *testing.T) {
+	a := assert.New(t)
+	var invocations []invocation
+	client := newBedrockServer(t, http.StatusOK, "titan_v2.json", &invocations)
+	encoder := bedrock.NewEncoder(client, bedrock.TitanEmbedTextV1)
+	result, err := encoder.EncodeBatch(context.Background(), []string{"a", "b"})
+	a.NoError(err)
+	a.Len(result, 2)
+	a.Len(invocations, 2)
+	a.Equal(map[string]any{"inputText": "b"}, invocations[1].Body)
+}
+
+// TestEncoderCohereBatch tests encoding several utterances with a Cohere model.
+func TestEncoderCohereBatch(t *testing.T) {
+	a := assert.New(t)
+	var invocations []invocation
+	client := newBedrockServer(t, http.StatusOK, "cohere_embed.json", &invocations)
+	encoder := bedrock.NewEncoder(
+		client,
+		bedrock.CohereEmbedEnglishV3,
+		bedrock.WithTruncate("END"),
+	)
+	result, err := encoder.EncodeBatch(context.Background(), []string{
+		"how's the weather today?",
+		"lovely weather today",
+	})
+	a.NoError(err)
+	a.Len(result, 2)
+	a.InDelta(-0.015052795, result[1][3], 1e-9)
+	a.Equal([]invocation{{
+		Model: bedrock.CohereEmbedEnglishV3,
+		Body: map[string]any{
+			"texts":      []any{"how's the weather today?", "lovely weather today"},
+			"input_type": "search_document",
+			"truncate":   "END",
+		},
+	}}, invocations)
+}
+
+// TestEncoderError tests that Bedrock errors are returned.
+func TestEncoderError(t *testing.T) {
+	var invocations []invocation
+	client := newBedrockServer(t, http.StatusBadRequest, "validation_error.json", &invocations)
+	encoder := bedrock.NewEncoder(client, bedrock.TitanEmbedTextV2)
+	_, err := encoder.Encode(context.Background(), "how's the weather today?")
+	assert.ErrorContains(t, err, "Malformed input request")
+}
@@ -0,0 +1,7 @@
+// Package bedrock provides encoders for embedding models hosted on Amazon
+// Bedrock.
+//
+// Amazon Titan and Cohere embedding models are supported. Requests are sent
+// through the Bedrock runtime client, which signs them with AWS Signature
+// Version 4.
+package bedrock
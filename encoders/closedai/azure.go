@@ -0,0 +1,56 @@
+package closedai
+
+import (
+	"net/http"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when none is
+// configured.
+const DefaultAzureAPIVersion = "2024-02-01"
+
+// AzureConfig is the configuration of an Azure OpenAI embedding deployment.
+type AzureConfig struct {
+	// APIKey is the key of the Azure OpenAI resource, or a Microsoft Entra ID
+	// token if UseEntraID is set.
+	APIKey string
+	// Endpoint is the endpoint of the Azure OpenAI resource, for example
+	// https://my-resource.openai.azure.com.
+	Endpoint string
+	// Deployment is the name of the embedding model deployment.
+	Deployment string
+	// APIVersion is the Azure OpenAI API version. It defaults to
+	// DefaultAzureAPIVersion.
+	APIVersion string
+	// UseEntraID sends APIKey as a bearer token instead of an api-key header.
+	UseEntraID bool
+	// HTTPClient is the client used to send requests.
+	HTTPClient *http.Client
+}
+
+// NewAzureEncoder creates an Encoder for an Azure OpenAI embedding deployment.
+//
+// Requests are sent to the deployment's embeddings endpoint with the configured
+// api-version query parameter.
+func NewAzureEncoder(cfg AzureConfig) Encoder {
+	config := openai.DefaultAzureConfig(cfg.APIKey, cfg.Endpoint)
+	if cfg.UseEntraID {
+		config.APIType = openai.APITypeAzureAD
+	}
+	config.APIVersion = cfg.APIVersion
+	if config.APIVersion == "" {
+		config.APIVersion = DefaultAzureAPIVersion
+	}
+	deployment := cfg.Deployment
+	config.AzureModelMapperFunc = func(string) string {
+		return deployment
+	}
+	if cfg.HTTPClient != nil {
+		config.HTTPClient = cfg.HTTPClient
+	}
+	return Encoder{
+		Client: openai.NewClientWithConfig(config),
+		Model:  openai.EmbeddingModel(deployment),
+	}
+}
@@ -0,0 +1,111 @@
+package closedai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/closedai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder        = closedai.Encoder{}
+	_ semanticrouter.BatchEncoder   = closedai.Encoder{}
+	_ semanticrouter.Float32Encoder = closedai.Encoder{}
+)
+
+// newAzureServer returns a stand-in for an Azure OpenAI resource that replays
+// the given recorded response for the my-embeddings deployment.
+func newAzureServer(
+	t *testing.T,
+	status int,
+	fixture string,
+	inputs *[]string,
+) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/openai/deployments/my-embeddings/embeddings", r.URL.Path)
+		assert.Equal(t, "2024-06-01", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "test-key", r.Header.Get("api-key"))
+		var req struct {
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		*inputs = append(*inputs, req.Input...)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestAzureEncoderBatch tests encoding with an Azure OpenAI deployment.
+func TestAzureEncoderBatch(t *testing.T) {
+	a := assert.New(t)
+	var inputs []string
+	srv := newAzureServer(t, http.StatusOK, "azure_embeddings.json", &inputs)
+	encoder := closedai.NewAzureEncoder(closedai.AzureConfig{
+		APIKey:     "test-key",
+		Endpoint:   srv.URL,
+		Deployment: "my-embeddings",
+		APIVersion: "2024-06-01",
+		HTTPClient: srv.Client(),
+	})
+	result, err := encoder.EncodeBatch(context.Background(), []string{
+		"how's the weather today?",
+		"lovely weather today",
+	})
+	a.NoError(err)
+	a.Equal([]string{"how's the weather today?", "lovely weather today"}, inputs)
+	a.Len(result, 2)
+	a.InDelta(0.0023064255, result[0][0], 1e-7)
+	a.InDelta(-0.0070945257, result[1][0], 1e-7)
+}
+
+// TestAzureEncoderFloat32 tests encoding float32 embeddings with an Azure
+// OpenAI deployment.
+func TestAzureEncoderFloat32(t *testing.T) {
+	a := assert.New(t)
+	var inputs []string
+	srv := newAzureServer(t, http.StatusOK, "azure_embeddings.json", &inputs)
+	encoder := closedai.NewAzureEncoder(closedai.AzureConfig{
+		APIKey:     "test-key",
+		Endpoint:   srv.URL,
+		Deployment: "my-embeddings",
+		APIVersion: "2024-06-01",
+		HTTPClient: srv.Client(),
+	})
+	result, err := encoder.EncodeFloat32(context.Background(), []string{
+		"how's the weather today?",
+		"lovely weather today",
+	})
+	a.NoError(err)
+	a.Len(result, 2)
+	a.Equal(float32(0.0023064255), result[0][0])
+	a.Equal(float32(-0.0070945257), result[1][0])
+}
+
+// TestAzureEncoderError tests that Azure OpenAI errors are returned.
+func TestAzureEncoderError(t *testing.T) {
+	var inputs []string
+	srv := newAzureServer(t, http.StatusNotFound, "azure_error.json", &inputs)
+	encoder := closedai.NewAzureEncoder(closedai.AzureConfig{
+		APIKey:     "test-key",
+		Endpoint:   srv.URL,
+		Deployment: "my-embeddings",
+		APIVersion: "2024-06-01",
+	})
+	_, err := encoder.Encode(context.Background(), "how's the weather today?")
+	assert.ErrorContains(t, err, "deployment for this resource does not exist")
+}
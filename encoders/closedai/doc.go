@@ -1,2 +1,5 @@
 // Package closedai provides encoders for OpenAI embedding models.
+//
+// Embedding deployments hosted on Azure OpenAI are supported through
+// NewAzureEncoder.
 package closedai
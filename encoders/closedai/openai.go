@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/conneroisu/semanticrouter-go"
 	openai "github.com/sashabaranov/go-openai"
 )
 
+// MaxBatchSize is the maximum number of inputs embedded per request.
+const MaxBatchSize = 100
+
 // Encoder encodes a query string into an OpenAI embedding.
 type Encoder struct {
 	// Client is the OpenAI client.
@@ -20,41 +24,118 @@ func (o Encoder) Encode(
 	ctx context.Context,
 	utterance string,
 ) ([]float64, error) {
-	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-			if o.Client == nil {
-				return nil, fmt.Errorf("OpenAI client is nil")
-			}
-			if o.Model == "" {
-				return nil, fmt.Errorf("OpenAI model is empty")
-			}
-			queryReq := openai.EmbeddingRequest{
-				Input: utterance,
-				Model: o.Model,
-			}
-			queryResponse, err := o.Client.CreateEmbeddings(
-				context.Background(),
-				queryReq,
+	embeddings, err := o.embed(ctx, []string{utterance})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EncodeBatch encodes the given utterances using the OpenAI API.
+//
+// The utterances are sent in requests of at most MaxBatchSize inputs.
+func (o Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	return o.embed(ctx, utterances)
+}
+
+// EncodeFloat32 encodes the given utterances into float32 embeddings, as
+// returned by the OpenAI API, in requests of at most MaxBatchSize inputs.
+func (o Encoder) EncodeFloat32(
+	ctx context.Context,
+	utterances []string,
+) ([][]float32, error) {
+	return o.embedBatch32(ctx, utterances)
+}
+
+// embed embeds the given inputs in requests of at most MaxBatchSize inputs.
+func (o Encoder) embed(
+	ctx context.Context,
+	inputs []string,
+) ([][]float64, error) {
+	embeddings, err := o.embedBatch32(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]float64, len(embeddings))
+	for i, embedding := range embeddings {
+		result[i] = semanticrouter.Convert[float64](embedding)
+	}
+	return result, nil
+}
+
+// embedBatch32 embeds the given inputs in requests of at most MaxBatchSize
+// inputs and returns their float32 embeddings in order.
+func (o Encoder) embedBatch32(
+	ctx context.Context,
+	inputs []string,
+) ([][]float32, error) {
+	result := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(inputs))
+		embeddings, err := o.embed32(ctx, inputs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, embeddings...)
+	}
+	return result, nil
+}
+
+// embed32 sends a single embeddings request for the given inputs and returns
+// the float32 embeddings of the response.
+func (o Encoder) embed32(
+	ctx context.Context,
+	inputs []string,
+) ([][]float32, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	if o.Client == nil {
+		return nil, fmt.Errorf("OpenAI client is nil")
+	}
+	if o.Model == "" {
+		return nil, fmt.Errorf("OpenAI model is empty")
+	}
+	queryReq := openai.EmbeddingRequest{
+		Input: inputs,
+		Model: o.Model,
+	}
+	queryResponse, err := o.Client.CreateEmbeddings(ctx, queryReq)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error creating query embedding: %w",
+			err,
+		)
+	}
+	if len(queryResponse.Data) != len(inputs) {
+		return nil, fmt.Errorf(
+			"error creating query embedding: got %d embeddings for %d inputs",
+			len(queryResponse.Data),
+			len(inputs),
+		)
+	}
+	embeddings := make([][]float32, len(inputs))
+	for _, data := range queryResponse.Data {
+		if data.Index < 0 || data.Index >= len(inputs) {
+			return nil, fmt.Errorf(
+				"error creating query embedding: embedding index %d out of range",
+				data.Index,
+			)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf(
+				"error creating query embedding: no embedding for input %d",
+				i,
 			)
-			if err != nil {
-				return nil, fmt.Errorf(
-					"error creating query embedding: %w",
-					err,
-				)
-			}
-			var floats []float32
-			for _, f := range queryResponse.Data[0].Embedding {
-				floats = append(floats, float32(f))
-			}
-			// Convert the float32 slice to a float64 slice
-			floats64 := make([]float64, len(floats))
-			for i, f := range floats {
-				floats64[i] = float64(f)
-			}
-			return floats64, nil
 		}
 	}
+	return embeddings, nil
 }
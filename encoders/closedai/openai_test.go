@@ -0,0 +1,98 @@
+package closedai_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go/encoders/closedai"
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEncoderBatchChunks tests that batches are split into requests of at
+// most MaxBatchSize inputs and that the embeddings keep the input order.
+func TestEncoderBatchChunks(t *testing.T) {
+	a := assert.New(t)
+	var sizes []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		var req struct {
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		sizes = append(sizes, len(req.Input))
+		resp := openai.EmbeddingResponse{Object: "list"}
+		for i, input := range req.Input {
+			var n float32
+			_, err := fmt.Sscan(input, &n)
+			assert.NoError(t, err)
+			resp.Data = append(resp.Data, openai.Embedding{
+				Object:    "embedding",
+				Index:     i,
+				Embedding: []float32{n},
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	t.Cleanup(srv.Close)
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = srv.URL + "/v1"
+	encoder := closedai.Encoder{
+		Client: openai.NewClientWithConfig(config),
+		Model:  openai.SmallEmbedding3,
+	}
+
+	utterances := make([]string, 2*closedai.MaxBatchSize+50)
+	for i := range utterances {
+		utterances[i] = fmt.Sprint(i)
+	}
+	result, err := encoder.EncodeBatch(context.Background(), utterances)
+	require.NoError(t, err)
+	a.Equal([]int{closedai.MaxBatchSize, closedai.MaxBatchSize, 50}, sizes)
+	require.Len(t, result, len(utterances))
+	for i, embedding := range result {
+		a.Equal([]float64{float64(i)}, embedding)
+	}
+}
+
+// TestEncoderInvalidIndex tests that responses whose indices leave an input
+// without an embedding are rejected.
+func TestEncoderInvalidIndex(t *testing.T) {
+	for name, tt := range map[string]struct {
+		indices  []int
+		expected string
+	}{
+		"out of range": {[]int{0, 2}, "embedding index 2 out of range"},
+		"duplicate":    {[]int{1, 1}, "no embedding for input 0"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				resp := openai.EmbeddingResponse{Object: "list"}
+				for _, index := range tt.indices {
+					resp.Data = append(resp.Data, openai.Embedding{
+						Object:    "embedding",
+						Index:     index,
+						Embedding: []float32{1},
+					})
+				}
+				w.Header().Set("Content-Type", "application/json")
+				assert.NoError(t, json.NewEncoder(w).Encode(resp))
+			}))
+			t.Cleanup(srv.Close)
+			config := openai.DefaultConfig("test-key")
+			config.BaseURL = srv.URL + "/v1"
+			encoder := closedai.Encoder{
+				Client: openai.NewClientWithConfig(config),
+				Model:  openai.SmallEmbedding3,
+			}
+			_, err := encoder.EncodeBatch(context.Background(), []string{"a", "b"})
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
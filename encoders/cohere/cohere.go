@@ -0,0 +1,386 @@
+package cohere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the Cohere API.
+	DefaultBaseURL = "https://api.cohere.com"
+	// MaxBatchSize is the maximum number of texts Cohere embeds per request.
+	MaxBatchSize = 96
+)
+
+// InputType is the kind of text being embedded.
+type InputType string
+
+const (
+	// InputTypeSearchDocument is used for texts that are stored and searched
+	// against, such as the utterances of a route.
+	InputTypeSearchDocument InputType = "search_document"
+	// InputTypeSearchQuery is used for texts that are searched with, such as
+	// the utterance given to Match.
+	InputTypeSearchQuery InputType = "search_query"
+	// InputTypeClassification is used for texts passed to a text classifier.
+	InputTypeClassification InputType = "classification"
+	// InputTypeClustering is used for texts that are clustered.
+	InputTypeClustering InputType = "clustering"
+)
+
+// EmbeddingType is the numeric representation of the returned embeddings.
+type EmbeddingType string
+
+const (
+	// EmbeddingTypeFloat returns float embeddings.
+	EmbeddingTypeFloat EmbeddingType = "float"
+	// EmbeddingTypeInt8 returns signed int8 embeddings.
+	EmbeddingTypeInt8 EmbeddingType = "int8"
+	// EmbeddingTypeUint8 returns unsigned int8 embeddings.
+	EmbeddingTypeUint8 EmbeddingType = "uint8"
+	// EmbeddingTypeBinary returns sign-bit packed embeddings stored as signed
+	// bytes offset by 128.
+	EmbeddingTypeBinary EmbeddingType = "binary"
+	// EmbeddingTypeUbinary returns sign-bit packed embeddings stored as
+	// unsigned bytes.
+	EmbeddingTypeUbinary EmbeddingType = "ubinary"
+)
+
+// Encoder is an encoder using Cohere embedding models.
+//
+// Binary embeddings are unpacked into one value per dimension, +1 for a set
+// bit and -1 for an unset bit, so they can be compared like float embeddings.
+type Encoder struct {
+	// APIKey is the Cohere API key.
+	APIKey string
+	// Model is the Cohere embedding model to use.
+	Model string
+	// InputType is the input type sent with each request.
+	InputType InputType
+	// EmbeddingType is the embedding type requested from the API.
+	EmbeddingType EmbeddingType
+	// Truncate is how the API handles inputs longer than the maximum token
+	// length: NONE, START or END. It is left to the API default if empty.
+	Truncate string
+	// BaseURL is the base URL of the Cohere API.
+	BaseURL string
+	// HTTPClient is the client used to send requests.
+	HTTPClient *http.Client
+}
+
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithInputType sets the input type sent with each request.
+func WithInputType(inputType InputType) Option {
+	return func(e *Encoder) {
+		e.InputType = inputType
+	}
+}
+
+// WithEmbeddingType sets the embedding type requested from the API.
+func WithEmbeddingType(embeddingType EmbeddingType) Option {
+	return func(e *Encoder) {
+		e.EmbeddingType = embeddingType
+	}
+}
+
+// WithTruncate sets how the API handles inputs that are too long.
+func WithTruncate(truncate string) Option {
+	return func(e *Encoder) {
+		e.Truncate = truncate
+	}
+}
+
+// WithBaseURL sets the base URL of the Cohere API.
+func WithBaseURL(baseURL string) Option {
+	return func(e *Encoder) {
+		e.BaseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the client used to send requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Encoder) {
+		e.HTTPClient = client
+	}
+}
+
+// NewEncoder creates a new Encoder.
+//
+// By default it embeds search documents as float embeddings.
+func NewEncoder(apiKey, model string, opts ...Option) *Encoder {
+	e := &Encoder{
+		APIKey:        apiKey,
+		Model:         model,
+		InputType:     InputTypeSearchDocument,
+		EmbeddingType: EmbeddingTypeFloat,
+		BaseURL:       DefaultBaseURL,
+		HTTPClient:    http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// embedRequest is the body of a request to the embed endpoint.
+type embedRequest struct {
+	Texts          []string        `json:"texts"`
+	Model          string          `json:"model"`
+	InputType      InputType       `json:"input_type,omitempty"`
+	EmbeddingTypes []EmbeddingType `json:"embedding_types"`
+	Truncate       string          `json:"truncate,omitempty"`
+}
+
+// embedResponse is the body of a response from the embed endpoint when
+// embedding types are requested.
+type embedResponse struct {
+	ID         string `json:"id"`
+	Embeddings struct {
+		Float   [][]float64 `json:"float"`
+		Int8    [][]int     `json:"int8"`
+		Uint8   [][]int     `json:"uint8"`
+		Binary  [][]int     `json:"binary"`
+		Ubinary [][]int     `json:"ubinary"`
+	} `json:"embeddings"`
+	Texts        []string `json:"texts"`
+	ResponseType string   `json:"response_type"`
+}
+
+// errorResponse is the body of an error response from the Cohere API.
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// Encode encodes a query string into a Cohere embedding.
+func (e *Encoder) Encode(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	embeddings, err := e.embed(ctx, []string{query}, e.InputType)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EncodeBatch encodes the given utterances into Cohere embeddings.
+//
+// The utterances are sent in requests of at most MaxBatchSize texts.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	return e.embedBatch(ctx, utterances, e.InputType)
+}
+
+// EncodeQuery encodes the given query.
+//
+// If the encoder's input type is a search input type, the query is embedded
+// with InputTypeSearchQuery, otherwise with the encoder's input type.
+func (e *Encoder) EncodeQuery(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	embeddings, err := e.embed(ctx, []string{query}, e.inputType(InputTypeSearchQuery))
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EncodeDocuments encodes the given documents.
+//
+// If the encoder's input type is a search input type, the documents are
+// embedded with InputTypeSearchDocument, otherwise with the encoder's input
+// type.
+func (e *Encoder) EncodeDocuments(
+	ctx context.Context,
+	documents []string,
+) ([][]float64, error) {
+	return e.embedBatch(ctx, documents, e.inputType(InputTypeSearchDocument))
+}
+
+// inputType returns the given search input type if the encoder is configured
+// for search, and the encoder's input type otherwise.
+func (e *Encoder) inputType(search InputType) InputType {
+	switch e.InputType {
+	case "", InputTypeSearchDocument, InputTypeSearchQuery:
+		return search
+	default:
+		return e.InputType
+	}
+}
+
+// embedBatch embeds the given texts in requests of at most MaxBatchSize
+// texts.
+func (e *Encoder) embedBatch(
+	ctx context.Context,
+	texts []string,
+	inputType InputType,
+) ([][]float64, error) {
+	result := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(texts))
+		embeddings, err := e.embed(ctx, texts[start:end], inputType)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, embeddings...)
+	}
+	return result, nil
+}
+
+// embed sends a single request to the embed endpoint.
+func (e *Encoder) embed(
+	ctx context.Context,
+	texts []string,
+	inputType InputType,
+) ([][]float64, error) {
+	embeddingType := e.EmbeddingType
+	if embeddingType == "" {
+		embeddingType = EmbeddingTypeFloat
+	}
+	body, err := json.Marshal(embedRequest{
+		Texts:          texts,
+		Model:          e.Model,
+		InputType:      inputType,
+		EmbeddingTypes: []EmbeddingType{embeddingType},
+		Truncate:       e.Truncate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling embed request: %w", err)
+	}
+	var resp embedResponse
+	err = e.do(ctx, "/v1/embed", body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := resp.decode(embeddingType)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf(
+			"error creating embeddings: got %d embeddings for %d texts",
+			len(embeddings),
+			len(texts),
+		)
+	}
+	return embeddings, nil
+}
+
+// do sends a POST request with the given body to the given path and decodes
+// the JSON response into out.
+func (e *Encoder) do(
+	ctx context.Context,
+	path string,
+	body []byte,
+	out any,
+) error {
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		strings.TrimSuffix(baseURL, "/")+path,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error creating embeddings: %w", err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var apiErr errorResponse
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf(
+				"error creating embeddings: %s: %s",
+				res.Status,
+				apiErr.Message,
+			)
+		}
+		return fmt.Errorf("error creating embeddings: %s", res.Status)
+	}
+	err = json.Unmarshal(data, out)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return nil
+}
+
+// decode returns the embeddings of the given type as float64 vectors.
+func (r *embedResponse) decode(
+	embeddingType EmbeddingType,
+) ([][]float64, error) {
+	switch embeddingType {
+	case EmbeddingTypeFloat:
+		return r.Embeddings.Float, nil
+	case EmbeddingTypeInt8:
+		return convertInts(r.Embeddings.Int8), nil
+	case EmbeddingTypeUint8:
+		return convertInts(r.Embeddings.Uint8), nil
+	case EmbeddingTypeBinary:
+		return unpackBits(r.Embeddings.Binary, 128), nil
+	case EmbeddingTypeUbinary:
+		return unpackBits(r.Embeddings.Ubinary, 0), nil
+	default:
+		return nil, fmt.Errorf("unsupported embedding type: %s", embeddingType)
+	}
+}
+
+// convertInts converts integer embeddings to float64 embeddings.
+func convertInts(embeddings [][]int) [][]float64 {
+	result := make([][]float64, len(embeddings))
+	for i, embedding := range embeddings {
+		result[i] = make([]float64, len(embedding))
+		for j, v := range embedding {
+			result[i][j] = float64(v)
+		}
+	}
+	return result
+}
+
+// unpackBits unpacks sign-bit packed embeddings, most significant bit first,
+// into +1 and -1 values.
+//
+// The offset is added to each packed value to recover the unsigned byte.
+func unpackBits(embeddings [][]int, offset int) [][]float64 {
+	result := make([][]float64, len(embeddings))
+	for i, embedding := range embeddings {
+		result[i] = make([]float64, 0, len(embedding)*8)
+		for _, v := range embedding {
+			b := byte(v + offset)
+			for bit := 7; bit >= 0; bit-- {
+				if b&(1<<bit) != 0 {
+					result[i] = append(result[i], 1)
+				} else {
+					result[i] = append(result[i], -1)
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,182 @@
+package cohere_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/cohere"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder              = (*cohere.Encoder)(nil)
+	_ semanticrouter.BatchEncoder         = (*cohere.Encoder)(nil)
+	_ semanticrouter.QueryDocumentEncoder = (*cohere.Encoder)(nil)
+)
+
+// request is the body of a request received by the fake Cohere API.
+type request struct {
+	Texts          []string `json:"texts"`
+	Model          string   `json:"model"`
+	InputType      string   `json:"input_type"`
+	EmbeddingTypes []string `json:"embedding_types"`
+	Truncate       string   `json:"truncate"`
+}
+
+// newFakeServer returns a server that replays the given recorded response and
+// records the requests it receives.
+func newFakeServer(
+	t *testing.T,
+	status int,
+	fixture string,
+	requests *[]request,
+) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/embed", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var req request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		*requests = append(*requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestEncoder tests encoding a single utterance.
+func TestEncoder(t *testing.T) {
+	a := assert.New(t)
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embed_int8.json", &requests)
+	encoder := cohere.NewEncoder(
+		"test-key",
+		"embed-english-v3.0",
+		cohere.WithBaseURL(srv.URL),
+		cohere.WithInputType(cohere.InputTypeSearchQuery),
+		cohere.WithEmbeddingType(cohere.EmbeddingTypeInt8),
+		cohere.WithTruncate("END"),
+	)
+	result, err := encoder.Encode(context.Background(), "how's the weather today?")
+	a.NoError(err)
+	a.Equal([]float64{-21, -65, 87, 40, -118, 6, 72, -3}, result)
+	a.Len(requests, 1)
+	a.Equal(request{
+		Texts:          []string{"how's the weather today?"},
+		Model:          "embed-english-v3.0",
+		InputType:      "search_query",
+		EmbeddingTypes: []string{"int8"},
+		Truncate:       "END",
+	}, requests[0])
+}
+
+// TestEncoderBatch tests encoding several utterances in one request.
+func TestEncoderBatch(t *testing.T) {
+	a := assert.New(t)
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embed_float.json", &requests)
+	encoder := cohere.NewEncoder(
+		"test-key",
+		"embed-english-v3.0",
+		cohere.WithBaseURL(srv.URL),
+		cohere.WithHTTPClient(srv.Client()),
+	)
+	result, err := encoder.EncodeBatch(context.Background(), []string{
+		"how's the weather today?",
+		"why don't you tell me about your political opinions",
+	})
+	a.NoError(err)
+	a.Len(result, 2)
+	a.Len(result[0], 8)
+	a.InDelta(0.016296387, result[0][0], 1e-9)
+	a.InDelta(0.0031528473, result[1][7], 1e-9)
+	a.Len(requests, 1)
+	a.Equal("search_document", requests[0].InputType)
+	a.Equal([]string{"float"}, requests[0].EmbeddingTypes)
+}
+
+// TestEncoderBinary tests unpacking binary embeddings.
+func TestEncoderBinary(t *testing.T) {
+	a := assert.New(t)
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embed_binary.json", &requests)
+	encoder := cohere.NewEncoder(
+		"test-key",
+		"embed-english-v3.0",
+		cohere.WithBaseURL(srv.URL),
+		cohere.WithEmbeddingType(cohere.EmbeddingTypeBinary),
+	)
+	result, err := encoder.Encode(context.Background(), "how's the weather today?")
+	a.NoError(err)
+	a.Equal([]float64{
+		-1, -1, -1, -1, -1, -1, -1, -1,
+		1, 1, 1, 1, 1, 1, 1, 1,
+	}, result)
+}
+
+// TestEncoderMismatchedCount tests that a response with the wrong number of
+// embeddings is rejected.
+func TestEncoderMismatchedCount(t *testing.T) {
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embed_float.json", &requests)
+	encoder := cohere.NewEncoder(
+		"test-key",
+		"embed-english-v3.0",
+		cohere.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.Encode(context.Background(), "how's the weather today?")
+	assert.Error(t, err)
+}
+
+// TestEncoderError tests that API errors are returned.
+func TestEncoderError(t *testing.T) {
+	var requests []request
+	srv := newFakeServer(t, http.StatusUnauthorized, "error_unauthorized.json", &requests)
+	encoder := cohere.NewEncoder(
+		"test-key",
+		"embed-english-v3.0",
+		cohere.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.Encode(context.Background(), "how's the weather today?")
+	assert.ErrorContains(t, err, "invalid api token")
+}
+
+// TestEncoderQueryDocument tests that queries and documents are embedded with
+// the search input types.
+func TestEncoderQueryDocument(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embed_float.json", &requests)
+	encoder := cohere.NewEncoder(
+		"test-key",
+		"embed-english-v3.0",
+		cohere.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.EncodeDocuments(ctx, []string{
+		"how's the weather today?",
+		"why don't you tell me about your political opinions",
+	})
+	a.NoError(err)
+	_, err = encoder.EncodeQuery(ctx, "is it sunny?")
+	a.Error(err, "a response with two embeddings for one query is rejected")
+	encoder.InputType = cohere.InputTypeClassification
+	_, err = encoder.EncodeDocuments(ctx, []string{"a", "b"})
+	a.NoError(err)
+	a.Len(requests, 3)
+	a.Equal("search_document", requests[0].InputType)
+	a.Equal("search_query", requests[1].InputType)
+	a.Equal("classification", requests[2].InputType)
+}
@@ -0,0 +1,6 @@
+// Package cohere provides encoders for Cohere embedding models.
+//
+// Cohere embedding models distinguish between the documents that are stored
+// (search_document) and the queries that are compared against them
+// (search_query), and can return float, int8 or binary embeddings.
+package cohere
@@ -1,4 +1,6 @@
-// Package encoders provides encoders for Google language models.
+// Package google provides encoders for Google embedding models.
 //
-// Google language models are language models that is trained on a large corpus of text data.
-package encoders
+// Google embedding models, such as text-embedding-004, are served by the
+// Gemini API and can tailor embeddings to a task type such as retrieval,
+// classification or semantic similarity.
+package google
@@ -1,47 +1,271 @@
-package encoders
+package google
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
-	"github.com/google/generative-ai-go/genai"
+	generativelanguage "cloud.google.com/go/ai/generativelanguage/apiv1beta"
+	"cloud.google.com/go/ai/generativelanguage/apiv1beta/generativelanguagepb"
 )
 
-// GoogleEncoder encodes a query string into a Google search URL.
-type GoogleEncoder struct {
-	client genai.Client
-	name   string
+const (
+	// DefaultModel is the default Google embedding model.
+	DefaultModel = "text-embedding-004"
+	// MaxBatchSize is the maximum number of contents embedded per batch
+	// request.
+	MaxBatchSize = 100
+)
+
+// TaskType is the type of task the embeddings will be used for.
+type TaskType = generativelanguagepb.TaskType
+
+const (
+	// TaskTypeUnspecified leaves the task type to the model default.
+	TaskTypeUnspecified = generativelanguagepb.TaskType_TASK_TYPE_UNSPECIFIED
+	// TaskTypeRetrievalQuery is used for queries in a search setting.
+	TaskTypeRetrievalQuery = generativelanguagepb.TaskType_RETRIEVAL_QUERY
+	// TaskTypeRetrievalDocument is used for documents of the searched corpus.
+	TaskTypeRetrievalDocument = generativelanguagepb.TaskType_RETRIEVAL_DOCUMENT
+	// TaskTypeSemanticSimilarity is used for semantic textual similarity.
+	TaskTypeSemanticSimilarity = generativelanguagepb.TaskType_SEMANTIC_SIMILARITY
+	// TaskTypeClassification is used for texts that will be classified.
+	TaskTypeClassification = generativelanguagepb.TaskType_CLASSIFICATION
+	// TaskTypeClustering is used for texts that will be clustered.
+	TaskTypeClustering = generativelanguagepb.TaskType_CLUSTERING
+)
+
+// Encoder encodes a query string into a Google embedding.
+//
+// The client can be created with generativelanguage.NewGenerativeRESTClient
+// and an API key option:
+//
+//	client, err := generativelanguage.NewGenerativeRESTClient(
+//		ctx,
+//		option.WithAPIKey(os.Getenv("GEMINI_API_KEY")),
+//	)
+type Encoder struct {
+	// Client is the Gemini API client.
+	Client *generativelanguage.GenerativeClient
+	// Model is the embedding model to use, for example text-embedding-004.
+	Model string
+	// TaskType is the task type sent with each request.
+	TaskType TaskType
+	// OutputDimensionality truncates the embeddings to the given number of
+	// dimensions. It is left to the model default if zero.
+	OutputDimensionality int32
+	// Title is the title sent with each request. It is only used with the
+	// TaskTypeRetrievalDocument task type.
+	Title string
+}
+
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithTaskType sets the task type sent with each request.
+func WithTaskType(taskType TaskType) Option {
+	return func(e *Encoder) {
+		e.TaskType = taskType
+	}
+}
+
+// WithOutputDimensionality sets the number of dimensions of the embeddings.
+func WithOutputDimensionality(dimensions int32) Option {
+	return func(e *Encoder) {
+		e.OutputDimensionality = dimensions
+	}
+}
+
+// WithTitle sets the title sent with each retrieval document request.
+func WithTitle(title string) Option {
+	return func(e *Encoder) {
+		e.Title = title
+	}
 }
 
-// NewGoogleEncoder creates a new GoogleEncoder.
-func NewGoogleEncoder(
-	client genai.Client,
-) *GoogleEncoder {
-	return &GoogleEncoder{
-		client: client,
+// NewEncoder creates a new Encoder.
+//
+// If model is empty, DefaultModel is used.
+func NewEncoder(
+	client *generativelanguage.GenerativeClient,
+	model string,
+	opts ...Option,
+) *Encoder {
+	if model == "" {
+		model = DefaultModel
 	}
+	e := &Encoder{
+		Client: client,
+		Model:  model,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Encode encodes a query string into a Google embedding.
+func (e *Encoder) Encode(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	return e.EncodeWithTitle(ctx, e.Title, query)
+}
+
+// EncodeWithTitle encodes a text with the given title into a Google
+// embedding.
+//
+// The title is only used with the TaskTypeRetrievalDocument task type.
+func (e *Encoder) EncodeWithTitle(
+	ctx context.Context,
+	title string,
+	text string,
+) ([]float64, error) {
+	if e.Client == nil {
+		return nil, fmt.Errorf("google client is nil")
+	}
+	resp, err := e.Client.EmbedContent(
+		ctx,
+		e.request(text, title, e.TaskType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating embedding: %w", err)
+	}
+	return convert(resp.GetEmbedding()), nil
+}
+
+// EncodeBatch encodes the given utterances using BatchEmbedContents.
+//
+// The utterances are sent in requests of at most MaxBatchSize contents.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	return e.embedBatch(ctx, utterances, e.TaskType)
 }
 
-// Encode encodes a query string into a Google search URL.
-func (e *GoogleEncoder) Encode(
+// EncodeQuery encodes the given query.
+//
+// If the encoder's task type is unspecified, the query is embedded with the
+// TaskTypeRetrievalQuery task type, otherwise with the encoder's task type.
+func (e *Encoder) EncodeQuery(
 	ctx context.Context,
 	query string,
 ) ([]float64, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-		model := e.client.EmbeddingModel(e.name)
-		embedding, err := model.EmbedContent(ctx, genai.Text(query))
+	if e.Client == nil {
+		return nil, fmt.Errorf("google client is nil")
+	}
+	taskType := e.TaskType
+	if taskType == TaskTypeUnspecified {
+		taskType = TaskTypeRetrievalQuery
+	}
+	resp, err := e.Client.EmbedContent(
+		ctx,
+		e.request(query, "", taskType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating embedding: %w", err)
+	}
+	return convert(resp.GetEmbedding()), nil
+}
+
+// EncodeDocuments encodes the given documents using BatchEmbedContents.
+//
+// If the encoder's task type is unspecified, the documents are embedded with
+// the TaskTypeRetrievalDocument task type and the encoder's title, otherwise
+// with the encoder's task type.
+func (e *Encoder) EncodeDocuments(
+	ctx context.Context,
+	documents []string,
+) ([][]float64, error) {
+	taskType := e.TaskType
+	if taskType == TaskTypeUnspecified {
+		taskType = TaskTypeRetrievalDocument
+	}
+	return e.embedBatch(ctx, documents, taskType)
+}
+
+// embedBatch embeds the given texts with the given task type.
+func (e *Encoder) embedBatch(
+	ctx context.Context,
+	texts []string,
+	taskType TaskType,
+) ([][]float64, error) {
+	if e.Client == nil {
+		return nil, fmt.Errorf("google client is nil")
+	}
+	result := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(texts))
+		req := &generativelanguagepb.BatchEmbedContentsRequest{
+			Model: e.modelName(),
+		}
+		for _, text := range texts[start:end] {
+			req.Requests = append(req.Requests, e.request(text, e.Title, taskType))
+		}
+		resp, err := e.Client.BatchEmbedContents(ctx, req)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("error creating embeddings: %w", err)
 		}
-		// type float32
-		a := embedding.Embedding.Values
-		// convert to []float64
-		b := make([]float64, len(a))
-		for i, v := range a {
-			b[i] = float64(v)
+		if len(resp.GetEmbeddings()) != end-start {
+			return nil, fmt.Errorf(
+				"error creating embeddings: got %d embeddings for %d texts",
+				len(resp.GetEmbeddings()),
+				end-start,
+			)
 		}
-		return b, nil
+		for _, embedding := range resp.GetEmbeddings() {
+			result = append(result, convert(embedding))
+		}
+	}
+	return result, nil
+}
+
+// request builds the embed content request of a single text.
+func (e *Encoder) request(
+	text string,
+	title string,
+	taskType TaskType,
+) *generativelanguagepb.EmbedContentRequest {
+	req := &generativelanguagepb.EmbedContentRequest{
+		Model: e.modelName(),
+		Content: &generativelanguagepb.Content{
+			Parts: []*generativelanguagepb.Part{{
+				Data: &generativelanguagepb.Part_Text{Text: text},
+			}},
+		},
+	}
+	if taskType != TaskTypeUnspecified {
+		req.TaskType = &taskType
+	}
+	if title != "" && taskType == TaskTypeRetrievalDocument {
+		req.Title = &title
+	}
+	if e.OutputDimensionality > 0 {
+		dimensions := e.OutputDimensionality
+		req.OutputDimensionality = &dimensions
+	}
+	return req
+}
+
+// modelName returns the full resource name of the encoder's model.
+func (e *Encoder) modelName() string {
+	model := e.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	if strings.Contains(model, "/") {
+		return model
+	}
+	return "models/" + model
+}
+
+// convert converts a float32 embedding into a float64 embedding.
+func convert(embedding *generativelanguagepb.ContentEmbedding) []float64 {
+	values := embedding.GetValues()
+	result := make([]float64, len(values))
+	for i, v := range values {
+		result[i] = float64(v)
 	}
+	return result
 }
@@ -0,0 +1,156 @@
+package google_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	generativelanguage "cloud.google.com/go/ai/generativelanguage/apiv1beta"
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/google"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+var (
+	_ semanticrouter.Encoder              = (*google.Encoder)(nil)
+	_ semanticrouter.BatchEncoder         = (*google.Encoder)(nil)
+	_ semanticrouter.QueryDocumentEncoder = (*google.Encoder)(nil)
+)
+
+// call is a request received by the fake Gemini API.
+type call struct {
+	Path string
+	Body map[string]any
+}
+
+// newFakeClient returns a client of a fake Gemini API that replays the given
+// recorded response and records the requests it receives.
+func newFakeClient(
+	t *testing.T,
+	fixture string,
+	calls *[]call,
+) *generativelanguage.GenerativeClient {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		key := r.Header.Get("X-Goog-Api-Key")
+		if key == "" {
+			key = r.URL.Query().Get("key")
+		}
+		assert.Equal(t, "test-key", key)
+		var decoded map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&decoded))
+		*calls = append(*calls, call{Path: r.URL.Path, Body: decoded})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	client, err := generativelanguage.NewGenerativeRESTClient(
+		context.Background(),
+		option.WithEndpoint(srv.URL),
+		option.WithAPIKey("test-key"),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// TestEncoder tests encoding a single utterance.
+func TestEncoder(t *testing.T) {
+	a := assert.New(t)
+	var calls []call
+	client := newFakeClient(t, "embed_content.json", &calls)
+	encoder := google.NewEncoder(
+		client,
+		"",
+		google.WithTaskType(google.TaskTypeClassification),
+		google.WithOutputDimensionality(4),
+	)
+	result, err := encoder.Encode(context.Background(), "how's the weather today?")
+	a.NoError(err)
+	a.Len(result, 4)
+	a.InDelta(0.013168523, result[0], 1e-9)
+	a.Len(calls, 1)
+	a.Equal("/v1beta/models/text-embedding-004:embedContent", calls[0].Path)
+	a.Equal("models/text-embedding-004", calls[0].Body["model"])
+	a.EqualValues(4, calls[0].Body["taskType"])
+	a.EqualValues(4, calls[0].Body["outputDimensionality"])
+	a.NotContains(calls[0].Body, "title")
+}
+
+// TestEncoderWithTitle tests that titles are sent with retrieval documents.
+func TestEncoderWithTitle(t *testing.T) {
+	a := assert.New(t)
+	var calls []call
+	client := newFakeClient(t, "embed_content.json", &calls)
+	encoder := google.NewEncoder(
+		client,
+		"models/text-embedding-004",
+		google.WithTaskType(google.TaskTypeRetrievalDocument),
+	)
+	_, err := encoder.EncodeWithTitle(context.Background(), "weather", "lovely weather today")
+	a.NoError(err)
+	a.Len(calls, 1)
+	a.Equal("weather", calls[0].Body["title"])
+	a.EqualValues(2, calls[0].Body["taskType"])
+}
+
+// TestEncoderBatch tests encoding several utterances with BatchEmbedContents.
+func TestEncoderBatch(t *testing.T) {
+	a := assert.New(t)
+	var calls []call
+	client := newFakeClient(t, "batch_embed_contents.json", &calls)
+	encoder := google.NewEncoder(
+		client,
+		"text-embedding-004",
+		google.WithTaskType(google.TaskTypeSemanticSimilarity),
+	)
+	result, err := encoder.EncodeBatch(context.Background(), []string{
+		"how's the weather today?",
+		"lovely weather today",
+	})
+	a.NoError(err)
+	a.Len(result, 2)
+	a.InDelta(0.045951966, result[1][0], 1e-9)
+	a.Len(calls, 1)
+	a.Equal("/v1beta/models/text-embedding-004:batchEmbedContents", calls[0].Path)
+	requests, ok := calls[0].Body["requests"].([]any)
+	a.True(ok)
+	a.Len(requests, 2)
+	a.EqualValues(3, requests[1].(map[string]any)["taskType"])
+}
+
+// TestEncoderQueryDocument tests that queries and documents are embedded with
+// the retrieval task types when no task type is configured.
+func TestEncoderQueryDocument(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	var calls []call
+	client := newFakeClient(t, "batch_embed_contents.json", &calls)
+	encoder := google.NewEncoder(client, "", google.WithTitle("chitchat"))
+	documents, err := encoder.EncodeDocuments(ctx, []string{
+		"how's the weather today?",
+		"lovely weather today",
+	})
+	a.NoError(err)
+	a.Len(documents, 2)
+	requests := calls[0].Body["requests"].([]any)
+	a.EqualValues(2, requests[0].(map[string]any)["taskType"])
+	a.Equal("chitchat", requests[0].(map[string]any)["title"])
+
+	calls = nil
+	client = newFakeClient(t, "embed_content.json", &calls)
+	encoder.Client = client
+	_, err = encoder.EncodeQuery(ctx, "is it sunny?")
+	a.NoError(err)
+	a.EqualValues(1, calls[0].Body["taskType"])
+	a.NotContains(calls[0].Body, "title")
+}
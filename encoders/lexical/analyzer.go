@@ -0,0 +1,127 @@
+package lexical
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NGramRange is an inclusive range of n-gram lengths.
+//
+// A range with a zero Max produces no n-grams.
+type NGramRange struct {
+	// Min is the shortest n-gram length.
+	Min int
+	// Max is the longest n-gram length.
+	Max int
+}
+
+// Analyzer splits texts into the terms counted by the encoders.
+//
+// Texts are lowercased and split into words at every rune that is neither a
+// letter nor a number. Word n-grams are joined with a space. Character
+// n-grams are taken from each word padded with '<' and '>' and are prefixed
+// with '#', so that they never collide with word n-grams.
+type Analyzer struct {
+	// WordNGrams is the range of word n-gram lengths.
+	WordNGrams NGramRange
+	// CharNGrams is the range of character n-gram lengths.
+	CharNGrams NGramRange
+	// StopWords are words that are dropped before building n-grams.
+	StopWords map[string]struct{}
+}
+
+// DefaultAnalyzer returns the analyzer used by the encoders unless
+// configured otherwise: word unigrams and bigrams without stop words.
+func DefaultAnalyzer() Analyzer {
+	return Analyzer{
+		WordNGrams: NGramRange{Min: 1, Max: 2},
+	}
+}
+
+// Option is a function that configures the Analyzer of an encoder.
+type Option func(*Analyzer)
+
+// WithWordNGrams sets the range of word n-gram lengths.
+func WithWordNGrams(minN, maxN int) Option {
+	return func(a *Analyzer) {
+		a.WordNGrams = NGramRange{Min: minN, Max: maxN}
+	}
+}
+
+// WithCharNGrams sets the range of character n-gram lengths.
+//
+// Character n-grams make the encoders robust to typos and inflections at the
+// cost of larger, noisier vectors.
+func WithCharNGrams(minN, maxN int) Option {
+	return func(a *Analyzer) {
+		a.CharNGrams = NGramRange{Min: minN, Max: maxN}
+	}
+}
+
+// WithStopWords sets the words dropped before building n-grams.
+func WithStopWords(words ...string) Option {
+	return func(a *Analyzer) {
+		a.StopWords = make(map[string]struct{}, len(words))
+		for _, word := range words {
+			a.StopWords[strings.ToLower(word)] = struct{}{}
+		}
+	}
+}
+
+// Words returns the lowercased words of the given text without stop words.
+func (a *Analyzer) Words(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(a.StopWords) == 0 {
+		return words
+	}
+	kept := words[:0]
+	for _, word := range words {
+		if _, ok := a.StopWords[word]; !ok {
+			kept = append(kept, word)
+		}
+	}
+	return kept
+}
+
+// Terms returns the word and character n-grams of the given text, in order
+// and with repetitions.
+func (a *Analyzer) Terms(text string) []string {
+	words := a.Words(text)
+	var terms []string
+	for n := max(a.WordNGrams.Min, 1); n <= a.WordNGrams.Max; n++ {
+		for i := 0; i+n <= len(words); i++ {
+			terms = append(terms, strings.Join(words[i:i+n], " "))
+		}
+	}
+	if a.CharNGrams.Max <= 0 {
+		return terms
+	}
+	for _, word := range words {
+		runes := []rune("<" + word + ">")
+		for n := max(a.CharNGrams.Min, 1); n <= a.CharNGrams.Max; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				terms = append(terms, "#"+string(runes[i:i+n]))
+			}
+		}
+	}
+	return terms
+}
+
+// counts returns the distinct terms of the given text in order of first
+// occurrence and the number of occurrences of each of them.
+//
+// Iterating the terms in a fixed order keeps the floating point sums of the
+// encoders deterministic.
+func (a *Analyzer) counts(text string) ([]string, map[string]int) {
+	var terms []string
+	counts := make(map[string]int)
+	for _, term := range a.Terms(text) {
+		if counts[term] == 0 {
+			terms = append(terms, term)
+		}
+		counts[term]++
+	}
+	return terms, counts
+}
@@ -0,0 +1,13 @@
+// Package lexical provides deterministic, dependency-free encoders based on
+// the words of the encoded texts.
+//
+// HashingEncoder hashes word and character n-grams into a fixed number of
+// dimensions and needs no training, while TFIDFEncoder weights the n-grams
+// of a vocabulary fitted on the route utterances by their inverse document
+// frequency, and must be fitted with all of them before the router is
+// created.
+//
+// Neither encoder needs a network connection, which makes them useful for
+// tests, offline and edge deployments, and as a lexical signal alongside
+// neural embeddings.
+package lexical
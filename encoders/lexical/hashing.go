@@ -0,0 +1,118 @@
+package lexical
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// DefaultDimensions is the default number of dimensions of a HashingEncoder.
+const DefaultDimensions = 1024
+
+// HashingEncoder encodes texts by hashing their n-grams into a fixed number
+// of dimensions.
+//
+// Each term is hashed with 64-bit FNV-1a; the hash selects both the
+// dimension and the sign of the term's contribution, so that colliding terms
+// tend to cancel out rather than accumulate. The encoder needs no fitting and
+// is safe for concurrent use.
+type HashingEncoder struct {
+	// Analyzer splits texts into terms.
+	Analyzer Analyzer
+	// Dimensions is the number of dimensions of the embeddings.
+	Dimensions int
+	// SublinearTF replaces each term count c with 1 + ln(c).
+	SublinearTF bool
+	// Normalize scales the embeddings to unit length.
+	Normalize bool
+}
+
+// NewHashingEncoder creates a new HashingEncoder.
+//
+// If dimensions is not positive, DefaultDimensions is used. The embeddings
+// are normalized to unit length.
+func NewHashingEncoder(dimensions int, opts ...Option) *HashingEncoder {
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	e := &HashingEncoder{
+		Analyzer:   DefaultAnalyzer(),
+		Dimensions: dimensions,
+		Normalize:  true,
+	}
+	for _, opt := range opts {
+		opt(&e.Analyzer)
+	}
+	return e
+}
+
+// Encode encodes a query string into a hashed bag of n-grams.
+func (e *HashingEncoder) Encode(
+	_ context.Context,
+	query string,
+) ([]float64, error) {
+	if e.Dimensions <= 0 {
+		return nil, fmt.Errorf(
+			"error encoding query: invalid dimensions %d",
+			e.Dimensions,
+		)
+	}
+	embedding := make([]float64, e.Dimensions)
+	terms, counts := e.Analyzer.counts(query)
+	for _, term := range terms {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(term))
+		sum := h.Sum64()
+		weight := termFrequency(counts[term], e.SublinearTF)
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		embedding[(sum&math.MaxInt64)%uint64(e.Dimensions)] += weight
+	}
+	if e.Normalize {
+		normalize(embedding)
+	}
+	return embedding, nil
+}
+
+// EncodeBatch encodes the given utterances into hashed bags of n-grams.
+func (e *HashingEncoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	result := make([][]float64, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.Encode(ctx, utterance)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = embedding
+	}
+	return result, nil
+}
+
+// termFrequency returns the weight of a term occurring count times.
+func termFrequency(count int, sublinear bool) float64 {
+	if sublinear {
+		return 1 + math.Log(float64(count))
+	}
+	return float64(count)
+}
+
+// normalize scales the given vector to unit length in place.
+//
+// The zero vector is left unchanged.
+func normalize(v []float64) {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i := range v {
+		v[i] /= norm
+	}
+}
@@ -0,0 +1,197 @@
+package lexical_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/lexical"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder              = (*lexical.HashingEncoder)(nil)
+	_ semanticrouter.BatchEncoder         = (*lexical.HashingEncoder)(nil)
+	_ semanticrouter.Encoder              = (*lexical.TFIDFEncoder)(nil)
+	_ semanticrouter.BatchEncoder         = (*lexical.TFIDFEncoder)(nil)
+	_ semanticrouter.QueryDocumentEncoder = (*lexical.TFIDFEncoder)(nil)
+)
+
+// dot returns the dot product of two vectors of the same length.
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// TestAnalyzerTerms tests splitting a text into word and character n-grams.
+func TestAnalyzerTerms(t *testing.T) {
+	a := assert.New(t)
+	analyzer := lexical.DefaultAnalyzer()
+	a.Equal(
+		[]string{"my", "dog", "s", "cold", "my dog", "dog s", "s cold"},
+		analyzer.Terms("My dog's cold!"),
+	)
+	lexical.WithWordNGrams(1, 1)(&analyzer)
+	lexical.WithCharNGrams(3, 3)(&analyzer)
+	lexical.WithStopWords("The")(&analyzer)
+	a.Equal(
+		[]string{"cat", "#<ca", "#cat", "#at>"},
+		analyzer.Terms("the cat"),
+	)
+}
+
+// TestHashingEncoder tests that the hashing encoder is deterministic and
+// places similar texts close together.
+func TestHashingEncoder(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	encoder := lexical.NewHashingEncoder(256, lexical.WithCharNGrams(3, 4))
+	first, err := encoder.Encode(ctx, "what is the best way to treat a dog with a cold?")
+	require.NoError(t, err)
+	again, err := lexical.NewHashingEncoder(256, lexical.WithCharNGrams(3, 4)).
+		Encode(ctx, "what is the best way to treat a dog with a cold?")
+	require.NoError(t, err)
+	a.Len(first, 256)
+	a.Equal(first, again)
+	a.InDelta(1, dot(first, first), 1e-9)
+
+	batch, err := encoder.EncodeBatch(ctx, []string{
+		"how do I treat my dog's cold?",
+		"what is your favorite color?",
+	})
+	require.NoError(t, err)
+	a.Greater(dot(first, batch[0]), dot(first, batch[1]))
+
+	empty, err := encoder.Encode(ctx, "?!")
+	a.NoError(err)
+	a.Equal(make([]float64, 256), empty)
+}
+
+// TestTFIDFEncoder tests fitting the TF-IDF encoder and encoding texts.
+func TestTFIDFEncoder(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	encoder := lexical.NewTFIDFEncoder(lexical.WithWordNGrams(1, 1))
+	_, err := encoder.Encode(ctx, "dog")
+	a.ErrorIs(err, lexical.ErrNotFitted)
+
+	encoder.Fit([]string{"the dog", "the cat", "a bird"})
+	a.True(encoder.Fitted())
+	a.Equal(5, encoder.Dimensions())
+	embedding, err := encoder.Encode(ctx, "the the dog fish")
+	require.NoError(t, err)
+	a.Len(embedding, 5)
+	// "the" occurs twice and in two documents, "dog" once and in one
+	// document, and "fish" is not in the vocabulary.
+	the, dog := 2*(math.Log(4.0/3.0)+1), math.Log(4.0/2.0)+1
+	norm := math.Hypot(the, dog)
+	a.InDelta(the/norm, embedding[0], 1e-9)
+	a.InDelta(dog/norm, embedding[1], 1e-9)
+	a.Zero(embedding[2])
+	a.InDelta(1, dot(embedding, embedding), 1e-9)
+}
+
+// routes are the routes of the router tests.
+var routes = []semanticrouter.Route{
+	{
+		Name: "noteworthy",
+		Utterances: []semanticrouter.Utterance{
+			{Utterance: "what is the best way to treat a dog with a cold?"},
+			{Utterance: "my cat has been limping, what should I do?"},
+		},
+	},
+	{
+		Name: "chitchat",
+		Utterances: []semanticrouter.Utterance{
+			{Utterance: "what is your favorite color?"},
+			{Utterance: "what is your favorite animal?"},
+		},
+	},
+}
+
+// utterances returns the texts of the utterances of the given routes.
+func utterances(routes []semanticrouter.Route) []string {
+	var texts []string
+	for _, route := range routes {
+		for _, utterance := range route.Utterances {
+			texts = append(texts, utterance.Utterance)
+		}
+	}
+	return texts
+}
+
+// TestRouter tests routing with both encoders without a network connection.
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	tfidf := lexical.NewTFIDFEncoder()
+	tfidf.Fit(utterances(routes))
+	for name, encoder := range map[string]semanticrouter.Encoder{
+		"hashing": lexical.NewHashingEncoder(0, lexical.WithCharNGrams(3, 5)),
+		"tfidf":   tfidf,
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			router, err := semanticrouter.NewRouter(
+				routes,
+				encoder,
+				memory.NewStore(),
+				semanticrouter.WithSimilarityDotMatrix(1.0),
+			)
+			require.NoError(t, err)
+			route, _, err := router.Match(ctx, "my dog has a cold, how do I treat it?")
+			a.NoError(err)
+			a.Equal("noteworthy", route.Name)
+			route, _, err = router.Match(ctx, "what's your favorite color")
+			a.NoError(err)
+			a.Equal("chitchat", route.Name)
+		})
+	}
+}
+
+// TestTFIDFEncoderStored tests that a router whose store already holds some
+// of the utterances gets embeddings of the same dimensions for all of them,
+// and that an unfitted encoder is not fitted on the missing utterances only.
+func TestTFIDFEncoderStored(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	first := lexical.NewTFIDFEncoder()
+	first.Fit(utterances(routes))
+	stored := routes[0].Utterances[0]
+	embeddings, err := first.EncodeDocuments(ctx, []string{stored.Utterance})
+	require.NoError(t, err)
+	stored.Embed = embeddings[0]
+	require.NoError(t, store.Set(ctx, stored))
+
+	_, err = semanticrouter.NewRouter(
+		routes,
+		lexical.NewTFIDFEncoder(),
+		store,
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.ErrorIs(t, err, lexical.ErrNotFitted)
+
+	encoder := lexical.NewTFIDFEncoder()
+	encoder.Fit(utterances(routes))
+	router, err := semanticrouter.NewRouter(
+		routes,
+		encoder,
+		store,
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	a := assert.New(t)
+	for _, text := range utterances(routes) {
+		embedding, err := store.Get(ctx, text)
+		require.NoError(t, err)
+		a.Len(embedding, encoder.Dimensions(), text)
+	}
+	route, _, err := router.Match(ctx, "my dog has a cold, how do I treat it?")
+	a.NoError(err)
+	a.Equal("noteworthy", route.Name)
+}
@@ -0,0 +1,173 @@
+package lexical
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+)
+
+// ErrNotFitted is returned when a TFIDFEncoder encodes a text before it has
+// been fitted.
+var ErrNotFitted = errors.New("tf-idf encoder has not been fitted")
+
+// TFIDFEncoder encodes texts as the TF-IDF weights of the n-grams of a
+// vocabulary fitted on a corpus, usually the utterances of the routes.
+//
+// The vocabulary is fitted by Fit, which must be called with all the route
+// utterances before creating the router: NewRouter only encodes the
+// utterances that are not already stored, so fitting on them would give the
+// stored and fresh embeddings different dimensions. The embeddings have one
+// dimension per vocabulary term in order of first occurrence; terms outside
+// the vocabulary are ignored. The inverse document frequency of a
+// term occurring in df of n documents is ln((1 + n) / (1 + df)) + 1.
+//
+// The encoder is safe for concurrent use. Refitting it changes the
+// dimensions of the embeddings, so stored embeddings must be re-encoded.
+type TFIDFEncoder struct {
+	// Analyzer splits texts into terms.
+	Analyzer Analyzer
+	// MinDocumentFrequency is the number of documents a term must occur in
+	// to be part of the vocabulary.
+	MinDocumentFrequency int
+	// SublinearTF replaces each term count c with 1 + ln(c).
+	SublinearTF bool
+	// Normalize scales the embeddings to unit length.
+	Normalize bool
+
+	mu         sync.RWMutex
+	vocabulary map[string]int
+	idf        []float64
+}
+
+// NewTFIDFEncoder creates a new, unfitted TFIDFEncoder.
+//
+// The embeddings are normalized to unit length.
+func NewTFIDFEncoder(opts ...Option) *TFIDFEncoder {
+	e := &TFIDFEncoder{
+		Analyzer:             DefaultAnalyzer(),
+		MinDocumentFrequency: 1,
+		Normalize:            true,
+	}
+	for _, opt := range opts {
+		opt(&e.Analyzer)
+	}
+	return e
+}
+
+// Fit fits the vocabulary and inverse document frequencies of the encoder on
+// the given documents, replacing any previous fit.
+func (e *TFIDFEncoder) Fit(documents []string) {
+	var terms []string
+	frequencies := make(map[string]int)
+	for _, document := range documents {
+		documentTerms, _ := e.Analyzer.counts(document)
+		for _, term := range documentTerms {
+			if frequencies[term] == 0 {
+				terms = append(terms, term)
+			}
+			frequencies[term]++
+		}
+	}
+	vocabulary := make(map[string]int)
+	var idf []float64
+	n := float64(len(documents))
+	for _, term := range terms {
+		df := frequencies[term]
+		if df < e.MinDocumentFrequency {
+			continue
+		}
+		vocabulary[term] = len(idf)
+		idf = append(idf, math.Log((1+n)/(1+float64(df)))+1)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.vocabulary = vocabulary
+	e.idf = idf
+}
+
+// Fitted reports whether the encoder has been fitted.
+func (e *TFIDFEncoder) Fitted() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.vocabulary != nil
+}
+
+// Dimensions returns the number of dimensions of the embeddings, which is the
+// size of the fitted vocabulary.
+func (e *TFIDFEncoder) Dimensions() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.idf)
+}
+
+// Encode encodes a query string into its TF-IDF weights.
+//
+// It returns ErrNotFitted if the encoder has not been fitted.
+func (e *TFIDFEncoder) Encode(
+	_ context.Context,
+	query string,
+) ([]float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.vocabulary == nil {
+		return nil, ErrNotFitted
+	}
+	return e.encode(query), nil
+}
+
+// EncodeBatch encodes the given utterances into their TF-IDF weights.
+//
+// It returns ErrNotFitted if the encoder has not been fitted.
+func (e *TFIDFEncoder) EncodeBatch(
+	_ context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.vocabulary == nil {
+		return nil, ErrNotFitted
+	}
+	result := make([][]float64, len(utterances))
+	for i, utterance := range utterances {
+		result[i] = e.encode(utterance)
+	}
+	return result, nil
+}
+
+// EncodeQuery encodes the given query into its TF-IDF weights.
+func (e *TFIDFEncoder) EncodeQuery(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	return e.Encode(ctx, query)
+}
+
+// EncodeDocuments encodes the given documents into their TF-IDF weights.
+//
+// It returns ErrNotFitted if the encoder has not been fitted; the documents
+// are not fitted on, since they may be only part of the corpus.
+func (e *TFIDFEncoder) EncodeDocuments(
+	ctx context.Context,
+	documents []string,
+) ([][]float64, error) {
+	return e.EncodeBatch(ctx, documents)
+}
+
+// encode returns the TF-IDF weights of the given text. The caller must hold
+// the read lock.
+func (e *TFIDFEncoder) encode(text string) []float64 {
+	embedding := make([]float64, len(e.idf))
+	terms, counts := e.Analyzer.counts(text)
+	for _, term := range terms {
+		index, ok := e.vocabulary[term]
+		if !ok {
+			continue
+		}
+		embedding[index] = termFrequency(counts[term], e.SublinearTF) * e.idf[index]
+	}
+	if e.Normalize {
+		normalize(embedding)
+	}
+	return embedding
+}
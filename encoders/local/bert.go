@@ -0,0 +1,473 @@
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Activation is the activation function of the feed-forward layers.
+type Activation string
+
+const (
+	// ActivationGELU is the exact GELU activation using the error function.
+	ActivationGELU Activation = "gelu"
+	// ActivationGELUTanh is the tanh approximation of the GELU activation.
+	ActivationGELUTanh Activation = "gelu_new"
+	// ActivationReLU is the ReLU activation.
+	ActivationReLU Activation = "relu"
+)
+
+// Config is the architecture of a BERT model.
+type Config struct {
+	// HiddenSize is the number of dimensions of the hidden states and of the
+	// embeddings.
+	HiddenSize int `json:"hidden_size"`
+	// Layers is the number of transformer layers.
+	Layers int `json:"num_hidden_layers"`
+	// Heads is the number of attention heads of each layer.
+	Heads int `json:"num_attention_heads"`
+	// IntermediateSize is the number of dimensions of the feed-forward
+	// layers.
+	IntermediateSize int `json:"intermediate_size"`
+	// MaxPositions is the maximum number of tokens of an input.
+	MaxPositions int `json:"max_position_embeddings"`
+	// LayerNormEpsilon is the epsilon of the layer normalizations.
+	LayerNormEpsilon float64 `json:"layer_norm_eps"`
+	// Activation is the activation function of the feed-forward layers.
+	Activation Activation `json:"hidden_act"`
+}
+
+// layer holds the weights of one transformer layer. Linear weights have the
+// shape [out, in].
+type layer struct {
+	query, queryBias           *tensor
+	key, keyBias               *tensor
+	value, valueBias           *tensor
+	attnOutput, attnOutputBias *tensor
+	attnNorm, attnNormBias     *tensor
+	intermediate, intermBias   *tensor
+	output, outputBias         *tensor
+	outputNorm, outputNormBias *tensor
+}
+
+// Model is a BERT model and its tokenizer.
+type Model struct {
+	// Config is the architecture of the model.
+	Config Config
+	// Tokenizer is the tokenizer of the model.
+	Tokenizer *Tokenizer
+
+	wordEmbeddings     *tensor
+	positionEmbeddings *tensor
+	typeEmbeddings     *tensor
+	embeddingNorm      *tensor
+	embeddingNormBias  *tensor
+	layers             []layer
+}
+
+// LoadModel loads a model from a Hugging Face model directory holding
+// config.json, model.safetensors and either tokenizer.json or vocab.txt.
+//
+// If the directory holds a tokenizer_config.json with do_lower_case set, it
+// decides whether the tokenizer lowercases texts.
+func LoadModel(dir string) (*Model, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading model config: %w", err)
+	}
+	var config Config
+	err = json.Unmarshal(raw, &config)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing model config: %w", err)
+	}
+	tokenizer, err := LoadTokenizer(filepath.Join(dir, "tokenizer.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		tokenizer, err = LoadTokenizer(filepath.Join(dir, "vocab.txt"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw, err = os.ReadFile(filepath.Join(dir, "tokenizer_config.json"))
+	if err == nil {
+		var tokenizerConfig struct {
+			DoLowerCase *bool `json:"do_lower_case"`
+		}
+		if json.Unmarshal(raw, &tokenizerConfig) == nil && tokenizerConfig.DoLowerCase != nil {
+			tokenizer.Lowercase = *tokenizerConfig.DoLowerCase
+		}
+	}
+	tensors, err := readSafetensors(filepath.Join(dir, "model.safetensors"))
+	if err != nil {
+		return nil, err
+	}
+	return newModel(config, tokenizer, safetensorsNames(tensors))
+}
+
+// LoadGGUF loads a BERT model and its tokenizer from a GGUF file as written
+// by llama.cpp's convert_hf_to_gguf.py.
+func LoadGGUF(path string) (*Model, error) {
+	f, err := readGGUF(path)
+	if err != nil {
+		return nil, err
+	}
+	arch, _ := f.metadata["general.architecture"].(string)
+	if arch != "bert" {
+		return nil, fmt.Errorf("error loading gguf: unsupported architecture %q", arch)
+	}
+	var config Config
+	config.HiddenSize, _ = f.uint("bert.embedding_length")
+	config.Layers, _ = f.uint("bert.block_count")
+	config.Heads, _ = f.uint("bert.attention.head_count")
+	config.IntermediateSize, _ = f.uint("bert.feed_forward_length")
+	config.MaxPositions, _ = f.uint("bert.context_length")
+	config.LayerNormEpsilon, _ = f.float("bert.attention.layer_norm_epsilon")
+	config.Activation = ActivationGELU
+	tokens, ok := f.strings("tokenizer.ggml.tokens")
+	if !ok {
+		return nil, fmt.Errorf("error loading gguf: missing tokenizer.ggml.tokens")
+	}
+	// The conversion marks word-initial pieces with a leading U+2581 and
+	// removes the ## prefix of continuation pieces; special tokens are kept.
+	for i, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]"):
+		case strings.HasPrefix(token, "▁"):
+			tokens[i] = strings.TrimPrefix(token, "▁")
+		default:
+			tokens[i] = "##" + token
+		}
+	}
+	tokenizer := NewTokenizer(tokens)
+	for key, token := range map[string]*string{
+		"tokenizer.ggml.unknown_token_id":   &tokenizer.Unknown,
+		"tokenizer.ggml.cls_token_id":       &tokenizer.Classification,
+		"tokenizer.ggml.seperator_token_id": &tokenizer.Separator,
+	} {
+		if id, ok := f.uint(key); ok && id < len(tokens) {
+			*token = tokens[id]
+		}
+	}
+	return newModel(config, tokenizer, ggufNames(f.tensors))
+}
+
+// tensorNames maps the role of each weight to its tensor.
+type tensorNames func(name string) (*tensor, bool)
+
+// safetensorsNames looks up Hugging Face BERT weights by their names, with
+// or without the "bert." prefix and with the old gamma and beta layer norm
+// names.
+func safetensorsNames(tensors map[string]*tensor) tensorNames {
+	return func(name string) (*tensor, bool) {
+		candidates := []string{name, "bert." + name}
+		if strings.HasSuffix(name, "LayerNorm.weight") {
+			old := strings.TrimSuffix(name, "weight") + "gamma"
+			candidates = append(candidates, old, "bert."+old)
+		}
+		if strings.HasSuffix(name, "LayerNorm.bias") {
+			old := strings.TrimSuffix(name, "bias") + "beta"
+			candidates = append(candidates, old, "bert."+old)
+		}
+		for _, candidate := range candidates {
+			if t, ok := tensors[candidate]; ok {
+				return t, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// ggufNames looks up Hugging Face BERT weights by their llama.cpp names.
+func ggufNames(tensors map[string]*tensor) tensorNames {
+	replacer := strings.NewReplacer(
+		"embeddings.word_embeddings", "token_embd",
+		"embeddings.position_embeddings", "position_embd",
+		"embeddings.token_type_embeddings", "token_types",
+		"embeddings.LayerNorm", "token_embd_norm",
+		"encoder.layer.", "blk.",
+		".attention.self.query", ".attn_q",
+		".attention.self.key", ".attn_k",
+		".attention.self.value", ".attn_v",
+		".attention.output.dense", ".attn_output",
+		".attention.output.LayerNorm", ".attn_output_norm",
+		".intermediate.dense", ".ffn_up",
+		".output.dense", ".ffn_down",
+		".output.LayerNorm", ".layer_output_norm",
+	)
+	return func(name string) (*tensor, bool) {
+		t, ok := tensors[replacer.Replace(name)]
+		return t, ok
+	}
+}
+
+// newModel creates a model from the given configuration, tokenizer and
+// weights, checking the shapes of the weights.
+func newModel(config Config, tokenizer *Tokenizer, names tensorNames) (*Model, error) {
+	if config.HiddenSize <= 0 || config.Layers <= 0 || config.Heads <= 0 ||
+		config.IntermediateSize <= 0 || config.HiddenSize%config.Heads != 0 {
+		return nil, fmt.Errorf("error loading model: invalid config %+v", config)
+	}
+	if config.LayerNormEpsilon == 0 {
+		config.LayerNormEpsilon = 1e-12
+	}
+	switch config.Activation {
+	case "":
+		config.Activation = ActivationGELU
+	case ActivationGELU, ActivationGELUTanh, ActivationReLU:
+	case "gelu_pytorch_tanh", "gelu_fast":
+		config.Activation = ActivationGELUTanh
+	default:
+		return nil, fmt.Errorf("error loading model: unsupported activation %q", config.Activation)
+	}
+	h, inter := config.HiddenSize, config.IntermediateSize
+	var err error
+	get := func(name string, shape ...int) *tensor {
+		if err != nil {
+			return nil
+		}
+		t, ok := names(name)
+		if !ok {
+			err = fmt.Errorf("error loading model: missing weight %s", name)
+			return nil
+		}
+		if len(shape) > 1 && shape[0] < 0 {
+			// A negative leading dimension accepts any number of rows.
+			shape[0] = size(t.shape) / max(shape[1], 1)
+		}
+		if !equalShapes(t.shape, shape) {
+			err = fmt.Errorf(
+				"error loading model: weight %s has shape %v, want %v",
+				name,
+				t.shape,
+				shape,
+			)
+			return nil
+		}
+		return t
+	}
+	m := &Model{
+		Config:             config,
+		Tokenizer:          tokenizer,
+		wordEmbeddings:     get("embeddings.word_embeddings.weight", -1, h),
+		positionEmbeddings: get("embeddings.position_embeddings.weight", -1, h),
+		typeEmbeddings:     get("embeddings.token_type_embeddings.weight", -1, h),
+		embeddingNorm:      get("embeddings.LayerNorm.weight", h),
+		embeddingNormBias:  get("embeddings.LayerNorm.bias", h),
+	}
+	for i := 0; i < config.Layers; i++ {
+		p := fmt.Sprintf("encoder.layer.%d.", i)
+		m.layers = append(m.layers, layer{
+			query:          get(p+"attention.self.query.weight", h, h),
+			queryBias:      get(p+"attention.self.query.bias", h),
+			key:            get(p+"attention.self.key.weight", h, h),
+			keyBias:        get(p+"attention.self.key.bias", h),
+			value:          get(p+"attention.self.value.weight", h, h),
+			valueBias:      get(p+"attention.self.value.bias", h),
+			attnOutput:     get(p+"attention.output.dense.weight", h, h),
+			attnOutputBias: get(p+"attention.output.dense.bias", h),
+			attnNorm:       get(p+"attention.output.LayerNorm.weight", h),
+			attnNormBias:   get(p+"attention.output.LayerNorm.bias", h),
+			intermediate:   get(p+"intermediate.dense.weight", inter, h),
+			intermBias:     get(p+"intermediate.dense.bias", inter),
+			output:         get(p+"output.dense.weight", h, inter),
+			outputBias:     get(p+"output.dense.bias", h),
+			outputNorm:     get(p+"output.LayerNorm.weight", h),
+			outputNormBias: get(p+"output.LayerNorm.bias", h),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	positions := m.positionEmbeddings.shape[0]
+	if m.Config.MaxPositions <= 0 || m.Config.MaxPositions > positions {
+		m.Config.MaxPositions = positions
+	}
+	if len(tokenizer.Vocabulary) > m.wordEmbeddings.shape[0] {
+		return nil, fmt.Errorf(
+			"error loading model: %d tokens for %d word embeddings",
+			len(tokenizer.Vocabulary),
+			m.wordEmbeddings.shape[0],
+		)
+	}
+	return m, nil
+}
+
+// equalShapes reports whether two shapes are equal.
+func equalShapes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Forward returns the last hidden states of the given token ids, one row of
+// Config.HiddenSize values per token.
+func (m *Model) Forward(ids []int) ([][]float32, error) {
+	if len(ids) > m.Config.MaxPositions {
+		return nil, fmt.Errorf(
+			"got %d tokens, the model supports at most %d",
+			len(ids),
+			m.Config.MaxPositions,
+		)
+	}
+	h := m.Config.HiddenSize
+	eps := float32(m.Config.LayerNormEpsilon)
+	x := make([][]float32, len(ids))
+	for t, id := range ids {
+		if id < 0 || id >= m.wordEmbeddings.shape[0] {
+			return nil, fmt.Errorf("token id %d out of range", id)
+		}
+		x[t] = make([]float32, h)
+		word := m.wordEmbeddings.data[id*h : (id+1)*h]
+		position := m.positionEmbeddings.data[t*h : (t+1)*h]
+		tokenType := m.typeEmbeddings.data[:h]
+		for i := range x[t] {
+			x[t][i] = word[i] + position[i] + tokenType[i]
+		}
+		layerNorm(x[t], m.embeddingNorm.data, m.embeddingNormBias.data, eps)
+	}
+	for i := range m.layers {
+		x = m.layers[i].forward(x, m.Config)
+	}
+	return x, nil
+}
+
+// forward applies the transformer layer to the hidden states.
+func (l *layer) forward(x [][]float32, config Config) [][]float32 {
+	n, h := len(x), config.HiddenSize
+	headSize := h / config.Heads
+	eps := float32(config.LayerNormEpsilon)
+	q := linear(x, l.query, l.queryBias)
+	k := linear(x, l.key, l.keyBias)
+	v := linear(x, l.value, l.valueBias)
+	context := make([][]float32, n)
+	for t := range context {
+		context[t] = make([]float32, h)
+	}
+	scale := float32(1 / math.Sqrt(float64(headSize)))
+	scores := make([]float32, n)
+	for head := 0; head < config.Heads; head++ {
+		lo, hi := head*headSize, (head+1)*headSize
+		for t := 0; t < n; t++ {
+			for s := 0; s < n; s++ {
+				scores[s] = dot(q[t][lo:hi], k[s][lo:hi]) * scale
+			}
+			softmax(scores)
+			out := context[t][lo:hi]
+			for s := 0; s < n; s++ {
+				for i, value := range v[s][lo:hi] {
+					out[i] += scores[s] * value
+				}
+			}
+		}
+	}
+	attention := linear(context, l.attnOutput, l.attnOutputBias)
+	for t := range attention {
+		for i := range attention[t] {
+			attention[t][i] += x[t][i]
+		}
+		layerNorm(attention[t], l.attnNorm.data, l.attnNormBias.data, eps)
+	}
+	intermediate := linear(attention, l.intermediate, l.intermBias)
+	for t := range intermediate {
+		activate(intermediate[t], config.Activation)
+	}
+	output := linear(intermediate, l.output, l.outputBias)
+	for t := range output {
+		for i := range output[t] {
+			output[t][i] += attention[t][i]
+		}
+		layerNorm(output[t], l.outputNorm.data, l.outputNormBias.data, eps)
+	}
+	return output
+}
+
+// linear returns x W^T + b for a weight W of shape [out, in].
+func linear(x [][]float32, w, b *tensor) [][]float32 {
+	out, in := w.shape[0], w.shape[1]
+	result := make([][]float32, len(x))
+	for t, row := range x {
+		result[t] = make([]float32, out)
+		for o := 0; o < out; o++ {
+			result[t][o] = dot(row, w.data[o*in:(o+1)*in]) + b.data[o]
+		}
+	}
+	return result
+}
+
+// dot returns the dot product of two vectors of the same length.
+func dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// softmax replaces the values with their softmax in place.
+func softmax(x []float32) {
+	maximum := x[0]
+	for _, v := range x[1:] {
+		maximum = max(maximum, v)
+	}
+	var sum float32
+	for i, v := range x {
+		x[i] = float32(math.Exp(float64(v - maximum)))
+		sum += x[i]
+	}
+	for i := range x {
+		x[i] /= sum
+	}
+}
+
+// layerNorm normalizes x to zero mean and unit variance and applies the
+// given scale and bias in place.
+func layerNorm(x, scale, bias []float32, eps float32) {
+	var mean float32
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float32(len(x))
+	var variance float32
+	for _, v := range x {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float32(len(x))
+	inv := float32(1 / math.Sqrt(float64(variance+eps)))
+	for i, v := range x {
+		x[i] = (v-mean)*inv*scale[i] + bias[i]
+	}
+}
+
+// activate applies the activation function to x in place.
+func activate(x []float32, activation Activation) {
+	for i, v := range x {
+		switch activation {
+		case ActivationReLU:
+			x[i] = max(v, 0)
+		case ActivationGELUTanh:
+			f := float64(v)
+			x[i] = float32(0.5 * f * (1 + math.Tanh(math.Sqrt(2/math.Pi)*(f+0.044715*f*f*f))))
+		default:
+			x[i] = float32(0.5 * float64(v) * (1 + math.Erf(float64(v)/math.Sqrt2)))
+		}
+	}
+}
@@ -0,0 +1,12 @@
+// Package local provides an encoder that runs BERT sentence-transformer
+// models, such as all-MiniLM-L6-v2, in pure Go on the CPU.
+//
+// Models are loaded either from a Hugging Face model directory holding
+// config.json, model.safetensors and vocab.txt or tokenizer.json, or from a
+// single GGUF file as written by llama.cpp's conversion scripts. Texts are
+// tokenized with a WordPiece tokenizer, encoded by the transformer, mean
+// pooled and L2 normalized, matching the sentence-transformers pipeline of
+// these models.
+//
+// Only unquantized float32, float16 and bfloat16 weights are supported.
+package local
@@ -0,0 +1,296 @@
+package local
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// ggufMagic is the magic number at the start of GGUF files, "GGUF" in
+// little-endian byte order.
+const ggufMagic = 0x46554747
+
+// ggufDefaultAlignment is the alignment of the tensor data of GGUF files
+// without a general.alignment metadata value.
+const ggufDefaultAlignment = 32
+
+// GGUF metadata value types.
+const (
+	ggufUint8 uint32 = iota
+	ggufInt8
+	ggufUint16
+	ggufInt16
+	ggufUint32
+	ggufInt32
+	ggufFloat32
+	ggufBool
+	ggufString
+	ggufArray
+	ggufUint64
+	ggufInt64
+	ggufFloat64
+)
+
+// GGML tensor types supported by the GGUF reader.
+const (
+	ggmlF32  uint32 = 0
+	ggmlF16  uint32 = 1
+	ggmlBF16 uint32 = 30
+)
+
+// errShortGGUF is returned when a GGUF file ends unexpectedly.
+var errShortGGUF = errors.New("unexpected end of file")
+
+// ggufFile holds the metadata and tensors of a GGUF file.
+type ggufFile struct {
+	metadata map[string]any
+	tensors  map[string]*tensor
+}
+
+// ggufReader reads little-endian values from the bytes of a GGUF file.
+type ggufReader struct {
+	raw []byte
+	off int
+	err error
+}
+
+// next returns the next n bytes, or nil once the file is exhausted.
+func (r *ggufReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.off+n > len(r.raw) {
+		r.err = errShortGGUF
+		return nil
+	}
+	b := r.raw[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *ggufReader) uint8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *ggufReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *ggufReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *ggufReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// count reads a uint64 length and checks that it fits in the file.
+func (r *ggufReader) count() int {
+	n := r.uint64()
+	if n > uint64(len(r.raw)) {
+		r.err = fmt.Errorf("length %d out of range", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *ggufReader) string() string {
+	return string(r.next(r.count()))
+}
+
+// value reads a metadata value of the given type.
+func (r *ggufReader) value(typ uint32) any {
+	switch typ {
+	case ggufUint8:
+		return r.uint8()
+	case ggufInt8:
+		return int8(r.uint8())
+	case ggufUint16:
+		return r.uint16()
+	case ggufInt16:
+		return int16(r.uint16())
+	case ggufUint32:
+		return r.uint32()
+	case ggufInt32:
+		return int32(r.uint32())
+	case ggufFloat32:
+		return math.Float32frombits(r.uint32())
+	case ggufBool:
+		return r.uint8() != 0
+	case ggufString:
+		return r.string()
+	case ggufArray:
+		elem := r.uint32()
+		n := r.count()
+		values := make([]any, 0, min(n, 1<<16))
+		for i := 0; i < n && r.err == nil; i++ {
+			values = append(values, r.value(elem))
+		}
+		return values
+	case ggufUint64:
+		return r.uint64()
+	case ggufInt64:
+		return int64(r.uint64())
+	case ggufFloat64:
+		return math.Float64frombits(r.uint64())
+	default:
+		r.err = fmt.Errorf("unknown metadata value type %d", typ)
+		return nil
+	}
+}
+
+// readGGUF reads the metadata and tensors of the GGUF file at the given
+// path.
+func readGGUF(path string) (*ggufFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading gguf: %w", err)
+	}
+	return parseGGUF(raw)
+}
+
+// parseGGUF parses the metadata and tensors of a GGUF file of version 2 or
+// 3.
+//
+// GGUF stores tensor dimensions innermost first, so the shapes are reversed
+// into the row-major order used by safetensors.
+func parseGGUF(raw []byte) (*ggufFile, error) {
+	r := &ggufReader{raw: raw}
+	if r.uint32() != ggufMagic {
+		return nil, fmt.Errorf("error parsing gguf: invalid magic number")
+	}
+	version := r.uint32()
+	if version != 2 && version != 3 {
+		return nil, fmt.Errorf("error parsing gguf: unsupported version %d", version)
+	}
+	tensorCount := r.count()
+	kvCount := r.count()
+	f := &ggufFile{
+		metadata: make(map[string]any, kvCount),
+		tensors:  make(map[string]*tensor, tensorCount),
+	}
+	for i := 0; i < kvCount && r.err == nil; i++ {
+		key := r.string()
+		f.metadata[key] = r.value(r.uint32())
+	}
+	type info struct {
+		name   string
+		shape  []int
+		typ    uint32
+		offset uint64
+	}
+	infos := make([]info, 0, tensorCount)
+	for i := 0; i < tensorCount && r.err == nil; i++ {
+		t := info{name: r.string()}
+		dims := int(r.uint32())
+		if dims > 4 {
+			return nil, fmt.Errorf("error parsing gguf: tensor %s has %d dimensions", t.name, dims)
+		}
+		t.shape = make([]int, dims)
+		for j := dims - 1; j >= 0; j-- {
+			t.shape[j] = r.count()
+		}
+		t.typ = r.uint32()
+		t.offset = r.uint64()
+		infos = append(infos, t)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("error parsing gguf: %w", r.err)
+	}
+	alignment := ggufDefaultAlignment
+	if a, ok := f.metadata["general.alignment"].(uint32); ok && a > 0 {
+		alignment = int(a)
+	}
+	start := (r.off + alignment - 1) / alignment * alignment
+	for _, t := range infos {
+		var d dtype
+		switch t.typ {
+		case ggmlF32:
+			d = dtypeF32
+		case ggmlF16:
+			d = dtypeF16
+		case ggmlBF16:
+			d = dtypeBF16
+		default:
+			return nil, fmt.Errorf(
+				"error parsing gguf: tensor %s has unsupported type %d",
+				t.name,
+				t.typ,
+			)
+		}
+		n := uint64(size(t.shape) * d.bytes())
+		begin := uint64(start) + t.offset
+		if t.offset > uint64(len(raw)) || begin+n > uint64(len(raw)) {
+			return nil, fmt.Errorf("error parsing gguf: tensor %s out of range", t.name)
+		}
+		decoded, err := decode(raw[begin:begin+n], d, t.shape)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing gguf tensor %s: %w", t.name, err)
+		}
+		f.tensors[t.name] = decoded
+	}
+	return f, nil
+}
+
+// uint returns the metadata value of the given key as an int.
+func (f *ggufFile) uint(key string) (int, bool) {
+	switch v := f.metadata[key].(type) {
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return int(v), true
+	case uint64:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// float returns the metadata value of the given key as a float64.
+func (f *ggufFile) float(key string) (float64, bool) {
+	switch v := f.metadata[key].(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// strings returns the metadata value of the given key as a string slice.
+func (f *ggufFile) strings(key string) ([]string, bool) {
+	values, ok := f.metadata[key].([]any)
+	if !ok {
+		return nil, false
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		result[i] = s
+	}
+	return result, true
+}
@@ -0,0 +1,17 @@
+module github.com/conneroisu/semanticrouter-go/encoders/local
+
+go 1.23.0
+
+require (
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/text v0.16.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rogpeppe/go-internal v1.11.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,134 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/conneroisu/semanticrouter-go"
+)
+
+// Encoder encodes utterances with a local BERT sentence-transformer model.
+//
+// The last hidden states of all tokens, including the classification and
+// separator tokens, are averaged and normalized to unit length. The encoder
+// is safe for concurrent use.
+type Encoder struct {
+	// Model is the BERT model.
+	Model *Model
+	// MaxLength is the maximum number of tokens of an utterance, including
+	// the special tokens; longer utterances are truncated. It defaults to
+	// the maximum supported by the model.
+	MaxLength int
+	// Normalize scales the embeddings to unit length.
+	Normalize bool
+}
+
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithMaxLength sets the maximum number of tokens of an utterance.
+func WithMaxLength(maxLength int) Option {
+	return func(e *Encoder) {
+		e.MaxLength = maxLength
+	}
+}
+
+// WithNormalize sets whether the embeddings are scaled to unit length.
+func WithNormalize(normalize bool) Option {
+	return func(e *Encoder) {
+		e.Normalize = normalize
+	}
+}
+
+// NewEncoder creates a new Encoder using the given model.
+func NewEncoder(model *Model, opts ...Option) *Encoder {
+	e := &Encoder{
+		Model:     model,
+		Normalize: true,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Encode encodes a query string into a mean pooled sentence embedding.
+func (e *Encoder) Encode(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	if e.Model == nil {
+		return nil, fmt.Errorf("local model is nil")
+	}
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+	maxLength := e.Model.Config.MaxPositions
+	if e.MaxLength > 0 {
+		maxLength = min(maxLength, e.MaxLength)
+	}
+	ids, err := e.Model.Tokenizer.Encode(query, maxLength)
+	if err != nil {
+		return nil, fmt.Errorf("error tokenizing query: %w", err)
+	}
+	hidden, err := e.Model.Forward(ids)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding query: %w", err)
+	}
+	embedding := make([]float64, e.Model.Config.HiddenSize)
+	for _, state := range hidden {
+		for i, v := range state {
+			embedding[i] += float64(v)
+		}
+	}
+	var sum float64
+	for i := range embedding {
+		embedding[i] /= float64(len(hidden))
+		sum += embedding[i] * embedding[i]
+	}
+	if e.Normalize && sum > 0 {
+		norm := math.Sqrt(sum)
+		for i := range embedding {
+			embedding[i] /= norm
+		}
+	}
+	return embedding, nil
+}
+
+// EncodeBatch encodes the given utterances into mean pooled sentence
+// embeddings.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	result := make([][]float64, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.Encode(ctx, utterance)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = embedding
+	}
+	return result, nil
+}
+
+// EncodeFloat32 encodes the given utterances into float32 mean pooled
+// sentence embeddings, the precision of the model's hidden states.
+//
+// The hidden states are still pooled in float64 before being rounded.
+func (e *Encoder) EncodeFloat32(
+	ctx context.Context,
+	utterances []string,
+) ([][]float32, error) {
+	result := make([][]float32, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.Encode(ctx, utterance)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = semanticrouter.Convert[float32](embedding)
+	}
+	return result, nil
+}
@@ -0,0 +1,402 @@
+package local_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/local"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder        = (*local.Encoder)(nil)
+	_ semanticrouter.BatchEncoder   = (*local.Encoder)(nil)
+	_ semanticrouter.Float32Encoder = (*local.Encoder)(nil)
+)
+
+// vocabulary is the vocabulary of the test model.
+var vocabulary = []string{
+	"[PAD]", "[UNK]", "[CLS]", "[SEP]",
+	"the", "dog", "##s", "cat", "is", "sick", ",", "?", "un", "##aff",
+	"##able", "cafe", "what", "your", "favorite", "color", "my", "has",
+	"a", "cold", "limping",
+}
+
+// testConfig is the architecture of the test model.
+var testConfig = local.Config{
+	HiddenSize:       8,
+	Layers:           2,
+	Heads:            2,
+	IntermediateSize: 16,
+	MaxPositions:     16,
+	LayerNormEpsilon: 1e-12,
+	Activation:       local.ActivationGELU,
+}
+
+// weight is a named weight of the test model in Hugging Face layout.
+type weight struct {
+	name  string
+	shape []int
+	data  []float32
+}
+
+// testWeights returns deterministic weights for the test model.
+//
+// Every value is a multiple of 1/64 in [-0.5, 0.5], which float16 and
+// bfloat16 represent exactly.
+func testWeights() []weight {
+	h, inter := testConfig.HiddenSize, testConfig.IntermediateSize
+	seed := uint32(1)
+	next := func(shape ...int) []float32 {
+		n := 1
+		for _, d := range shape {
+			n *= d
+		}
+		data := make([]float32, n)
+		for i := range data {
+			seed = seed*1664525 + 1013904223
+			data[i] = float32(int(seed>>24)%65-32) / 64
+		}
+		return data
+	}
+	ones := func(n int) []float32 {
+		data := make([]float32, n)
+		for i := range data {
+			data[i] = 1
+		}
+		return data
+	}
+	weights := []weight{
+		{"embeddings.word_embeddings.weight", []int{len(vocabulary), h}, next(len(vocabulary), h)},
+		{"embeddings.position_embeddings.weight", []int{16, h}, next(16, h)},
+		{"embeddings.token_type_embeddings.weight", []int{2, h}, next(2, h)},
+		{"embeddings.LayerNorm.weight", []int{h}, ones(h)},
+		{"embeddings.LayerNorm.bias", []int{h}, next(h)},
+	}
+	for i := 0; i < testConfig.Layers; i++ {
+		p := "encoder.layer." + string(rune('0'+i)) + "."
+		for _, name := range []string{"query", "key", "value"} {
+			weights = append(weights,
+				weight{p + "attention.self." + name + ".weight", []int{h, h}, next(h, h)},
+				weight{p + "attention.self." + name + ".bias", []int{h}, next(h)},
+			)
+		}
+		weights = append(weights,
+			weight{p + "attention.output.dense.weight", []int{h, h}, next(h, h)},
+			weight{p + "attention.output.dense.bias", []int{h}, next(h)},
+			weight{p + "attention.output.LayerNorm.weight", []int{h}, ones(h)},
+			weight{p + "attention.output.LayerNorm.bias", []int{h}, next(h)},
+			weight{p + "intermediate.dense.weight", []int{inter, h}, next(inter, h)},
+			weight{p + "intermediate.dense.bias", []int{inter}, next(inter)},
+			weight{p + "output.dense.weight", []int{h, inter}, next(h, inter)},
+			weight{p + "output.dense.bias", []int{h}, next(h)},
+			weight{p + "output.LayerNorm.weight", []int{h}, ones(h)},
+			weight{p + "output.LayerNorm.bias", []int{h}, next(h)},
+		)
+	}
+	return weights
+}
+
+// encodeValues serializes the values as little-endian data of the given
+// safetensors dtype.
+func encodeValues(data []float32, dtype string) []byte {
+	var buf bytes.Buffer
+	for _, v := range data {
+		bits := math.Float32bits(v)
+		switch dtype {
+		case "F32":
+			_ = binary.Write(&buf, binary.LittleEndian, bits)
+		case "BF16":
+			_ = binary.Write(&buf, binary.LittleEndian, uint16(bits>>16))
+		case "F16":
+			_ = binary.Write(&buf, binary.LittleEndian, floatToHalf(v))
+		}
+	}
+	return buf.Bytes()
+}
+
+// floatToHalf converts a float32 that float16 represents exactly.
+func floatToHalf(v float32) uint16 {
+	bits := math.Float32bits(v)
+	sign := uint16(bits>>16) & 0x8000
+	if v == 0 {
+		return sign
+	}
+	exponent := int((bits>>23)&0xff) - 127 + 15
+	mantissa := uint16((bits >> 13) & 0x3ff)
+	if exponent <= 0 {
+		// Subnormal: shift the implicit leading one into the mantissa.
+		return sign | (0x400|mantissa)>>(1-exponent)
+	}
+	return sign | uint16(exponent)<<10 | mantissa
+}
+
+// writeModelDir writes the test model as a Hugging Face model directory
+// with weights of the given dtype.
+func writeModelDir(t *testing.T, dtype string) string {
+	t.Helper()
+	dir := t.TempDir()
+	config, err := json.Marshal(map[string]any{
+		"hidden_size":             testConfig.HiddenSize,
+		"num_hidden_layers":       testConfig.Layers,
+		"num_attention_heads":     testConfig.Heads,
+		"intermediate_size":       testConfig.IntermediateSize,
+		"max_position_embeddings": testConfig.MaxPositions,
+		"layer_norm_eps":          testConfig.LayerNormEpsilon,
+		"hidden_act":              "gelu",
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), config, 0o644))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "vocab.txt"),
+		[]byte(strings.Join(vocabulary, "\n")+"\n"),
+		0o644,
+	))
+	header := map[string]any{"__metadata__": map[string]string{"format": "pt"}}
+	var data []byte
+	for _, w := range testWeights() {
+		raw := encodeValues(w.data, dtype)
+		header["bert."+w.name] = map[string]any{
+			"dtype":        dtype,
+			"shape":        w.shape,
+			"data_offsets": []int{len(data), len(data) + len(raw)},
+		}
+		data = append(data, raw...)
+	}
+	headerJSON, err := json.Marshal(header)
+	require.NoError(t, err)
+	var file bytes.Buffer
+	_ = binary.Write(&file, binary.LittleEndian, uint64(len(headerJSON)))
+	file.Write(headerJSON)
+	file.Write(data)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "model.safetensors"), file.Bytes(), 0o644))
+	return dir
+}
+
+// ggufName returns the llama.cpp name of a Hugging Face BERT weight.
+func ggufName(name string) string {
+	return strings.NewReplacer(
+		"embeddings.word_embeddings", "token_embd",
+		"embeddings.position_embeddings", "position_embd",
+		"embeddings.token_type_embeddings", "token_types",
+		"embeddings.LayerNorm", "token_embd_norm",
+		"encoder.layer.", "blk.",
+		".attention.self.query", ".attn_q",
+		".attention.self.key", ".attn_k",
+		".attention.self.value", ".attn_v",
+		".attention.output.dense", ".attn_output",
+		".attention.output.LayerNorm", ".attn_output_norm",
+		".intermediate.dense", ".ffn_up",
+		".output.dense", ".ffn_down",
+		".output.LayerNorm", ".layer_output_norm",
+	).Replace(name)
+}
+
+// writeGGUF writes the test model as a version 3 GGUF file.
+func writeGGUF(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	put := func(v any) { _ = binary.Write(&buf, binary.LittleEndian, v) }
+	putString := func(s string) {
+		put(uint64(len(s)))
+		buf.WriteString(s)
+	}
+	tokens := make([]string, len(vocabulary))
+	for i, token := range vocabulary {
+		switch {
+		case strings.HasPrefix(token, "["):
+			tokens[i] = token
+		case strings.HasPrefix(token, "##"):
+			tokens[i] = strings.TrimPrefix(token, "##")
+		default:
+			tokens[i] = "▁" + token
+		}
+	}
+	weights := testWeights()
+	sort.Slice(weights, func(i, j int) bool { return weights[i].name < weights[j].name })
+
+	put(uint32(0x46554747))
+	put(uint32(3))
+	put(uint64(len(weights)))
+	put(uint64(10))
+	putString("general.architecture")
+	put(uint32(8))
+	putString("bert")
+	for key, value := range map[string]uint32{
+		"bert.block_count":                uint32(testConfig.Layers),
+		"bert.embedding_length":           uint32(testConfig.HiddenSize),
+		"bert.feed_forward_length":        uint32(testConfig.IntermediateSize),
+		"bert.attention.head_count":       uint32(testConfig.Heads),
+		"bert.context_length":             uint32(testConfig.MaxPositions),
+		"tokenizer.ggml.cls_token_id":     2,
+		"tokenizer.ggml.unknown_token_id": 1,
+	} {
+		putString(key)
+		put(uint32(4))
+		put(value)
+	}
+	putString("bert.attention.layer_norm_epsilon")
+	put(uint32(6))
+	put(float32(testConfig.LayerNormEpsilon))
+	putString("tokenizer.ggml.tokens")
+	put(uint32(9))
+	put(uint32(8))
+	put(uint64(len(tokens)))
+	for _, token := range tokens {
+		putString(token)
+	}
+	var offset uint64
+	for _, w := range weights {
+		putString(ggufName(w.name))
+		put(uint32(len(w.shape)))
+		for i := len(w.shape) - 1; i >= 0; i-- {
+			put(uint64(w.shape[i]))
+		}
+		put(uint32(0))
+		put(offset)
+		offset += uint64(4*len(w.data)+31) / 32 * 32
+	}
+	for buf.Len()%32 != 0 {
+		buf.WriteByte(0)
+	}
+	for _, w := range weights {
+		buf.Write(encodeValues(w.data, "F32"))
+		for buf.Len()%32 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	return path
+}
+
+// TestTokenizer tests WordPiece tokenization.
+func TestTokenizer(t *testing.T) {
+	a := assert.New(t)
+	tokenizer := local.NewTokenizer(vocabulary)
+	a.Equal(
+		[]string{"the", "dog", "##s", ",", "un", "##aff", "##able", "cafe", "?", "[UNK]"},
+		tokenizer.Tokenize("The  dogs, unaffable\tCAFÉ? xyz"),
+	)
+	ids, err := tokenizer.Encode("the cat is sick", 4)
+	a.NoError(err)
+	a.Equal([]int{2, 4, 7, 3}, ids)
+
+	fromJSON, err := local.ReadTokenizerJSON(strings.NewReader(`{
+		"normalizer": {"type": "BertNormalizer", "lowercase": false},
+		"model": {"type": "WordPiece", "unk_token": "[UNK]", "vocab": {"[UNK]": 0, "Dog": 1, "##s": 2}}
+	}`))
+	require.NoError(t, err)
+	a.Equal([]string{"Dog", "##s", "[UNK]"}, fromJSON.Tokenize("Dogs dog"))
+
+	_, err = local.ReadTokenizerJSON(strings.NewReader(`{"model": {"type": "BPE"}}`))
+	a.Error(err)
+}
+
+// TestEncoder tests that the safetensors and GGUF formats and every weight
+// dtype produce the same normalized embeddings.
+func TestEncoder(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	var embeddings [][]float64
+	for _, dtype := range []string{"F32", "F16", "BF16"} {
+		model, err := local.LoadModel(writeModelDir(t, dtype))
+		require.NoError(t, err, dtype)
+		a.Equal(testConfig, model.Config)
+		embedding, err := local.NewEncoder(model).Encode(ctx, "the dog is sick")
+		require.NoError(t, err, dtype)
+		embeddings = append(embeddings, embedding)
+	}
+	model, err := local.LoadGGUF(writeGGUF(t))
+	require.NoError(t, err)
+	a.Equal(testConfig.HiddenSize, model.Config.HiddenSize)
+	encoder := local.NewEncoder(model)
+	embedding, err := encoder.Encode(ctx, "the dog is sick")
+	require.NoError(t, err)
+	embeddings = append(embeddings, embedding)
+
+	a.Len(embeddings[0], testConfig.HiddenSize)
+	var norm float64
+	for _, v := range embeddings[0] {
+		norm += v * v
+	}
+	a.InDelta(1, norm, 1e-6)
+	for _, other := range embeddings[1:] {
+		a.InDeltaSlice(embeddings[0], other, 1e-6)
+	}
+
+	other, err := encoder.Encode(ctx, "what is your favorite color?")
+	require.NoError(t, err)
+	a.NotEqual(embedding, other)
+
+	long := strings.Repeat("dog ", 40)
+	_, err = encoder.Encode(ctx, long)
+	a.NoError(err, "long inputs are truncated")
+	truncated, err := local.NewEncoder(model, local.WithMaxLength(3)).Encode(ctx, "the dog is sick")
+	require.NoError(t, err)
+	a.NotEqual(embedding, truncated)
+}
+
+// TestLoadModelErrors tests that invalid models are rejected.
+func TestLoadModelErrors(t *testing.T) {
+	a := assert.New(t)
+	_, err := local.LoadModel(t.TempDir())
+	a.ErrorContains(err, "config")
+
+	dir := writeModelDir(t, "F32")
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, "config.json"),
+		[]byte(`{"hidden_size": 8, "num_hidden_layers": 3, "num_attention_heads": 2, "intermediate_size": 16}`),
+		0o644,
+	))
+	_, err = local.LoadModel(dir)
+	a.ErrorContains(err, "missing weight encoder.layer.2")
+
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	require.NoError(t, os.WriteFile(path, []byte("GGML"), 0o644))
+	_, err = local.LoadGGUF(path)
+	a.Error(err)
+}
+
+// TestRouter tests routing with the local encoder.
+func TestRouter(t *testing.T) {
+	a := assert.New(t)
+	model, err := local.LoadModel(writeModelDir(t, "F32"))
+	require.NoError(t, err)
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{
+			{
+				Name: "noteworthy",
+				Utterances: []semanticrouter.Utterance{
+					{Utterance: "my dog has a cold"},
+					{Utterance: "my cat is limping"},
+				},
+			},
+			{
+				Name: "chitchat",
+				Utterances: []semanticrouter.Utterance{
+					{Utterance: "what is your favorite color?"},
+				},
+			},
+		},
+		local.NewEncoder(model),
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	route, score, err := router.Match(context.Background(), "my dog has a cold")
+	a.NoError(err)
+	a.Equal("noteworthy", route.Name)
+	a.InDelta(1, score, 1e-6)
+}
@@ -0,0 +1,86 @@
+package local
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// safetensorsEntry describes one tensor in a safetensors header.
+type safetensorsEntry struct {
+	DType       string   `json:"dtype"`
+	Shape       []int    `json:"shape"`
+	DataOffsets [2]int64 `json:"data_offsets"`
+}
+
+// readSafetensors reads every tensor of the safetensors file at the given
+// path.
+func readSafetensors(path string) (map[string]*tensor, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading safetensors: %w", err)
+	}
+	return parseSafetensors(raw)
+}
+
+// parseSafetensors parses the tensors of a safetensors file.
+//
+// The file holds a little-endian uint64 header length, a JSON header mapping
+// tensor names to their data type, shape and byte range, and the tensor data.
+func parseSafetensors(raw []byte) (map[string]*tensor, error) {
+	if len(raw) < 8 {
+		return nil, fmt.Errorf("error parsing safetensors: file too short")
+	}
+	n := binary.LittleEndian.Uint64(raw)
+	if n > uint64(len(raw)-8) {
+		return nil, fmt.Errorf("error parsing safetensors: header length %d out of range", n)
+	}
+	var header map[string]json.RawMessage
+	err := json.Unmarshal(raw[8:8+n], &header)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing safetensors header: %w", err)
+	}
+	data := raw[8+n:]
+	tensors := make(map[string]*tensor, len(header))
+	for name, msg := range header {
+		if name == "__metadata__" {
+			continue
+		}
+		var entry safetensorsEntry
+		err = json.Unmarshal(msg, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing safetensors entry %s: %w", name, err)
+		}
+		var d dtype
+		switch entry.DType {
+		case "F32":
+			d = dtypeF32
+		case "F16":
+			d = dtypeF16
+		case "BF16":
+			d = dtypeBF16
+		default:
+			return nil, fmt.Errorf(
+				"error parsing safetensors entry %s: unsupported dtype %s",
+				name,
+				entry.DType,
+			)
+		}
+		start, end := entry.DataOffsets[0], entry.DataOffsets[1]
+		if start < 0 || end < start || end > int64(len(data)) {
+			return nil, fmt.Errorf(
+				"error parsing safetensors entry %s: data offsets %d-%d out of range",
+				name,
+				start,
+				end,
+			)
+		}
+		t, err := decode(data[start:end], d, entry.Shape)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing safetensors entry %s: %w", name, err)
+		}
+		tensors[name] = t
+	}
+	return tensors, nil
+}
@@ -0,0 +1,87 @@
+package local
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// tensor is a dense row-major float32 tensor.
+type tensor struct {
+	shape []int
+	data  []float32
+}
+
+// size returns the number of elements of a tensor of the given shape.
+func size(shape []int) int {
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
+
+// dtype is the element type of serialized tensor data.
+type dtype int
+
+const (
+	dtypeF32 dtype = iota
+	dtypeF16
+	dtypeBF16
+)
+
+// bytes returns the size in bytes of one element of the data type.
+func (d dtype) bytes() int {
+	if d == dtypeF32 {
+		return 4
+	}
+	return 2
+}
+
+// decode converts little-endian serialized data of the given type and shape
+// into a tensor.
+func decode(raw []byte, d dtype, shape []int) (*tensor, error) {
+	n := size(shape)
+	if len(raw) != n*d.bytes() {
+		return nil, fmt.Errorf(
+			"got %d bytes for %d elements of %d bytes",
+			len(raw),
+			n,
+			d.bytes(),
+		)
+	}
+	t := &tensor{shape: shape, data: make([]float32, n)}
+	for i := range t.data {
+		switch d {
+		case dtypeF32:
+			t.data[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		case dtypeF16:
+			t.data[i] = halfToFloat(binary.LittleEndian.Uint16(raw[2*i:]))
+		case dtypeBF16:
+			t.data[i] = math.Float32frombits(uint32(binary.LittleEndian.Uint16(raw[2*i:])) << 16)
+		}
+	}
+	return t, nil
+}
+
+// halfToFloat converts an IEEE 754 half precision value to float32.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exponent := int32(h>>10) & 0x1f
+	mantissa := uint32(h) & 0x3ff
+	switch {
+	case exponent == 0 && mantissa == 0:
+		return math.Float32frombits(sign)
+	case exponent == 0:
+		// Subnormal half values are normal float32 values.
+		exponent = 1
+		for mantissa&0x400 == 0 {
+			mantissa <<= 1
+			exponent--
+		}
+		mantissa &= 0x3ff
+	case exponent == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | mantissa<<13)
+	}
+	return math.Float32frombits(sign | uint32(exponent+112)<<23 | mantissa<<13)
+}
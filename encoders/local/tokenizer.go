@@ -0,0 +1,286 @@
+package local
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tokenizer is a BERT WordPiece tokenizer.
+//
+// Texts are cleaned, optionally lowercased and stripped of accents, split on
+// whitespace, punctuation and CJK characters, and each word is split into
+// the longest matching vocabulary pieces from left to right, continuation
+// pieces being prefixed with "##".
+type Tokenizer struct {
+	// Vocabulary maps each token to its id.
+	Vocabulary map[string]int
+	// Lowercase lowercases texts and strips their accents.
+	Lowercase bool
+	// Unknown is the token of words that cannot be split into pieces.
+	Unknown string
+	// Classification is the token prepended to each text.
+	Classification string
+	// Separator is the token appended to each text.
+	Separator string
+	// MaxWordLength is the number of runes above which a word is unknown.
+	MaxWordLength int
+}
+
+// NewTokenizer creates a lowercasing tokenizer using the given tokens, whose
+// ids are their positions, and the standard BERT special tokens.
+func NewTokenizer(tokens []string) *Tokenizer {
+	vocabulary := make(map[string]int, len(tokens))
+	for id, token := range tokens {
+		if _, ok := vocabulary[token]; !ok {
+			vocabulary[token] = id
+		}
+	}
+	return &Tokenizer{
+		Vocabulary:     vocabulary,
+		Lowercase:      true,
+		Unknown:        "[UNK]",
+		Classification: "[CLS]",
+		Separator:      "[SEP]",
+		MaxWordLength:  100,
+	}
+}
+
+// ReadVocabulary reads a vocab.txt file holding one token per line and
+// creates a tokenizer from it.
+func ReadVocabulary(r io.Reader) (*Tokenizer, error) {
+	var tokens []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		tokens = append(tokens, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error reading vocabulary: %w", err)
+	}
+	return NewTokenizer(tokens), nil
+}
+
+// tokenizerJSON is the subset of a Hugging Face tokenizer.json file used by
+// the WordPiece tokenizer.
+type tokenizerJSON struct {
+	Normalizer *struct {
+		Type      string `json:"type"`
+		Lowercase *bool  `json:"lowercase"`
+	} `json:"normalizer"`
+	Model struct {
+		Type                    string         `json:"type"`
+		UnkToken                string         `json:"unk_token"`
+		ContinuingSubwordPrefix string         `json:"continuing_subword_prefix"`
+		MaxInputCharsPerWord    int            `json:"max_input_chars_per_word"`
+		Vocab                   map[string]int `json:"vocab"`
+	} `json:"model"`
+}
+
+// ReadTokenizerJSON reads a Hugging Face tokenizer.json file of a WordPiece
+// model and creates a tokenizer from it.
+func ReadTokenizerJSON(r io.Reader) (*Tokenizer, error) {
+	var file tokenizerJSON
+	err := json.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tokenizer: %w", err)
+	}
+	if file.Model.Type != "WordPiece" {
+		return nil, fmt.Errorf(
+			"error reading tokenizer: unsupported model type %q",
+			file.Model.Type,
+		)
+	}
+	if prefix := file.Model.ContinuingSubwordPrefix; prefix != "" && prefix != "##" {
+		return nil, fmt.Errorf(
+			"error reading tokenizer: unsupported subword prefix %q",
+			prefix,
+		)
+	}
+	t := &Tokenizer{
+		Vocabulary:     file.Model.Vocab,
+		Lowercase:      true,
+		Unknown:        file.Model.UnkToken,
+		Classification: "[CLS]",
+		Separator:      "[SEP]",
+		MaxWordLength:  file.Model.MaxInputCharsPerWord,
+	}
+	if t.Unknown == "" {
+		t.Unknown = "[UNK]"
+	}
+	if t.MaxWordLength <= 0 {
+		t.MaxWordLength = 100
+	}
+	if n := file.Normalizer; n != nil && n.Lowercase != nil {
+		t.Lowercase = *n.Lowercase
+	}
+	return t, nil
+}
+
+// LoadTokenizer loads a tokenizer from a tokenizer.json or vocab.txt file,
+// chosen by the extension of the given path.
+func LoadTokenizer(path string) (*Tokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening tokenizer: %w", err)
+	}
+	defer f.Close()
+	if strings.HasSuffix(path, ".json") {
+		return ReadTokenizerJSON(f)
+	}
+	return ReadVocabulary(f)
+}
+
+// Tokenize returns the WordPiece tokens of the given text without special
+// tokens.
+func (t *Tokenizer) Tokenize(text string) []string {
+	var tokens []string
+	for _, word := range t.words(text) {
+		tokens = append(tokens, t.pieces(word)...)
+	}
+	return tokens
+}
+
+// Encode returns the token ids of the given text wrapped in the
+// classification and separator tokens and truncated to at most maxLength
+// ids. A maxLength of zero or less does not truncate.
+func (t *Tokenizer) Encode(text string, maxLength int) ([]int, error) {
+	tokens := t.Tokenize(text)
+	if maxLength > 0 && len(tokens) > maxLength-2 {
+		tokens = tokens[:max(maxLength-2, 0)]
+	}
+	ids := make([]int, 0, len(tokens)+2)
+	for _, token := range append(append([]string{t.Classification}, tokens...), t.Separator) {
+		id, ok := t.Vocabulary[token]
+		if !ok {
+			id, ok = t.Vocabulary[t.Unknown]
+		}
+		if !ok {
+			return nil, fmt.Errorf("token %q is not in the vocabulary", token)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// words splits the given text into words and punctuation as BERT's basic
+// tokenizer does.
+func (t *Tokenizer) words(text string) []string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || isControl(r):
+			continue
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		case isCJK(r):
+			b.WriteRune(' ')
+			b.WriteRune(r)
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	var words []string
+	for _, word := range strings.Fields(b.String()) {
+		if t.Lowercase {
+			word = stripAccents(strings.ToLower(word))
+		}
+		start := 0
+		runes := []rune(word)
+		for i, r := range runes {
+			if !isPunctuation(r) {
+				continue
+			}
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			words = append(words, string(r))
+			start = i + 1
+		}
+		if start < len(runes) {
+			words = append(words, string(runes[start:]))
+		}
+	}
+	return words
+}
+
+// pieces splits a word into the longest matching vocabulary pieces from left
+// to right, or returns the unknown token if that is impossible.
+func (t *Tokenizer) pieces(word string) []string {
+	runes := []rune(word)
+	if t.MaxWordLength > 0 && len(runes) > t.MaxWordLength {
+		return []string{t.Unknown}
+	}
+	var pieces []string
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		var piece string
+		for ; end > start; end-- {
+			candidate := string(runes[start:end])
+			if start > 0 {
+				candidate = "##" + candidate
+			}
+			if _, ok := t.Vocabulary[candidate]; ok {
+				piece = candidate
+				break
+			}
+		}
+		if piece == "" {
+			return []string{t.Unknown}
+		}
+		pieces = append(pieces, piece)
+		start = end
+	}
+	return pieces
+}
+
+// stripAccents removes the combining marks of the canonical decomposition of
+// the given text.
+func stripAccents(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isControl reports whether the rune is a control character other than
+// whitespace.
+func isControl(r rune) bool {
+	if r == '\t' || r == '\n' || r == '\r' {
+		return false
+	}
+	return unicode.In(r, unicode.Cc, unicode.Cf)
+}
+
+// isPunctuation reports whether the rune is punctuation, counting every
+// non-alphanumeric ASCII symbol as punctuation like BERT does.
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) ||
+		(r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// isCJK reports whether the rune is in one of the CJK Unified Ideographs
+// blocks, whose characters BERT tokenizes individually.
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
@@ -0,0 +1,2 @@
+// Package mistral provides encoders for Mistral AI embedding models.
+package mistral
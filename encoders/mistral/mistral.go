@@ -0,0 +1,239 @@
+package mistral
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the Mistral AI API.
+	DefaultBaseURL = "https://api.mistral.ai"
+	// DefaultModel is the default Mistral embedding model.
+	DefaultModel = "mistral-embed"
+	// DefaultBatchSize is the default number of inputs sent per request.
+	DefaultBatchSize = 128
+)
+
+// Encoder is an encoder using Mistral AI embedding models.
+type Encoder struct {
+	// APIKey is the Mistral AI API key.
+	APIKey string
+	// Model is the Mistral embedding model to use.
+	Model string
+	// BatchSize is the maximum number of inputs sent per request.
+	BatchSize int
+	// BaseURL is the base URL of the Mistral AI API.
+	BaseURL string
+	// HTTPClient is the client used to send requests.
+	HTTPClient *http.Client
+}
+
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithBatchSize sets the maximum number of inputs sent per request.
+func WithBatchSize(size int) Option {
+	return func(e *Encoder) {
+		e.BatchSize = size
+	}
+}
+
+// WithBaseURL sets the base URL of the Mistral AI API.
+func WithBaseURL(baseURL string) Option {
+	return func(e *Encoder) {
+		e.BaseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the client used to send requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Encoder) {
+		e.HTTPClient = client
+	}
+}
+
+// NewEncoder creates a new Encoder.
+//
+// If model is empty, DefaultModel is used.
+func NewEncoder(apiKey, model string, opts ...Option) *Encoder {
+	if model == "" {
+		model = DefaultModel
+	}
+	e := &Encoder{
+		APIKey:     apiKey,
+		Model:      model,
+		BatchSize:  DefaultBatchSize,
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// embeddingRequest is the body of a request to the embeddings endpoint.
+type embeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// embeddingResponse is the body of a response from the embeddings endpoint.
+type embeddingResponse struct {
+	ID    string `json:"id"`
+	Data  []data `json:"data"`
+	Model string `json:"model"`
+}
+
+// data is a single embedding of an embeddings response.
+type data struct {
+	Embedding []float64 `json:"embedding"`
+	Index     int       `json:"index"`
+}
+
+// errorResponse is the body of an error response from the Mistral AI API.
+type errorResponse struct {
+	Message any `json:"message"`
+}
+
+// Encode encodes a query string into a Mistral embedding.
+func (e *Encoder) Encode(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	embeddings, err := e.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EncodeBatch encodes the given utterances into Mistral embeddings.
+//
+// The utterances are sent in requests of at most BatchSize inputs.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	size := e.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	result := make([][]float64, 0, len(utterances))
+	for start := 0; start < len(utterances); start += size {
+		end := min(start+size, len(utterances))
+		embeddings, err := e.embed(ctx, utterances[start:end])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, embeddings...)
+	}
+	return result, nil
+}
+
+// embed sends a single request to the embeddings endpoint.
+func (e *Encoder) embed(
+	ctx context.Context,
+	inputs []string,
+) ([][]float64, error) {
+	body, err := json.Marshal(embeddingRequest{
+		Model:          e.Model,
+		Input:          inputs,
+		EncodingFormat: "float",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling embeddings request: %w", err)
+	}
+	var resp embeddingResponse
+	err = e.do(ctx, "/v1/embeddings", body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf(
+			"error creating embeddings: got %d embeddings for %d inputs",
+			len(resp.Data),
+			len(inputs),
+		)
+	}
+	embeddings := make([][]float64, len(inputs))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(inputs) {
+			return nil, fmt.Errorf(
+				"error creating embeddings: embedding index %d out of range",
+				d.Index,
+			)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf(
+				"error creating embeddings: no embedding for input %d",
+				i,
+			)
+		}
+	}
+	return embeddings, nil
+}
+
+// do sends a POST request with the given body to the given path and decodes
+// the JSON response into out.
+func (e *Encoder) do(
+	ctx context.Context,
+	path string,
+	body []byte,
+	out any,
+) error {
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		strings.TrimSuffix(baseURL, "/")+path,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error creating embeddings: %w", err)
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var apiErr errorResponse
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Message != nil {
+			return fmt.Errorf(
+				"error creating embeddings: %s: %v",
+				res.Status,
+				apiErr.Message,
+			)
+		}
+		return fmt.Errorf("error creating embeddings: %s", res.Status)
+	}
+	err = json.Unmarshal(raw, out)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package mistral_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/mistral"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder      = (*mistral.Encoder)(nil)
+	_ semanticrouter.BatchEncoder = (*mistral.Encoder)(nil)
+)
+
+// request is the body of a request received by the fake Mistral AI API.
+type request struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// newFakeServer returns a server that replays the given recorded responses,
+// one per request, and records the requests it receives.
+func newFakeServer(
+	t *testing.T,
+	status int,
+	requests *[]request,
+	fixtures ...string,
+) *httptest.Server {
+	t.Helper()
+	bodies := make([][]byte, len(fixtures))
+	for i, fixture := range fixtures {
+		body, err := os.ReadFile(filepath.Join("testdata", fixture))
+		require.NoError(t, err)
+		bodies[i] = body
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var req request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		body := bodies[len(*requests)%len(bodies)]
+		*requests = append(*requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestEncoderBatch tests encoding several utterances in one request.
+func TestEncoderBatch(t *testing.T) {
+	a := assert.New(t)
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, &requests, "embeddings.json")
+	encoder := mistral.NewEncoder(
+		"test-key",
+		"",
+		mistral.WithBaseURL(srv.URL),
+		mistral.WithHTTPClient(srv.Client()),
+	)
+	result, err := encoder.EncodeBatch(context.Background(), []string{
+		"how's the weather today?",
+		"lovely weather today",
+	})
+	a.NoError(err)
+	a.Len(result, 2)
+	a.Len(result[0], 8)
+	a.InDelta(-0.0165863037109375, result[0][0], 1e-12)
+	a.InDelta(0.002964019775390625, result[1][7], 1e-12)
+	a.Equal([]request{{
+		Model:          mistral.DefaultModel,
+		Input:          []string{"how's the weather today?", "lovely weather today"},
+		EncodingFormat: "float",
+	}}, requests)
+}
+
+// TestEncoderBatchSize tests that batches are split into several requests and
+// that embeddings are ordered by their index.
+func TestEncoderBatchSize(t *testing.T) {
+	a := assert.New(t)
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, &requests, "embeddings_unordered.json")
+	encoder := mistral.NewEncoder(
+		"test-key",
+		"mistral-embed",
+		mistral.WithBaseURL(srv.URL),
+		mistral.WithBatchSize(2),
+	)
+	result, err := encoder.EncodeBatch(context.Background(), []string{
+		"a", "b", "c", "d",
+	})
+	a.NoError(err)
+	a.Equal([][]float64{
+		{0.75, 1.0}, {0.25, 0.5},
+		{0.75, 1.0}, {0.25, 0.5},
+	}, result)
+	a.Len(requests, 2)
+	a.Equal([]string{"c", "d"}, requests[1].Input)
+}
+
+// TestEncoderMismatchedCount tests that a response with the wrong number of
+// embeddings is rejected.
+func TestEncoderMismatchedCount(t *testing.T) {
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, &requests, "embeddings.json")
+	encoder := mistral.NewEncoder(
+		"test-key",
+		"mistral-embed",
+		mistral.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.Encode(context.Background(), "how's the weather today?")
+	assert.Error(t, err)
+}
+
+// TestEncoderDuplicateIndex tests that a response leaving an input without
+// an embedding is rejected.
+func TestEncoderDuplicateIndex(t *testing.T) {
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, &requests, "embeddings_duplicate.json")
+	encoder := mistral.NewEncoder(
+		"test-key",
+		"mistral-embed",
+		mistral.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.EncodeBatch(context.Background(), []string{"a", "b"})
+	assert.ErrorContains(t, err, "no embedding for input 1")
+}
+
+// TestEncoderError tests that API errors are returned.
+func TestEncoderError(t *testing.T) {
+	var requests []request
+	srv := newFakeServer(t, http.StatusUnauthorized, &requests, "error_unauthorized.json")
+	encoder := mistral.NewEncoder(
+		"test-key",
+		"mistral-embed",
+		mistral.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.Encode(context.Background(), "how's the weather today?")
+	assert.ErrorContains(t, err, "Unauthorized")
+}
@@ -2,20 +2,138 @@ package ollama
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/conneroisu/semanticrouter-go"
 	"github.com/ollama/ollama/api"
 )
 
 // Encoder is an encoder using Ollama models.
+//
+// By default it uses the /api/embeddings endpoint, which embeds one prompt
+// per request, as earlier versions did. The /api/embed endpoint, selected
+// with WithEmbedEndpoint, embeds many inputs per request but returns
+// normalized embeddings: the embeddings stored with one endpoint must be
+// encoded again when switching to the other.
 type Encoder struct {
 	Client *api.Client
 	Model  string
+	// KeepAlive controls how long the model stays loaded after a request.
+	// It is left to the server default if nil.
+	KeepAlive *api.Duration
+	// Truncate controls whether inputs longer than the context length are
+	// truncated instead of returning an error. It is left to the server
+	// default if nil. Only the /api/embed endpoint supports it: encoding
+	// with the /api/embeddings endpoint fails if it is set.
+	Truncate *bool
+	// Options are model options, such as num_ctx, sent with each request.
+	Options map[string]any
+	// EmbedEndpoint selects the batching /api/embed endpoint instead of the
+	// single-prompt /api/embeddings endpoint.
+	EmbedEndpoint bool
+}
+
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithKeepAlive sets how long the model stays loaded after a request.
+//
+// A negative duration keeps the model loaded indefinitely and zero unloads
+// it immediately.
+func WithKeepAlive(keepAlive time.Duration) Option {
+	return func(e *Encoder) {
+		e.KeepAlive = &api.Duration{Duration: keepAlive}
+	}
+}
+
+// WithTruncate sets whether inputs longer than the context length are
+// truncated. It requires the /api/embed endpoint, see WithEmbedEndpoint.
+func WithTruncate(truncate bool) Option {
+	return func(e *Encoder) {
+		e.Truncate = &truncate
+	}
+}
+
+// WithOptions sets model options sent with each request.
+func WithOptions(options map[string]any) Option {
+	return func(e *Encoder) {
+		if e.Options == nil {
+			e.Options = make(map[string]any, len(options))
+		}
+		for k, v := range options {
+			e.Options[k] = v
+		}
+	}
+}
+
+// WithNumCtx sets the context length of the model.
+func WithNumCtx(numCtx int) Option {
+	return WithOptions(map[string]any{"num_ctx": numCtx})
+}
+
+// WithEmbedEndpoint selects the /api/embed endpoint, which embeds many
+// inputs per request and returns normalized embeddings.
+//
+// The embeddings stored with the default /api/embeddings endpoint are not
+// normalized, so they must be encoded again, with a new store for instance.
+func WithEmbedEndpoint() Option {
+	return func(e *Encoder) {
+		e.EmbedEndpoint = true
+	}
 }
 
 // NewEncoder creates a new Encoder.
-func NewEncoder(client *api.Client, model string) *Encoder {
-	return &Encoder{Client: client, Model: model}
+func NewEncoder(client *api.Client, model string, opts ...Option) *Encoder {
+	e := &Encoder{Client: client, Model: model}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// NewVerifiedEncoder creates a new Encoder and verifies that its model is
+// available on the Ollama server, pulling it first if it is missing.
+func NewVerifiedEncoder(
+	ctx context.Context,
+	client *api.Client,
+	model string,
+	opts ...Option,
+) (*Encoder, error) {
+	e := NewEncoder(client, model, opts...)
+	err := e.Verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Verify verifies that the encoder's model is available on the Ollama server,
+// pulling it if it is missing.
+func (e *Encoder) Verify(ctx context.Context) error {
+	_, err := e.Client.Show(ctx, &api.ShowRequest{Model: e.Model})
+	if err == nil {
+		return nil
+	}
+	var statusErr api.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("error showing model %s: %w", e.Model, err)
+	}
+	err = e.Client.Pull(
+		ctx,
+		&api.PullRequest{Model: e.Model},
+		func(api.ProgressResponse) error { return nil },
+	)
+	if err != nil {
+		return fmt.Errorf("error pulling model %s: %w", e.Model, err)
+	}
+	_, err = e.Client.Show(ctx, &api.ShowRequest{Model: e.Model})
+	if err != nil {
+		return fmt.Errorf("error showing model %s: %w", e.Model, err)
+	}
+	return nil
 }
 
 // Encode encodes a query string into a Ollama embedding.
@@ -27,15 +145,131 @@ func (e *Encoder) Encode(
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
-		req := &api.EmbeddingRequest{
-			Model:  e.Model,
-			Prompt: query,
+	}
+	if !e.EmbedEndpoint {
+		return e.embeddings(ctx, query)
+	}
+	embeddings, err := e.embed(ctx, query, 1)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EncodeBatch encodes the given utterances into Ollama embeddings, with a
+// single request if the encoder uses the /api/embed endpoint.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	if e.EmbedEndpoint {
+		return e.embed(ctx, utterances, len(utterances))
+	}
+	result := make([][]float64, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.embeddings(ctx, utterance)
+		if err != nil {
+			return nil, err
 		}
-		em, err := e.Client.Embeddings(ctx, req)
+		result[i] = embedding
+	}
+	return result, nil
+}
+
+// EncodeFloat32 encodes the given utterances into float32 Ollama embeddings,
+// as returned by the /api/embed endpoint with a single request if the encoder
+// uses it.
+func (e *Encoder) EncodeFloat32(
+	ctx context.Context,
+	utterances []string,
+) ([][]float32, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	if e.EmbedEndpoint {
+		return e.embed32(ctx, utterances, len(utterances))
+	}
+	result := make([][]float32, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.embeddings(ctx, utterance)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		result = em.Embedding
-		return result, nil
+		result[i] = semanticrouter.Convert[float32](embedding)
+	}
+	return result, nil
+}
+
+// embed sends a request to the /api/embed endpoint for the given input,
+// which is either a string or a slice of strings.
+func (e *Encoder) embed(
+	ctx context.Context,
+	input any,
+	count int,
+) ([][]float64, error) {
+	embeddings, err := e.embed32(ctx, input, count)
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]float64, len(embeddings))
+	for i, embedding := range embeddings {
+		result[i] = semanticrouter.Convert[float64](embedding)
+	}
+	return result, nil
+}
+
+// embed32 sends a request to the /api/embed endpoint for the given input and
+// returns the float32 embeddings of the response.
+func (e *Encoder) embed32(
+	ctx context.Context,
+	input any,
+	count int,
+) ([][]float32, error) {
+	resp, err := e.Client.Embed(ctx, &api.EmbedRequest{
+		Model:     e.Model,
+		Input:     input,
+		KeepAlive: e.KeepAlive,
+		Truncate:  e.Truncate,
+		Options:   e.Options,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating embeddings: %w", err)
+	}
+	if len(resp.Embeddings) != count {
+		return nil, fmt.Errorf(
+			"error creating embeddings: got %d embeddings for %d inputs",
+			len(resp.Embeddings),
+			count,
+		)
+	}
+	return resp.Embeddings, nil
+}
+
+// embeddings sends a request to the /api/embeddings endpoint.
+func (e *Encoder) embeddings(
+	ctx context.Context,
+	prompt string,
+) ([]float64, error) {
+	if e.Truncate != nil {
+		return nil, fmt.Errorf(
+			"error creating embedding: truncate requires the /api/embed endpoint",
+		)
+	}
+	em, err := e.Client.Embeddings(ctx, &api.EmbeddingRequest{
+		Model:     e.Model,
+		Prompt:    prompt,
+		KeepAlive: e.KeepAlive,
+		Options:   e.Options,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating embedding: %w", err)
 	}
+	return em.Embedding, nil
 }
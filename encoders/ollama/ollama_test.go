@@ -2,13 +2,25 @@ package ollama_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
+	"github.com/conneroisu/semanticrouter-go"
 	"github.com/conneroisu/semanticrouter-go/encoders/ollama"
 	"github.com/ollama/ollama/api"
 	"github.com/stretchr/testify/assert"
 )
 
+var (
+	_ semanticrouter.BatchEncoder   = (*ollama.Encoder)(nil)
+	_ semanticrouter.Float32Encoder = (*ollama.Encoder)(nil)
+)
+
 // TestEncoder tests the encoder.
 func TestEncoder(t *testing.T) {
 	ctx := context.Background()
@@ -21,3 +33,177 @@ func TestEncoder(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+// fakeServer is a stand-in for an Ollama server.
+type fakeServer struct {
+	pulled   bool
+	requests []map[string]any
+	failWith string
+}
+
+// ServeHTTP serves the show, pull, embed and embeddings endpoints.
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	req["path"] = r.URL.Path
+	f.requests = append(f.requests, req)
+	w.Header().Set("Content-Type", "application/json")
+	if f.failWith != "" {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, `{"error":%q}`, f.failWith)
+		return
+	}
+	switch r.URL.Path {
+	case "/api/show":
+		if !f.pulled {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprintf(w, `{"error":"model '%s' not found"}`, req["model"])
+			return
+		}
+		_, _ = w.Write([]byte(`{"details":{"family":"bert"}}`))
+	case "/api/pull":
+		f.pulled = true
+		_, _ = w.Write([]byte("{\"status\":\"pulling manifest\"}\n{\"status\":\"success\"}\n"))
+	case "/api/embed":
+		inputs, ok := req["input"].([]any)
+		if !ok {
+			inputs = []any{req["input"]}
+		}
+		embeddings := make([][]float32, len(inputs))
+		for i := range inputs {
+			embeddings[i] = []float32{float32(i), 0.5}
+		}
+		_ = json.NewEncoder(w).Encode(api.EmbedResponse{
+			Model:      req["model"].(string),
+			Embeddings: embeddings,
+		})
+	case "/api/embeddings":
+		_ = json.NewEncoder(w).Encode(api.EmbeddingResponse{
+			Embedding: []float64{0.25, 0.75},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newFakeClient returns a client of the given fake Ollama server.
+func newFakeClient(t *testing.T, f *fakeServer) *api.Client {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	base, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.NewClient(base, srv.Client())
+}
+
+// TestEncoderEmbed tests encoding with the /api/embed endpoint and its
+// request options.
+func TestEncoderEmbed(t *testing.T) {
+	a := assert.New(t)
+	f := &fakeServer{pulled: true}
+	encoder := ollama.NewEncoder(
+		newFakeClient(t, f),
+		"all-minilm",
+		ollama.WithEmbedEndpoint(),
+		ollama.WithKeepAlive(5*time.Minute),
+		ollama.WithTruncate(false),
+		ollama.WithNumCtx(256),
+	)
+	result, err := encoder.Encode(context.Background(), "hello world")
+	a.NoError(err)
+	a.Equal([]float64{0, 0.5}, result)
+	a.Len(f.requests, 1)
+	a.Equal("/api/embed", f.requests[0]["path"])
+	a.Equal("hello world", f.requests[0]["input"])
+	a.Equal("5m0s", f.requests[0]["keep_alive"])
+	a.Equal(false, f.requests[0]["truncate"])
+	a.Equal(map[string]any{"num_ctx": float64(256)}, f.requests[0]["options"])
+}
+
+// TestEncoderBatch tests encoding several utterances with one request.
+func TestEncoderBatch(t *testing.T) {
+	a := assert.New(t)
+	f := &fakeServer{pulled: true}
+	encoder := ollama.NewEncoder(newFakeClient(t, f), "all-minilm", ollama.WithEmbedEndpoint())
+	result, err := encoder.EncodeBatch(context.Background(), []string{"a", "b", "c"})
+	a.NoError(err)
+	a.Equal([][]float64{{0, 0.5}, {1, 0.5}, {2, 0.5}}, result)
+	a.Len(f.requests, 1)
+	a.Equal([]any{"a", "b", "c"}, f.requests[0]["input"])
+}
+
+// TestEncoderFloat32 tests encoding float32 embeddings with one request.
+func TestEncoderFloat32(t *testing.T) {
+	a := assert.New(t)
+	f := &fakeServer{pulled: true}
+	encoder := ollama.NewEncoder(newFakeClient(t, f), "all-minilm", ollama.WithEmbedEndpoint())
+	result, err := encoder.EncodeFloat32(context.Background(), []string{"a", "b"})
+	a.NoError(err)
+	a.Equal([][]float32{{0, 0.5}, {1, 0.5}}, result)
+	a.Len(f.requests, 1)
+}
+
+// TestEncoderEmbeddings tests encoding with the default /api/embeddings
+// endpoint, one request per utterance.
+func TestEncoderEmbeddings(t *testing.T) {
+	a := assert.New(t)
+	f := &fakeServer{pulled: true}
+	encoder := ollama.NewEncoder(newFakeClient(t, f), "all-minilm")
+	result, err := encoder.EncodeBatch(context.Background(), []string{"a", "b"})
+	a.NoError(err)
+	a.Equal([][]float64{{0.25, 0.75}, {0.25, 0.75}}, result)
+	a.Len(f.requests, 2)
+	a.Equal("/api/embeddings", f.requests[1]["path"])
+	a.Equal("b", f.requests[1]["prompt"])
+}
+
+// TestEncoderEmbeddingsTruncate tests that the /api/embeddings endpoint,
+// which cannot truncate inputs, is not used when truncation is set.
+func TestEncoderEmbeddingsTruncate(t *testing.T) {
+	f := &fakeServer{pulled: true}
+	encoder := ollama.NewEncoder(newFakeClient(t, f), "all-minilm", ollama.WithTruncate(true))
+	_, err := encoder.Encode(context.Background(), "hello world")
+	assert.ErrorContains(t, err, "truncate requires the /api/embed endpoint")
+	assert.Empty(t, f.requests)
+}
+
+// TestEncoderError tests that server errors are returned instead of exiting.
+func TestEncoderError(t *testing.T) {
+	f := &fakeServer{failWith: "llama runner process has terminated"}
+	encoder := ollama.NewEncoder(newFakeClient(t, f), "all-minilm")
+	_, err := encoder.Encode(context.Background(), "hello world")
+	assert.ErrorContains(t, err, "llama runner process has terminated")
+}
+
+// TestNewVerifiedEncoder tests that a missing model is pulled at construction
+// time.
+func TestNewVerifiedEncoder(t *testing.T) {
+	a := assert.New(t)
+	f := &fakeServer{}
+	encoder, err := ollama.NewVerifiedEncoder(
+		context.Background(),
+		newFakeClient(t, f),
+		"all-minilm",
+	)
+	a.NoError(err)
+	a.NotNil(encoder)
+	a.True(f.pulled)
+	paths := make([]any, len(f.requests))
+	for i, req := range f.requests {
+		paths[i] = req["path"]
+	}
+	a.Equal([]any{"/api/show", "/api/pull", "/api/show"}, paths)
+}
+
+// TestNewVerifiedEncoderError tests that verification errors are returned.
+func TestNewVerifiedEncoderError(t *testing.T) {
+	f := &fakeServer{failWith: "connection reset"}
+	_, err := ollama.NewVerifiedEncoder(
+		context.Background(),
+		newFakeClient(t, f),
+		"all-minilm",
+	)
+	assert.ErrorContains(t, err, "connection reset")
+}
@@ -0,0 +1,10 @@
+// Package postprocess provides an encoder that post-processes the embeddings
+// of another encoder.
+//
+// A Pipeline of steps, such as L2 normalization, Matryoshka truncation,
+// centering, whitening, PCA projection and int8 or binary quantization, is
+// applied to both the route utterances and the matched utterances. Steps
+// that are fitted, such as centering, are fitted on the route utterances,
+// and every step serializes to JSON so that a fitted pipeline can be saved
+// and applied again to the stored embeddings.
+package postprocess
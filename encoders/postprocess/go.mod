@@ -0,0 +1,14 @@
+module github.com/conneroisu/semanticrouter-go/encoders/postprocess
+
+go 1.23.0
+
+require (
+	github.com/stretchr/testify v1.9.0
+	gonum.org/v1/gonum v0.15.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
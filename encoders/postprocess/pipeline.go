@@ -0,0 +1,118 @@
+package postprocess
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Pipeline is a sequence of steps applied in order.
+//
+// It marshals to a JSON array of objects holding the type of each step and
+// its fields, for example:
+//
+//	[{"type": "center", "mean": [0.1, 0.2]}, {"type": "l2"}]
+type Pipeline []Step
+
+// Fitted reports whether every step of the pipeline that must be fitted has
+// been fitted.
+func (p Pipeline) Fitted() bool {
+	for _, step := range p {
+		if f, ok := step.(Fitter); ok && !f.Fitted() {
+			return false
+		}
+	}
+	return true
+}
+
+// Fit fits the unfitted steps of the pipeline on the given embeddings, each
+// step being fitted on the output of the steps before it, and returns the
+// embeddings transformed by the whole pipeline.
+func (p Pipeline) Fit(embeddings [][]float64) ([][]float64, error) {
+	for _, step := range p {
+		if f, ok := step.(Fitter); ok && !f.Fitted() {
+			err := f.Fit(embeddings)
+			if err != nil {
+				return nil, fmt.Errorf("error fitting %s step: %w", step.Type(), err)
+			}
+		}
+		transformed := make([][]float64, len(embeddings))
+		for i, embedding := range embeddings {
+			result, err := step.Apply(embedding)
+			if err != nil {
+				return nil, fmt.Errorf("error applying %s step: %w", step.Type(), err)
+			}
+			transformed[i] = result
+		}
+		embeddings = transformed
+	}
+	return embeddings, nil
+}
+
+// Apply returns the embedding transformed by every step of the pipeline.
+func (p Pipeline) Apply(embedding []float64) ([]float64, error) {
+	for _, step := range p {
+		result, err := step.Apply(embedding)
+		if err != nil {
+			return nil, fmt.Errorf("error applying %s step: %w", step.Type(), err)
+		}
+		embedding = result
+	}
+	return embedding, nil
+}
+
+// MarshalJSON marshals the pipeline as an array of typed steps.
+func (p Pipeline) MarshalJSON() ([]byte, error) {
+	result := make([]json.RawMessage, len(p))
+	for i, step := range p {
+		fields, err := json.Marshal(step)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling %s step: %w", step.Type(), err)
+		}
+		var object map[string]json.RawMessage
+		err = json.Unmarshal(fields, &object)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling %s step: %w", step.Type(), err)
+		}
+		if object == nil {
+			object = make(map[string]json.RawMessage)
+		}
+		object["type"], _ = json.Marshal(step.Type())
+		result[i], err = json.Marshal(object)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling %s step: %w", step.Type(), err)
+		}
+	}
+	return json.Marshal(result)
+}
+
+// UnmarshalJSON unmarshals an array of typed steps, whose types must be
+// registered with RegisterStep.
+func (p *Pipeline) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling pipeline: %w", err)
+	}
+	pipeline := make(Pipeline, len(raw))
+	for i, msg := range raw {
+		var header struct {
+			Type string `json:"type"`
+		}
+		err = json.Unmarshal(msg, &header)
+		if err != nil {
+			return fmt.Errorf("error unmarshaling step %d: %w", i, err)
+		}
+		fn, ok := steps[header.Type]
+		if !ok {
+			return fmt.Errorf("error unmarshaling step %d: unknown type %q", i, header.Type)
+		}
+		step := fn()
+		err = json.Unmarshal(msg, step)
+		if err != nil {
+			return fmt.Errorf("error unmarshaling %s step: %w", header.Type, err)
+		}
+		pipeline[i] = step
+	}
+	*p = pipeline
+	return nil
+}
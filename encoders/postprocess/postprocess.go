@@ -0,0 +1,171 @@
+package postprocess
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/conneroisu/semanticrouter-go"
+)
+
+// Encoder wraps an encoder and post-processes its embeddings with a
+// pipeline.
+//
+// The unfitted steps of the pipeline are fitted on the route utterances the
+// first time EncodeDocuments is called, which NewRouter does for the
+// utterances that are not already stored. If the storage already holds the
+// route embeddings, load the pipeline they were encoded with, or call Fit
+// with the route utterances, before matching.
+//
+// The encoder is safe for concurrent use as long as the pipeline is not
+// modified directly.
+type Encoder struct {
+	// Encoder is the wrapped encoder.
+	Encoder semanticrouter.Encoder
+	// Pipeline is the post-processing applied to every embedding.
+	Pipeline Pipeline
+
+	mu sync.RWMutex
+}
+
+// NewEncoder creates an encoder post-processing the embeddings of the given
+// encoder with the given steps.
+func NewEncoder(encoder semanticrouter.Encoder, steps ...Step) *Encoder {
+	return &Encoder{
+		Encoder:  encoder,
+		Pipeline: steps,
+	}
+}
+
+// Encode encodes a query string with the wrapped encoder and post-processes
+// the embedding.
+//
+// It returns ErrNotFitted if a step of the pipeline has not been fitted.
+func (e *Encoder) Encode(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	embedding, err := e.Encoder.Encode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return e.apply(embedding)
+}
+
+// EncodeBatch encodes the given utterances with the wrapped encoder and
+// post-processes the embeddings.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	embeddings, err := e.encodeBatch(ctx, utterances)
+	if err != nil {
+		return nil, err
+	}
+	return e.applyAll(embeddings)
+}
+
+// EncodeQuery encodes the given query as a query if the wrapped encoder
+// distinguishes queries from documents, and post-processes the embedding.
+func (e *Encoder) EncodeQuery(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	qd, ok := e.Encoder.(semanticrouter.QueryDocumentEncoder)
+	if !ok {
+		return e.Encode(ctx, query)
+	}
+	embedding, err := qd.EncodeQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return e.apply(embedding)
+}
+
+// EncodeDocuments encodes the given documents as documents if the wrapped
+// encoder distinguishes queries from documents, fits the unfitted steps of
+// the pipeline on them, and post-processes the embeddings.
+func (e *Encoder) EncodeDocuments(
+	ctx context.Context,
+	documents []string,
+) ([][]float64, error) {
+	var embeddings [][]float64
+	var err error
+	if qd, ok := e.Encoder.(semanticrouter.QueryDocumentEncoder); ok {
+		embeddings, err = qd.EncodeDocuments(ctx, documents)
+	} else {
+		embeddings, err = e.encodeBatch(ctx, documents)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.mu.RLock()
+	fitted := e.Pipeline.Fitted()
+	e.mu.RUnlock()
+	if fitted {
+		return e.applyAll(embeddings)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.Pipeline.Fit(embeddings)
+}
+
+// Fit encodes the given utterances as documents and fits the unfitted steps
+// of the pipeline on them.
+func (e *Encoder) Fit(ctx context.Context, utterances []string) error {
+	_, err := e.EncodeDocuments(ctx, utterances)
+	return err
+}
+
+// encodeBatch encodes the given utterances with the wrapped encoder, in a
+// single call if it is a BatchEncoder.
+func (e *Encoder) encodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	if batch, ok := e.Encoder.(semanticrouter.BatchEncoder); ok {
+		embeddings, err := batch.EncodeBatch(ctx, utterances)
+		if err != nil {
+			return nil, err
+		}
+		if len(embeddings) != len(utterances) {
+			return nil, fmt.Errorf(
+				"error encoding utterances: got %d embeddings for %d utterances",
+				len(embeddings),
+				len(utterances),
+			)
+		}
+		return embeddings, nil
+	}
+	embeddings := make([][]float64, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.Encoder.Encode(ctx, utterance)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// apply post-processes a single embedding.
+func (e *Encoder) apply(embedding []float64) ([]float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.Pipeline.Apply(embedding)
+}
+
+// applyAll post-processes the given embeddings.
+func (e *Encoder) applyAll(embeddings [][]float64) ([][]float64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	result := make([][]float64, len(embeddings))
+	for i, embedding := range embeddings {
+		processed, err := e.Pipeline.Apply(embedding)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = processed
+	}
+	return result, nil
+}
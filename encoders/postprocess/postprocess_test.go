@@ -0,0 +1,180 @@
+package postprocess_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/postprocess"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder              = (*postprocess.Encoder)(nil)
+	_ semanticrouter.BatchEncoder         = (*postprocess.Encoder)(nil)
+	_ semanticrouter.QueryDocumentEncoder = (*postprocess.Encoder)(nil)
+	_ postprocess.Fitter                  = (*postprocess.Center)(nil)
+	_ postprocess.Fitter                  = (*postprocess.PCA)(nil)
+	_ postprocess.Fitter                  = (*postprocess.Whiten)(nil)
+	_ postprocess.Fitter                  = (*postprocess.Int8)(nil)
+)
+
+// fakeEncoder embeds utterances as fixed vectors.
+type fakeEncoder map[string][]float64
+
+// Encode returns the fixed vector of the given utterance.
+func (e fakeEncoder) Encode(_ context.Context, utterance string) ([]float64, error) {
+	return e[utterance], nil
+}
+
+// TestSteps tests the stateless steps.
+func TestSteps(t *testing.T) {
+	a := assert.New(t)
+	normalized, err := (&postprocess.Normalize{}).Apply([]float64{3, 4})
+	a.NoError(err)
+	a.Equal([]float64{0.6, 0.8}, normalized)
+
+	truncated, err := (&postprocess.Truncate{Dimensions: 2}).Apply([]float64{1, 2, 3})
+	a.NoError(err)
+	a.Equal([]float64{1, 2}, truncated)
+	_, err = (&postprocess.Truncate{Dimensions: 4}).Apply([]float64{1, 2, 3})
+	a.Error(err)
+
+	binary, err := (&postprocess.Binary{}).Apply([]float64{0.5, -0.1, 0})
+	a.NoError(err)
+	a.Equal([]float64{1, -1, -1}, binary)
+
+	quantized, err := (&postprocess.Int8{Range: 1}).Apply([]float64{-1, 0, 1, 2})
+	a.NoError(err)
+	a.Equal([]float64{-128, 0, 127, 127}, quantized)
+}
+
+// TestFittedSteps tests fitting centering, PCA, whitening and int8
+// quantization.
+func TestFittedSteps(t *testing.T) {
+	a := assert.New(t)
+	embeddings := [][]float64{{1, 1, 0}, {3, 3, 0}, {2, 2, 1}, {2, 2, -1}}
+
+	center := &postprocess.Center{}
+	_, err := center.Apply([]float64{1, 1, 1})
+	a.ErrorIs(err, postprocess.ErrNotFitted)
+	require.NoError(t, center.Fit(embeddings))
+	centered, err := center.Apply([]float64{1, 1, 1})
+	a.NoError(err)
+	a.Equal([]float64{-1, -1, 1}, centered)
+
+	pca := &postprocess.PCA{Components: 1}
+	require.NoError(t, pca.Fit(embeddings))
+	a.Len(pca.Basis, 1)
+	projected, err := pca.Apply([]float64{3, 3, 0})
+	a.NoError(err)
+	a.Len(projected, 1)
+	a.InDelta(1.4142135623730951, abs(projected[0]), 1e-9)
+
+	whiten := &postprocess.Whiten{}
+	require.NoError(t, whiten.Fit(embeddings))
+	a.Len(whiten.Basis, 2)
+	var sums [2]float64
+	for _, embedding := range embeddings {
+		whitened, err := whiten.Apply(embedding)
+		require.NoError(t, err)
+		for k, v := range whitened {
+			sums[k] += v * v
+		}
+	}
+	// Each whitened component has unit sample variance.
+	a.InDelta(3, sums[0], 1e-9)
+	a.InDelta(3, sums[1], 1e-9)
+
+	quantize := &postprocess.Int8{}
+	require.NoError(t, quantize.Fit(embeddings))
+	quantized, err := quantize.Apply([]float64{1, 3, 0})
+	a.NoError(err)
+	a.Equal([]float64{-128, 127, 0}, quantized)
+}
+
+// abs returns the absolute value of x.
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// TestPipelineJSON tests that a fitted pipeline survives a JSON round trip.
+func TestPipelineJSON(t *testing.T) {
+	a := assert.New(t)
+	pipeline := postprocess.Pipeline{
+		&postprocess.Truncate{Dimensions: 2},
+		&postprocess.Center{},
+		&postprocess.Normalize{},
+		&postprocess.Int8{Range: 1},
+	}
+	_, err := pipeline.Fit([][]float64{{1, 2, 9}, {3, 0, 9}})
+	require.NoError(t, err)
+	data, err := json.Marshal(pipeline)
+	require.NoError(t, err)
+	a.JSONEq(`[
+		{"type": "truncate", "dimensions": 2},
+		{"type": "center", "mean": [2, 1]},
+		{"type": "l2"},
+		{"type": "int8", "range": 1}
+	]`, string(data))
+
+	var loaded postprocess.Pipeline
+	require.NoError(t, json.Unmarshal(data, &loaded))
+	a.True(loaded.Fitted())
+	want, err := pipeline.Apply([]float64{4, 4, 4})
+	require.NoError(t, err)
+	got, err := loaded.Apply([]float64{4, 4, 4})
+	require.NoError(t, err)
+	a.Equal(want, got)
+
+	a.Error(json.Unmarshal([]byte(`[{"type": "unknown"}]`), &loaded))
+}
+
+// TestEncoder tests that the pipeline is fitted on the route utterances and
+// applied to matched utterances.
+func TestEncoder(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	base := fakeEncoder{
+		"my dog has a cold":    {5, 5.2, 4.9, 1},
+		"my cat is limping":    {5, 5.3, 4.8, 1},
+		"what's your favorite": {5.2, 4.9, 5, 0},
+		"tell me a joke":       {5.1, 4.9, 5.1, 0},
+		"my dog is sneezing":   {5, 5.25, 4.85, 0.9},
+	}
+	encoder := postprocess.NewEncoder(
+		base,
+		&postprocess.Center{},
+		&postprocess.Normalize{},
+	)
+	_, err := encoder.Encode(ctx, "my dog is sneezing")
+	a.ErrorIs(err, postprocess.ErrNotFitted)
+
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{
+			{Name: "noteworthy", Utterances: []semanticrouter.Utterance{
+				{Utterance: "my dog has a cold"},
+				{Utterance: "my cat is limping"},
+			}},
+			{Name: "chitchat", Utterances: []semanticrouter.Utterance{
+				{Utterance: "what's your favorite"},
+				{Utterance: "tell me a joke"},
+			}},
+		},
+		encoder,
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	a.True(encoder.Pipeline.Fitted())
+	route, score, err := router.Match(ctx, "my dog is sneezing")
+	a.NoError(err)
+	a.Equal("noteworthy", route.Name)
+	a.Greater(score, 0.9)
+}
@@ -0,0 +1,405 @@
+package postprocess
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ErrNotFitted is returned when a step that must be fitted is applied before
+// it has been fitted.
+var ErrNotFitted = errors.New("post-processing step has not been fitted")
+
+// Step is a transformation applied to every embedding.
+//
+// Steps are serialized to JSON with their exported fields and the name
+// returned by Type, which must be registered with RegisterStep.
+type Step interface {
+	// Type returns the registered name of the step.
+	Type() string
+	// Apply returns the transformed embedding. It must not modify the given
+	// embedding.
+	Apply(embedding []float64) ([]float64, error)
+}
+
+// Fitter is a Step that is fitted on the embeddings of the route utterances.
+type Fitter interface {
+	Step
+	// Fit fits the step on the given embeddings.
+	Fit(embeddings [][]float64) error
+	// Fitted reports whether the step has been fitted.
+	Fitted() bool
+}
+
+// steps maps the registered step names to functions creating empty steps.
+var steps = map[string]func() Step{
+	"l2":       func() Step { return &Normalize{} },
+	"truncate": func() Step { return &Truncate{} },
+	"center":   func() Step { return &Center{} },
+	"whiten":   func() Step { return &Whiten{} },
+	"pca":      func() Step { return &PCA{} },
+	"int8":     func() Step { return &Int8{} },
+	"binary":   func() Step { return &Binary{} },
+}
+
+// RegisterStep registers a step type so that pipelines holding it can be
+// unmarshaled. The function must return a pointer to an empty step.
+func RegisterStep(name string, fn func() Step) {
+	steps[name] = fn
+}
+
+// Normalize scales embeddings to unit length. The zero vector is left
+// unchanged.
+type Normalize struct{}
+
+// Type returns "l2".
+func (*Normalize) Type() string { return "l2" }
+
+// Apply returns the embedding scaled to unit length.
+func (*Normalize) Apply(embedding []float64) ([]float64, error) {
+	result := make([]float64, len(embedding))
+	var sum float64
+	for _, v := range embedding {
+		sum += v * v
+	}
+	if sum == 0 {
+		copy(result, embedding)
+		return result, nil
+	}
+	norm := math.Sqrt(sum)
+	for i, v := range embedding {
+		result[i] = v / norm
+	}
+	return result, nil
+}
+
+// Truncate keeps the first Dimensions dimensions of embeddings, as done with
+// models trained with Matryoshka representation learning. It is usually
+// followed by Normalize.
+type Truncate struct {
+	// Dimensions is the number of dimensions kept.
+	Dimensions int `json:"dimensions"`
+}
+
+// Type returns "truncate".
+func (*Truncate) Type() string { return "truncate" }
+
+// Apply returns the first Dimensions dimensions of the embedding.
+func (t *Truncate) Apply(embedding []float64) ([]float64, error) {
+	if t.Dimensions <= 0 || t.Dimensions > len(embedding) {
+		return nil, fmt.Errorf(
+			"error truncating embedding of %d dimensions to %d dimensions",
+			len(embedding),
+			t.Dimensions,
+		)
+	}
+	result := make([]float64, t.Dimensions)
+	copy(result, embedding)
+	return result, nil
+}
+
+// Center subtracts the mean of the fitted embeddings, which removes the
+// common direction shared by all embeddings of a model.
+type Center struct {
+	// Mean is the fitted mean embedding.
+	Mean []float64 `json:"mean"`
+}
+
+// Type returns "center".
+func (*Center) Type() string { return "center" }
+
+// Fit sets Mean to the mean of the given embeddings.
+func (c *Center) Fit(embeddings [][]float64) error {
+	mean, err := meanOf(embeddings)
+	if err != nil {
+		return err
+	}
+	c.Mean = mean
+	return nil
+}
+
+// Fitted reports whether the mean has been fitted.
+func (c *Center) Fitted() bool { return c.Mean != nil }
+
+// Apply returns the embedding minus the fitted mean.
+func (c *Center) Apply(embedding []float64) ([]float64, error) {
+	if c.Mean == nil {
+		return nil, ErrNotFitted
+	}
+	if len(embedding) != len(c.Mean) {
+		return nil, dimensionError(len(embedding), len(c.Mean))
+	}
+	result := make([]float64, len(embedding))
+	for i, v := range embedding {
+		result[i] = v - c.Mean[i]
+	}
+	return result, nil
+}
+
+// PCA projects embeddings onto the principal components of the fitted
+// embeddings.
+type PCA struct {
+	// Components is the number of principal components kept. It is capped
+	// by the rank of the fitted embeddings; zero keeps every component.
+	Components int `json:"components"`
+	// Mean is the fitted mean embedding.
+	Mean []float64 `json:"mean,omitempty"`
+	// Basis holds the fitted principal components, one per row, by
+	// decreasing variance.
+	Basis [][]float64 `json:"basis,omitempty"`
+}
+
+// Type returns "pca".
+func (*PCA) Type() string { return "pca" }
+
+// Fit fits the mean and principal components of the given embeddings.
+func (p *PCA) Fit(embeddings [][]float64) error {
+	mean, basis, _, err := principalComponents(embeddings, p.Components)
+	if err != nil {
+		return err
+	}
+	p.Mean, p.Basis = mean, basis
+	return nil
+}
+
+// Fitted reports whether the principal components have been fitted.
+func (p *PCA) Fitted() bool { return p.Basis != nil }
+
+// Apply returns the coordinates of the centered embedding in the basis of
+// principal components.
+func (p *PCA) Apply(embedding []float64) ([]float64, error) {
+	if p.Basis == nil {
+		return nil, ErrNotFitted
+	}
+	return project(embedding, p.Mean, p.Basis, nil)
+}
+
+// Whiten projects embeddings onto the principal components of the fitted
+// embeddings and scales each component to unit variance, which decorrelates
+// the dimensions and evens out their contributions to similarity scores.
+type Whiten struct {
+	// Components is the number of principal components kept. It is capped
+	// by the rank of the fitted embeddings; zero keeps every component.
+	Components int `json:"components"`
+	// Epsilon is added to each variance before scaling to avoid dividing by
+	// tiny variances.
+	Epsilon float64 `json:"epsilon"`
+	// Mean is the fitted mean embedding.
+	Mean []float64 `json:"mean,omitempty"`
+	// Basis holds the fitted principal components, one per row, by
+	// decreasing variance.
+	Basis [][]float64 `json:"basis,omitempty"`
+	// Scales holds the fitted inverse standard deviation of each component.
+	Scales []float64 `json:"scales,omitempty"`
+}
+
+// Type returns "whiten".
+func (*Whiten) Type() string { return "whiten" }
+
+// Fit fits the mean, principal components and scales of the given
+// embeddings.
+func (w *Whiten) Fit(embeddings [][]float64) error {
+	mean, basis, variances, err := principalComponents(embeddings, w.Components)
+	if err != nil {
+		return err
+	}
+	scales := make([]float64, len(variances))
+	for i, v := range variances {
+		scales[i] = 1 / math.Sqrt(v+w.Epsilon)
+	}
+	w.Mean, w.Basis, w.Scales = mean, basis, scales
+	return nil
+}
+
+// Fitted reports whether the whitening transform has been fitted.
+func (w *Whiten) Fitted() bool { return w.Basis != nil }
+
+// Apply returns the whitened embedding.
+func (w *Whiten) Apply(embedding []float64) ([]float64, error) {
+	if w.Basis == nil {
+		return nil, ErrNotFitted
+	}
+	return project(embedding, w.Mean, w.Basis, w.Scales)
+}
+
+// Int8 quantizes each dimension of embeddings to one of the 256 levels of a
+// signed byte, returned as float64 values in [-128, 127].
+//
+// The range of each dimension is fitted on the embeddings, or set for every
+// dimension by Range if the step is not fitted; values outside the range are
+// clamped.
+type Int8 struct {
+	// Range quantizes every dimension over [-Range, Range] if the step has
+	// not been fitted.
+	Range float64 `json:"range,omitempty"`
+	// Min holds the fitted minimum of each dimension.
+	Min []float64 `json:"min,omitempty"`
+	// Max holds the fitted maximum of each dimension.
+	Max []float64 `json:"max,omitempty"`
+}
+
+// Type returns "int8".
+func (*Int8) Type() string { return "int8" }
+
+// Fit sets the range of each dimension to its range over the given
+// embeddings.
+func (q *Int8) Fit(embeddings [][]float64) error {
+	if len(embeddings) == 0 {
+		return fmt.Errorf("error fitting int8 quantization: no embeddings")
+	}
+	n := len(embeddings[0])
+	q.Min = append([]float64(nil), embeddings[0]...)
+	q.Max = append([]float64(nil), embeddings[0]...)
+	for _, embedding := range embeddings[1:] {
+		if len(embedding) != n {
+			return dimensionError(len(embedding), n)
+		}
+		for i, v := range embedding {
+			q.Min[i] = min(q.Min[i], v)
+			q.Max[i] = max(q.Max[i], v)
+		}
+	}
+	return nil
+}
+
+// Fitted reports whether the quantization ranges are known, either fitted
+// or set by Range.
+func (q *Int8) Fitted() bool { return q.Min != nil || q.Range > 0 }
+
+// Apply returns the quantized embedding.
+func (q *Int8) Apply(embedding []float64) ([]float64, error) {
+	if q.Min != nil && len(embedding) != len(q.Min) {
+		return nil, dimensionError(len(embedding), len(q.Min))
+	}
+	if q.Min == nil && q.Range <= 0 {
+		return nil, ErrNotFitted
+	}
+	result := make([]float64, len(embedding))
+	for i, v := range embedding {
+		lo, hi := -q.Range, q.Range
+		if q.Min != nil {
+			lo, hi = q.Min[i], q.Max[i]
+		}
+		if hi <= lo {
+			continue
+		}
+		level := math.Round((v-lo)/(hi-lo)*255) - 128
+		result[i] = min(max(level, -128), 127)
+	}
+	return result, nil
+}
+
+// Binary quantizes each dimension of embeddings to its sign, +1 for
+// positive values and -1 otherwise.
+type Binary struct{}
+
+// Type returns "binary".
+func (*Binary) Type() string { return "binary" }
+
+// Apply returns the signs of the embedding.
+func (*Binary) Apply(embedding []float64) ([]float64, error) {
+	result := make([]float64, len(embedding))
+	for i, v := range embedding {
+		if v > 0 {
+			result[i] = 1
+		} else {
+			result[i] = -1
+		}
+	}
+	return result, nil
+}
+
+// meanOf returns the mean of the given embeddings.
+func meanOf(embeddings [][]float64) ([]float64, error) {
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("error fitting step: no embeddings")
+	}
+	mean := make([]float64, len(embeddings[0]))
+	for _, embedding := range embeddings {
+		if len(embedding) != len(mean) {
+			return nil, dimensionError(len(embedding), len(mean))
+		}
+		for i, v := range embedding {
+			mean[i] += v
+		}
+	}
+	for i := range mean {
+		mean[i] /= float64(len(embeddings))
+	}
+	return mean, nil
+}
+
+// principalComponents returns the mean, the principal components by
+// decreasing variance and their variances of the given embeddings.
+//
+// The components are the right singular vectors of the centered embeddings;
+// components without variance are dropped, as are those beyond the given
+// number of components if it is positive.
+func principalComponents(
+	embeddings [][]float64,
+	components int,
+) ([]float64, [][]float64, []float64, error) {
+	mean, err := meanOf(embeddings)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	n, d := len(embeddings), len(mean)
+	if n < 2 {
+		return nil, nil, nil, fmt.Errorf("error fitting step: need at least 2 embeddings, got %d", n)
+	}
+	centered := mat.NewDense(n, d, nil)
+	for i, embedding := range embeddings {
+		for j, v := range embedding {
+			centered.Set(i, j, v-mean[j])
+		}
+	}
+	var svd mat.SVD
+	if !svd.Factorize(centered, mat.SVDThin) {
+		return nil, nil, nil, fmt.Errorf("error fitting step: singular value decomposition failed")
+	}
+	values := svd.Values(nil)
+	var v mat.Dense
+	svd.VTo(&v)
+	var basis [][]float64
+	var variances []float64
+	tolerance := 1e-12 * max(values[0], 1)
+	for k, sigma := range values {
+		if sigma <= tolerance || (components > 0 && k >= components) {
+			break
+		}
+		basis = append(basis, mat.Col(nil, k, &v))
+		variances = append(variances, sigma*sigma/float64(n-1))
+	}
+	if basis == nil {
+		return nil, nil, nil, fmt.Errorf("error fitting step: embeddings have no variance")
+	}
+	return mean, basis, variances, nil
+}
+
+// project returns the coordinates of the embedding minus the mean in the
+// given basis, multiplied by the given scales if they are not nil.
+func project(embedding, mean []float64, basis [][]float64, scales []float64) ([]float64, error) {
+	if len(embedding) != len(mean) {
+		return nil, dimensionError(len(embedding), len(mean))
+	}
+	result := make([]float64, len(basis))
+	for k, component := range basis {
+		var sum float64
+		for i, v := range embedding {
+			sum += (v - mean[i]) * component[i]
+		}
+		if scales != nil {
+			sum *= scales[k]
+		}
+		result[k] = sum
+	}
+	return result, nil
+}
+
+// dimensionError returns the error of an embedding of got dimensions given to
+// a step expecting want dimensions.
+func dimensionError(got, want int) error {
+	return fmt.Errorf("embedding has %d dimensions, want %d", got, want)
+}
@@ -0,0 +1,7 @@
+// Package static provides an encoder that pools pretrained static word
+// vectors, such as GloVe, fastText or word2vec vectors, into sentence
+// embeddings.
+//
+// The vectors are read from local files, so the encoder works without a
+// network connection or a GPU while still capturing some semantics.
+package static
@@ -0,0 +1,226 @@
+package static
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/conneroisu/semanticrouter-go"
+)
+
+// Pooling is how the word vectors of an utterance are combined.
+type Pooling int
+
+const (
+	// PoolingMean averages the word vectors.
+	PoolingMean Pooling = iota
+	// PoolingSIF averages the word vectors weighted by a / (a + p(w)), where
+	// p(w) is the estimated probability of the word w, as in "A Simple but
+	// Tough-to-Beat Baseline for Sentence Embeddings" (Arora et al., 2017).
+	PoolingSIF
+)
+
+// DefaultSIFParameter is the default smoothing parameter a of SIF weighting.
+const DefaultSIFParameter = 1e-3
+
+// Encoder encodes utterances by pooling the static vectors of their words.
+//
+// Utterances are split into words at every rune that is neither a letter nor
+// a number. Each word is looked up as is and then lowercased; words without
+// a vector are skipped, and an utterance without any known word is encoded
+// as the zero vector.
+//
+// The SIF paper also removes the projection on the first principal
+// component of the sentence embeddings; that step is left to a
+// post-processing stage fitted on the route utterances.
+type Encoder struct {
+	// Vectors is the table of word vectors.
+	Vectors *Vectors
+	// Pooling is how the word vectors are combined.
+	Pooling Pooling
+	// SIFParameter is the smoothing parameter a of SIF weighting.
+	SIFParameter float64
+	// Frequencies are the relative frequencies of words used by SIF
+	// weighting. If a word has no frequency, it is estimated from the rank
+	// of the word in Vectors assuming Zipf's law.
+	Frequencies map[string]float64
+	// Normalize scales the embeddings to unit length.
+	Normalize bool
+}
+
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithPooling sets how the word vectors are combined.
+func WithPooling(pooling Pooling) Option {
+	return func(e *Encoder) {
+		e.Pooling = pooling
+	}
+}
+
+// WithSIF enables SIF weighting with the given smoothing parameter.
+func WithSIF(a float64) Option {
+	return func(e *Encoder) {
+		e.Pooling = PoolingSIF
+		e.SIFParameter = a
+	}
+}
+
+// WithFrequencies sets the relative word frequencies used by SIF weighting.
+func WithFrequencies(frequencies map[string]float64) Option {
+	return func(e *Encoder) {
+		e.Frequencies = frequencies
+	}
+}
+
+// WithNormalize sets whether the embeddings are scaled to unit length.
+func WithNormalize(normalize bool) Option {
+	return func(e *Encoder) {
+		e.Normalize = normalize
+	}
+}
+
+// NewEncoder creates a new Encoder using the given word vectors.
+//
+// By default it averages the word vectors and normalizes the result.
+func NewEncoder(vectors *Vectors, opts ...Option) *Encoder {
+	e := &Encoder{
+		Vectors:      vectors,
+		Pooling:      PoolingMean,
+		SIFParameter: DefaultSIFParameter,
+		Normalize:    true,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Encode encodes a query string by pooling the vectors of its words.
+func (e *Encoder) Encode(
+	_ context.Context,
+	query string,
+) ([]float64, error) {
+	if e.Vectors == nil {
+		return nil, fmt.Errorf("static vectors are nil")
+	}
+	embedding := make([]float64, e.Vectors.Dimensions())
+	var total float64
+	for _, word := range words(query) {
+		rank, ok := e.lookup(word)
+		if !ok {
+			continue
+		}
+		weight := e.weight(e.Vectors.words[rank], rank)
+		for i, x := range e.Vectors.vector(rank) {
+			embedding[i] += weight * float64(x)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return embedding, nil
+	}
+	for i := range embedding {
+		embedding[i] /= total
+	}
+	if e.Normalize {
+		normalize(embedding)
+	}
+	return embedding, nil
+}
+
+// EncodeBatch encodes the given utterances by pooling the vectors of their
+// words.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	result := make([][]float64, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.Encode(ctx, utterance)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = embedding
+	}
+	return result, nil
+}
+
+// EncodeFloat32 encodes the given utterances into float32 embeddings, the
+// precision of the word vectors.
+//
+// The word vectors are still pooled in float64 before being rounded.
+func (e *Encoder) EncodeFloat32(
+	ctx context.Context,
+	utterances []string,
+) ([][]float32, error) {
+	result := make([][]float32, len(utterances))
+	for i, utterance := range utterances {
+		embedding, err := e.Encode(ctx, utterance)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = semanticrouter.Convert[float32](embedding)
+	}
+	return result, nil
+}
+
+// lookup returns the rank of the given word, trying its lowercase form if
+// the word itself has no vector.
+func (e *Encoder) lookup(word string) (int, bool) {
+	rank, ok := e.Vectors.Rank(word)
+	if ok {
+		return rank, true
+	}
+	return e.Vectors.Rank(strings.ToLower(word))
+}
+
+// weight returns the pooling weight of the given word at the given rank.
+func (e *Encoder) weight(word string, rank int) float64 {
+	if e.Pooling != PoolingSIF {
+		return 1
+	}
+	a := e.SIFParameter
+	if a <= 0 {
+		a = DefaultSIFParameter
+	}
+	p, ok := e.Frequencies[word]
+	if !ok {
+		p = zipf(rank, e.Vectors.Len())
+	}
+	return a / (a + p)
+}
+
+// zipf estimates the relative frequency of the word at the given zero-based
+// rank of n words assuming Zipf's law: p(r) = 1 / (r * H(n)), where r is the
+// one-based rank and H(n) is the n-th harmonic number.
+func zipf(rank, n int) float64 {
+	harmonic := math.Log(float64(n)) + 0.5772156649
+	return 1 / (float64(rank+1) * harmonic)
+}
+
+// words splits the given text into words.
+func words(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// normalize scales the given vector to unit length in place.
+//
+// The zero vector is left unchanged.
+func normalize(v []float64) {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for i := range v {
+		v[i] /= norm
+	}
+}
@@ -0,0 +1,144 @@
+package static_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/static"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder        = (*static.Encoder)(nil)
+	_ semanticrouter.BatchEncoder   = (*static.Encoder)(nil)
+	_ semanticrouter.Float32Encoder = (*static.Encoder)(nil)
+)
+
+// TestReadText tests reading GloVe and fastText text files.
+func TestReadText(t *testing.T) {
+	a := assert.New(t)
+	glove, err := static.LoadFile(filepath.Join("testdata", "glove.txt"), 0)
+	require.NoError(t, err)
+	a.Equal(15, glove.Len())
+	a.Equal(4, glove.Dimensions())
+	vector, ok := glove.Vector("dog")
+	a.True(ok)
+	a.Equal([]float32{0.9, 0.1, 0, 0}, vector)
+
+	fasttext, err := static.LoadFile(filepath.Join("testdata", "fasttext.vec"), 2)
+	require.NoError(t, err)
+	a.Equal(2, fasttext.Len())
+	a.Equal(4, fasttext.Dimensions())
+	rank, ok := fasttext.Rank("what")
+	a.True(ok)
+	a.Equal(1, rank)
+	_, ok = fasttext.Vector("is")
+	a.False(ok)
+
+	_, err = static.ReadText(strings.NewReader("a 1 2\nb 1\n"), 0)
+	a.ErrorContains(err, "line 2")
+}
+
+// TestReadWord2Vec tests reading binary word2vec files.
+func TestReadWord2Vec(t *testing.T) {
+	a := assert.New(t)
+	var buf bytes.Buffer
+	buf.WriteString("2 3\n")
+	for _, entry := range []struct {
+		word   string
+		vector []float32
+	}{
+		{"dog", []float32{1, 0.5, -1}},
+		{"cat", []float32{0.25, 2, 0}},
+	} {
+		buf.WriteString(entry.word + " ")
+		for _, x := range entry.vector {
+			_ = binary.Write(&buf, binary.LittleEndian, math.Float32bits(x))
+		}
+		buf.WriteString("\n")
+	}
+	vectors, err := static.ReadWord2Vec(bytes.NewReader(buf.Bytes()), 0)
+	require.NoError(t, err)
+	a.Equal(2, vectors.Len())
+	vector, ok := vectors.Vector("cat")
+	a.True(ok)
+	a.Equal([]float32{0.25, 2, 0}, vector)
+
+	_, err = static.ReadWord2Vec(bytes.NewReader(buf.Bytes()[:20]), 0)
+	a.Error(err)
+}
+
+// TestEncoder tests mean and SIF pooling.
+func TestEncoder(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	vectors, err := static.NewVectors(
+		[]string{"the", "dog"},
+		[][]float32{{1, 0}, {0, 1}},
+	)
+	require.NoError(t, err)
+
+	mean, err := static.NewEncoder(vectors, static.WithNormalize(false)).
+		Encode(ctx, "The dog, unknown!")
+	require.NoError(t, err)
+	a.Equal([]float64{0.5, 0.5}, mean)
+
+	sif, err := static.NewEncoder(
+		vectors,
+		static.WithSIF(0.1),
+		static.WithFrequencies(map[string]float64{"the": 0.4, "dog": 0.1}),
+		static.WithNormalize(false),
+	).Encode(ctx, "the dog")
+	require.NoError(t, err)
+	// The weights are 0.1/0.5 = 0.2 for "the" and 0.1/0.2 = 0.5 for "dog".
+	a.InDelta(0.2/0.7, sif[0], 1e-9)
+	a.InDelta(0.5/0.7, sif[1], 1e-9)
+
+	empty, err := static.NewEncoder(vectors).Encode(ctx, "cat")
+	require.NoError(t, err)
+	a.Equal([]float64{0, 0}, empty)
+}
+
+// TestRouter tests routing with GloVe vectors.
+func TestRouter(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	vectors, err := static.LoadFile(filepath.Join("testdata", "glove.txt"), 0)
+	require.NoError(t, err)
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{
+			{
+				Name: "noteworthy",
+				Utterances: []semanticrouter.Utterance{
+					{Utterance: "what is the best way to treat a dog with a cold?"},
+					{Utterance: "my cat has been limping, what should I do?"},
+				},
+			},
+			{
+				Name: "chitchat",
+				Utterances: []semanticrouter.Utterance{
+					{Utterance: "what is your favorite color?"},
+					{Utterance: "what is your favorite animal?"},
+				},
+			},
+		},
+		static.NewEncoder(vectors, static.WithSIF(static.DefaultSIFParameter)),
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	route, _, err := router.Match(ctx, "my dog is sick")
+	a.NoError(err)
+	a.Equal("noteworthy", route.Name)
+	route, _, err = router.Match(ctx, "blue is my favorite")
+	a.NoError(err)
+	a.Equal("chitchat", route.Name)
+}
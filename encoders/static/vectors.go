@@ -0,0 +1,246 @@
+package static
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Vectors is a table of static word vectors.
+//
+// The words keep the order of the file they were read from, which for
+// GloVe, fastText and word2vec files is by descending frequency. The rank of
+// a word is used to estimate its frequency for SIF weighting.
+type Vectors struct {
+	dimensions int
+	words      []string
+	index      map[string]int
+	data       []float32
+}
+
+// NewVectors creates a table of word vectors from the given words and their
+// vectors, which must all have the same number of dimensions.
+//
+// The words should be ordered by descending frequency.
+func NewVectors(words []string, vectors [][]float32) (*Vectors, error) {
+	if len(words) != len(vectors) {
+		return nil, fmt.Errorf(
+			"error creating vectors: got %d vectors for %d words",
+			len(vectors),
+			len(words),
+		)
+	}
+	v := &Vectors{index: make(map[string]int, len(words))}
+	if len(vectors) > 0 {
+		v.dimensions = len(vectors[0])
+	}
+	for i, word := range words {
+		err := v.add(word, vectors[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// Dimensions returns the number of dimensions of the vectors.
+func (v *Vectors) Dimensions() int {
+	return v.dimensions
+}
+
+// Len returns the number of words in the table.
+func (v *Vectors) Len() int {
+	return len(v.words)
+}
+
+// Vector returns the vector of the given word and whether the word is in the
+// table. The returned slice must not be modified.
+func (v *Vectors) Vector(word string) ([]float32, bool) {
+	i, ok := v.index[word]
+	if !ok {
+		return nil, false
+	}
+	return v.vector(i), true
+}
+
+// Rank returns the zero-based position of the given word in the table and
+// whether the word is in the table.
+func (v *Vectors) Rank(word string) (int, bool) {
+	i, ok := v.index[word]
+	return i, ok
+}
+
+// vector returns the vector of the word at the given position.
+func (v *Vectors) vector(i int) []float32 {
+	return v.data[i*v.dimensions : (i+1)*v.dimensions : (i+1)*v.dimensions]
+}
+
+// add appends a word and its vector to the table. Words that are already in
+// the table keep their first vector.
+func (v *Vectors) add(word string, vector []float32) error {
+	if len(vector) != v.dimensions {
+		return fmt.Errorf(
+			"error adding vector of %q: got %d dimensions, want %d",
+			word,
+			len(vector),
+			v.dimensions,
+		)
+	}
+	if _, ok := v.index[word]; ok {
+		return nil
+	}
+	v.index[word] = len(v.words)
+	v.words = append(v.words, word)
+	v.data = append(v.data, vector...)
+	return nil
+}
+
+// ReadText reads word vectors in the text format used by GloVe, fastText
+// .vec files and word2vec text files.
+//
+// Each line holds a word followed by its vector components separated by
+// spaces. An optional first line holding the number of words and dimensions,
+// as written by fastText and word2vec, is skipped. At most limit words are
+// read; a limit of zero or less reads every word.
+func ReadText(r io.Reader, limit int) (*Vectors, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	v := &Vectors{index: make(map[string]int)}
+	line := 0
+	for scanner.Scan() {
+		line++
+		if limit > 0 && v.Len() >= limit {
+			break
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if line == 1 && len(fields) == 2 {
+			dimensions, err := strconv.Atoi(fields[1])
+			if _, countErr := strconv.Atoi(fields[0]); countErr == nil && err == nil {
+				v.dimensions = dimensions
+				continue
+			}
+		}
+		if v.dimensions == 0 {
+			v.dimensions = len(fields) - 1
+		}
+		if len(fields) <= v.dimensions {
+			return nil, fmt.Errorf(
+				"error reading vectors: line %d: got %d components, want %d",
+				line,
+				len(fields)-1,
+				v.dimensions,
+			)
+		}
+		// Some GloVe files contain words with spaces, so the word is
+		// everything before the last dimensions fields.
+		split := len(fields) - v.dimensions
+		vector := make([]float32, v.dimensions)
+		for i, field := range fields[split:] {
+			f, err := strconv.ParseFloat(field, 32)
+			if err != nil {
+				return nil, fmt.Errorf("error reading vectors: line %d: %w", line, err)
+			}
+			vector[i] = float32(f)
+		}
+		err := v.add(strings.Join(fields[:split], " "), vector)
+		if err != nil {
+			return nil, fmt.Errorf("error reading vectors: line %d: %w", line, err)
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error reading vectors: %w", err)
+	}
+	return v, nil
+}
+
+// ReadWord2Vec reads word vectors in the binary word2vec format.
+//
+// The file starts with a line holding the number of words and dimensions,
+// followed by each word, a space, and its components as little-endian
+// float32 values. At most limit words are read; a limit of zero or less
+// reads every word.
+func ReadWord2Vec(r io.Reader, limit int) (*Vectors, error) {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("error reading word2vec header: %w", err)
+	}
+	var count, dimensions int
+	_, err = fmt.Sscan(header, &count, &dimensions)
+	if err != nil {
+		return nil, fmt.Errorf("error reading word2vec header: %w", err)
+	}
+	if count < 0 || dimensions <= 0 {
+		return nil, fmt.Errorf(
+			"error reading word2vec header: invalid shape %d x %d",
+			count,
+			dimensions,
+		)
+	}
+	if limit > 0 {
+		count = min(count, limit)
+	}
+	v := &Vectors{
+		dimensions: dimensions,
+		index:      make(map[string]int, count),
+	}
+	raw := make([]byte, 4*dimensions)
+	for n := 0; n < count; n++ {
+		word, err := br.ReadString(' ')
+		if err != nil {
+			return nil, fmt.Errorf("error reading word %d: %w", n, err)
+		}
+		word = strings.TrimLeft(strings.TrimSuffix(word, " "), "\n")
+		_, err = io.ReadFull(br, raw)
+		if err != nil {
+			return nil, fmt.Errorf("error reading vector of %q: %w", word, err)
+		}
+		vector := make([]float32, dimensions)
+		for i := range vector {
+			vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+		err = v.add(word, vector)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// LoadFile loads word vectors from the file at the given path.
+//
+// Files ending in .bin are read with ReadWord2Vec and all other files with
+// ReadText. Files ending in .gz are decompressed first, so for example
+// vectors.bin.gz is a gzipped binary word2vec file.
+func LoadFile(path string, limit int) (*Vectors, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening vectors: %w", err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	name := path
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("error opening vectors: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	if strings.HasSuffix(name, ".bin") {
+		return ReadWord2Vec(r, limit)
+	}
+	return ReadText(r, limit)
+}
@@ -1,2 +1,16 @@
 // Package voyageai provides encoders for VoyageAI language models.
+//
+// VoyageAI models embed retrieval queries and documents with different
+// prompts, which the encoder exposes through EncodeQuery and EncodeDocuments.
+//
+// NewEncoder takes the API key instead of a *voyageai.Client from
+// github.com/conneroisu/go-voyageai, which cannot send the input type of the
+// texts nor cancel its requests. Callers importing that package as govoyageai
+// replace
+//
+//	voyageai.NewEncoder(govoyageai.NewClient(apiKey), model)
+//
+// with
+//
+//	voyageai.NewEncoder(apiKey, model)
 package voyageai
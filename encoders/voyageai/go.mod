@@ -2,4 +2,10 @@ module github.com/conneroisu/semanticrouter-go/encoders/voyageai
 
 go 1.23.0
 
-require github.com/conneroisu/go-voyageai v0.0.0-20240712192129-77bcd696824e
+require github.com/stretchr/testify v1.9.0
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -1,21 +1,130 @@
 package voyageai
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
 
-	"github.com/conneroisu/go-voyageai"
+const (
+	// DefaultBaseURL is the base URL of the VoyageAI API.
+	DefaultBaseURL = "https://api.voyageai.com"
+	// DefaultBatchSize is the default number of inputs sent per request.
+	DefaultBatchSize = 128
+)
+
+// InputType is the kind of text being embedded.
+type InputType string
+
+const (
+	// InputTypeNone embeds texts without a retrieval prompt.
+	InputTypeNone InputType = ""
+	// InputTypeQuery embeds texts as retrieval queries.
+	InputTypeQuery InputType = "query"
+	// InputTypeDocument embeds texts as retrieval documents.
+	InputTypeDocument InputType = "document"
 )
 
 // Encoder is an encoder using VoyageAI embedding models.
+//
+// Encode and EncodeBatch embed texts with the configured InputType, while
+// EncodeQuery and EncodeDocuments always embed texts as queries and documents
+// respectively.
 type Encoder struct {
-	Client *voyageai.Client
-	Model  string
+	// APIKey is the VoyageAI API key.
+	APIKey string
+	// Model is the VoyageAI embedding model to use.
+	Model string
+	// InputType is the input type used by Encode and EncodeBatch.
+	InputType InputType
+	// Truncation controls whether inputs longer than the context length are
+	// truncated. It is left to the API default if nil.
+	Truncation *bool
+	// BatchSize is the maximum number of inputs sent per request.
+	BatchSize int
+	// BaseURL is the base URL of the VoyageAI API.
+	BaseURL string
+	// HTTPClient is the client used to send requests.
+	HTTPClient *http.Client
 }
 
-// NewEncoder creates a new Encoder.
-func NewEncoder(client *voyageai.Client, model string) *Encoder {
-	return &Encoder{Client: client, Model: model}
+// Option is a function that configures an Encoder.
+type Option func(*Encoder)
+
+// WithInputType sets the input type used by Encode and EncodeBatch.
+func WithInputType(inputType InputType) Option {
+	return func(e *Encoder) {
+		e.InputType = inputType
+	}
+}
+
+// WithTruncation sets whether inputs that are too long are truncated.
+func WithTruncation(truncation bool) Option {
+	return func(e *Encoder) {
+		e.Truncation = &truncation
+	}
+}
+
+// WithBatchSize sets the maximum number of inputs sent per request.
+func WithBatchSize(size int) Option {
+	return func(e *Encoder) {
+		e.BatchSize = size
+	}
+}
+
+// WithBaseURL sets the base URL of the VoyageAI API.
+func WithBaseURL(baseURL string) Option {
+	return func(e *Encoder) {
+		e.BaseURL = baseURL
+	}
+}
+
+// WithHTTPClient sets the client used to send requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Encoder) {
+		e.HTTPClient = client
+	}
+}
+
+// NewEncoder creates a new Encoder sending requests with the given API key.
+func NewEncoder(apiKey, model string, opts ...Option) *Encoder {
+	e := &Encoder{
+		APIKey:     apiKey,
+		Model:      model,
+		BatchSize:  DefaultBatchSize,
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// embeddingsRequest is the body of a request to the embeddings endpoint.
+type embeddingsRequest struct {
+	Input      []string  `json:"input"`
+	Model      string    `json:"model"`
+	InputType  InputType `json:"input_type,omitempty"`
+	Truncation *bool     `json:"truncation,omitempty"`
+}
+
+// embeddingsResponse is the body of a response from the embeddings endpoint.
+type embeddingsResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Model string `json:"model"`
+}
+
+// errorResponse is the body of an error response from the VoyageAI API.
+type errorResponse struct {
+	Detail string `json:"detail"`
 }
 
 // Encode encodes a query string into a VoyageAI embedding.
@@ -23,17 +132,161 @@ func (e *Encoder) Encode(
 	ctx context.Context,
 	query string,
 ) (result []float64, err error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-		resp, err := e.Client.Embeddings(voyageai.EmbeddingsRequest{
-			Model: e.Model,
-			Input: []string{query},
-		})
+	embeddings, err := e.embed(ctx, []string{query}, e.InputType)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EncodeBatch encodes the given utterances into VoyageAI embeddings.
+func (e *Encoder) EncodeBatch(
+	ctx context.Context,
+	utterances []string,
+) ([][]float64, error) {
+	return e.embedBatch(ctx, utterances, e.InputType)
+}
+
+// EncodeQuery encodes the given query with the query input type.
+func (e *Encoder) EncodeQuery(
+	ctx context.Context,
+	query string,
+) ([]float64, error) {
+	embeddings, err := e.embed(ctx, []string{query}, InputTypeQuery)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EncodeDocuments encodes the given documents with the document input type.
+func (e *Encoder) EncodeDocuments(
+	ctx context.Context,
+	documents []string,
+) ([][]float64, error) {
+	return e.embedBatch(ctx, documents, InputTypeDocument)
+}
+
+// embedBatch embeds the given texts in requests of at most BatchSize inputs.
+func (e *Encoder) embedBatch(
+	ctx context.Context,
+	texts []string,
+	inputType InputType,
+) ([][]float64, error) {
+	size := e.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	result := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		end := min(start+size, len(texts))
+		embeddings, err := e.embed(ctx, texts[start:end], inputType)
 		if err != nil {
-			return nil, fmt.Errorf("error creating query embedding: %w", err)
+			return nil, err
+		}
+		result = append(result, embeddings...)
+	}
+	return result, nil
+}
+
+// embed sends a single request to the embeddings endpoint.
+func (e *Encoder) embed(
+	ctx context.Context,
+	inputs []string,
+	inputType InputType,
+) ([][]float64, error) {
+	body, err := json.Marshal(embeddingsRequest{
+		Input:      inputs,
+		Model:      e.Model,
+		InputType:  inputType,
+		Truncation: e.Truncation,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling embeddings request: %w", err)
+	}
+	var resp embeddingsResponse
+	err = e.do(ctx, "/v1/embeddings", body, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf(
+			"error creating query embedding: got %d embeddings for %d inputs",
+			len(resp.Data),
+			len(inputs),
+		)
+	}
+	embeddings := make([][]float64, len(inputs))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(inputs) {
+			return nil, fmt.Errorf(
+				"error creating query embedding: embedding index %d out of range",
+				d.Index,
+			)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	for i, embedding := range embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf(
+				"error creating query embedding: no embedding for input %d",
+				i,
+			)
+		}
+	}
+	return embeddings, nil
+}
+
+// do sends a POST request with the given body to the given path and decodes
+// the JSON response into out.
+func (e *Encoder) do(
+	ctx context.Context,
+	path string,
+	body []byte,
+	out any,
+) error {
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		strings.TrimSuffix(baseURL, "/")+path,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error creating query embedding: %w", err)
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var apiErr errorResponse
+		if json.Unmarshal(raw, &apiErr) == nil && apiErr.Detail != "" {
+			return fmt.Errorf(
+				"error creating query embedding: %s: %s",
+				res.Status,
+				apiErr.Detail,
+			)
 		}
-		return resp.Data[0].Embedding, nil
+		return fmt.Errorf("error creating query embedding: %s", res.Status)
+	}
+	err = json.Unmarshal(raw, out)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling response: %w", err)
 	}
+	return nil
 }
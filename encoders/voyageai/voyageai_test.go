@@ -0,0 +1,131 @@
+package voyageai_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/voyageai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Encoder              = (*voyageai.Encoder)(nil)
+	_ semanticrouter.BatchEncoder         = (*voyageai.Encoder)(nil)
+	_ semanticrouter.QueryDocumentEncoder = (*voyageai.Encoder)(nil)
+)
+
+// request is the body of a request received by the fake VoyageAI API.
+type request struct {
+	Input      []string `json:"input"`
+	Model      string   `json:"model"`
+	InputType  *string  `json:"input_type"`
+	Truncation *bool    `json:"truncation"`
+}
+
+// newFakeServer returns a server that replays the given recorded response and
+// records the requests it receives.
+func newFakeServer(
+	t *testing.T,
+	status int,
+	fixture string,
+	requests *[]request,
+) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var req request
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		*requests = append(*requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestEncoder tests encoding a single utterance without an input type.
+func TestEncoder(t *testing.T) {
+	a := assert.New(t)
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embedding.json", &requests)
+	encoder := voyageai.NewEncoder(
+		"test-key",
+		"voyage-3",
+		voyageai.WithBaseURL(srv.URL),
+		voyageai.WithTruncation(true),
+	)
+	result, err := encoder.Encode(context.Background(), "how's the weather today?")
+	a.NoError(err)
+	a.Len(result, 6)
+	a.InDelta(0.024399, result[0], 1e-9)
+	a.Len(requests, 1)
+	a.Nil(requests[0].InputType)
+	a.Equal(true, *requests[0].Truncation)
+}
+
+// TestEncoderQueryDocument tests that queries and documents are embedded
+// with their input types.
+func TestEncoderQueryDocument(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embeddings.json", &requests)
+	encoder := voyageai.NewEncoder(
+		"test-key",
+		"voyage-3",
+		voyageai.WithBaseURL(srv.URL),
+		voyageai.WithHTTPClient(srv.Client()),
+	)
+	documents, err := encoder.EncodeDocuments(ctx, []string{
+		"how's the weather today?",
+		"lovely weather today",
+	})
+	a.NoError(err)
+	a.Len(documents, 2)
+	a.InDelta(-0.005436, documents[1][0], 1e-9)
+	_, err = encoder.EncodeQuery(ctx, "is it sunny?")
+	a.Error(err, "a response with two embeddings for one query is rejected")
+	a.Len(requests, 2)
+	a.Equal("document", *requests[0].InputType)
+	a.Equal("query", *requests[1].InputType)
+	a.Equal([]string{"is it sunny?"}, requests[1].Input)
+}
+
+// TestEncoderDuplicateIndex tests that a response leaving an input without
+// an embedding is rejected.
+func TestEncoderDuplicateIndex(t *testing.T) {
+	var requests []request
+	srv := newFakeServer(t, http.StatusOK, "embeddings_duplicate.json", &requests)
+	encoder := voyageai.NewEncoder(
+		"test-key",
+		"voyage-3",
+		voyageai.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.EncodeDocuments(context.Background(), []string{"a", "b"})
+	assert.ErrorContains(t, err, "no embedding for input 1")
+}
+
+// TestEncoderError tests that API errors are returned.
+func TestEncoderError(t *testing.T) {
+	var requests []request
+	srv := newFakeServer(t, http.StatusUnauthorized, "error_unauthorized.json", &requests)
+	encoder := voyageai.NewEncoder(
+		"test-key",
+		"voyage-3",
+		voyageai.WithBaseURL(srv.URL),
+	)
+	_, err := encoder.Encode(context.Background(), "how's the weather today?")
+	assert.ErrorContains(t, err, "Provided API key is invalid.")
+}
@@ -1,5 +1,10 @@
 package semanticrouter
 
+import (
+	"strconv"
+	"strings"
+)
+
 // ErrNoRouteFound is an error that is returned when no route is found.
 type ErrNoRouteFound struct {
 	Message   string
@@ -31,3 +36,94 @@ type ErrGetEmbedding struct {
 func (e ErrGetEmbedding) Error() string {
 	return e.Message
 }
+
+// ErrNoHandler is an error that is returned when a dispatched route has no
+// handler and the router has no fallback.
+type ErrNoHandler struct {
+	Message string
+	Route   string
+}
+
+// Error returns the error message.
+func (e ErrNoHandler) Error() string {
+	return e.Message
+}
+
+// ErrHandler is an error that is returned when the handler of a dispatched
+// route fails. It wraps the handler's error.
+type ErrHandler struct {
+	Message   string
+	Route     string // Route is the name of the matched route, empty if none matched.
+	Utterance string
+	Err       error
+}
+
+// Error returns the error message.
+func (e ErrHandler) Error() string {
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap returns the handler's error.
+func (e ErrHandler) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidArguments is an error that is returned when the arguments of the
+// function of a dynamic route cannot be extracted or are invalid. It wraps
+// the extraction or validation error.
+type ErrInvalidArguments struct {
+	Message string
+	Route   string
+	Err     error
+}
+
+// Error returns the error message.
+func (e ErrInvalidArguments) Error() string {
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap returns the extraction or validation error.
+func (e ErrInvalidArguments) Unwrap() error {
+	return e.Err
+}
+
+// ErrAmbiguousRoute is an error that is returned when routes with the same
+// priority score within the router's ambiguity margin of each other.
+type ErrAmbiguousRoute struct {
+	Message   string
+	Utterance string
+	Routes    []string // Routes are the names of the contending routes.
+}
+
+// Error returns the error message.
+func (e ErrAmbiguousRoute) Error() string {
+	return e.Message + " : utterance : " + e.Utterance +
+		" : routes : " + strings.Join(e.Routes, ", ")
+}
+
+// ErrConfig is an error that is returned when a configuration file cannot be
+// parsed or is invalid, at the given position of the file.
+type ErrConfig struct {
+	Message string
+	File    string // File is the path of the file, empty if not read from a file.
+	Line    int    // Line is the line of the error, 0 if unknown.
+	Column  int    // Column is the column of the error, 0 if unknown.
+}
+
+// Error returns the error message, prefixed by its position.
+func (e ErrConfig) Error() string {
+	position := e.File
+	if e.Line > 0 {
+		if position != "" {
+			position += ":"
+		}
+		position += strconv.Itoa(e.Line)
+		if e.Column > 0 {
+			position += ":" + strconv.Itoa(e.Column)
+		}
+	}
+	if position == "" {
+		return e.Message
+	}
+	return position + ": " + e.Message
+}
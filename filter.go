@@ -0,0 +1,73 @@
+package semanticrouter
+
+import (
+	"context"
+	"reflect"
+	"slices"
+)
+
+// Filter reports whether an utterance of a route is considered when
+// matching, see WithFilter. The utterance is nil when the route itself is
+// considered, by its rules, its centroid or a classifier.
+type Filter func(route *Route, utterance *Utterance) bool
+
+// filterKey is the context key of the filter of the matches.
+type filterKey struct{}
+
+// WithFilter returns a copy of the given context with which the router only
+// considers the routes and utterances accepted by the given filter, in
+// addition to the filter of the context if any.
+func WithFilter(ctx context.Context, filter Filter) context.Context {
+	if previous := filterFrom(ctx); previous != nil {
+		next := filter
+		filter = func(route *Route, utterance *Utterance) bool {
+			return previous(route, utterance) && next(route, utterance)
+		}
+	}
+	return context.WithValue(ctx, filterKey{}, filter)
+}
+
+// filterFrom returns the filter of the given context, or nil if it has none.
+func filterFrom(ctx context.Context) Filter {
+	filter, _ := ctx.Value(filterKey{}).(Filter)
+	return filter
+}
+
+// accepts reports whether the filter of the given context, if any, accepts
+// the given route and utterance.
+func accepts(ctx context.Context, route *Route, utterance *Utterance) bool {
+	filter := filterFrom(ctx)
+	return filter == nil || filter(route, utterance)
+}
+
+// HasTags returns a filter accepting the utterances for which each of the
+// given tags, such as "tenant=acme", is a tag of the route or of the
+// utterance.
+func HasTags(tags ...string) Filter {
+	return func(route *Route, utterance *Utterance) bool {
+		for _, tag := range tags {
+			if slices.Contains(route.Tags, tag) {
+				continue
+			}
+			if utterance == nil || !slices.Contains(utterance.Tags, tag) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MetadataEquals returns a filter accepting the utterances whose metadata,
+// or whose route's metadata, has the given value for the given key.
+func MetadataEquals(key string, value any) Filter {
+	return func(route *Route, utterance *Utterance) bool {
+		if v, ok := route.Metadata[key]; ok && reflect.DeepEqual(v, value) {
+			return true
+		}
+		if utterance == nil {
+			return false
+		}
+		v, ok := utterance.Metadata[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
@@ -0,0 +1,134 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filterRoutes are routes whose noteworthy route belongs to the acme tenant
+// and whose chitchat utterances belong to different tenants.
+var filterRoutes = []semanticrouter.Route{
+	{
+		Name:        "noteworthy",
+		Description: "questions about pets",
+		Metadata:    map[string]any{"team": "vets"},
+		Tags:        []string{"tenant=acme"},
+		Utterances: []semanticrouter.Utterance{
+			{
+				Utterance: "what is the best way to treat a dog with a cold?",
+				Metadata:  map[string]any{"tool": "treatments"},
+			},
+			{Utterance: "my cat has been limping, what should I do?"},
+		},
+	},
+	{
+		Name: "chitchat",
+		Utterances: []semanticrouter.Utterance{
+			{
+				Utterance: "what is your favorite color?",
+				Tags:      []string{"tenant=acme"},
+			},
+			{
+				Utterance: "what is your favorite animal?",
+				Tags:      []string{"tenant=globex"},
+			},
+		},
+	},
+}
+
+// TestMatchMetadata tests that match results hold the metadata of the
+// matched route and utterance.
+func TestMatchMetadata(t *testing.T) {
+	a := assert.New(t)
+	router := newTestRouter(t, filterRoutes)
+	res, err := router.Resolve(context.Background(), "my dog is sneezing")
+	require.NoError(t, err)
+	require.NotNil(t, res.Route)
+	a.Equal("questions about pets", res.Route.Description)
+	a.Equal("vets", res.Route.Metadata["team"])
+	require.NotNil(t, res.Matched)
+	a.Equal("what is the best way to treat a dog with a cold?", res.Matched.Utterance)
+	a.Equal("treatments", res.Matched.Metadata["tool"])
+}
+
+// TestFilterTags tests matching only the routes and utterances with given
+// tags.
+func TestFilterTags(t *testing.T) {
+	a := assert.New(t)
+	router := newTestRouter(t, filterRoutes)
+	ctx := semanticrouter.WithFilter(
+		context.Background(),
+		semanticrouter.HasTags("tenant=globex"),
+	)
+	res, err := router.Resolve(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	require.NotNil(t, res.Route)
+	a.Equal("chitchat", res.Route.Name)
+	a.Equal("what is your favorite animal?", res.Matched.Utterance)
+
+	ctx = semanticrouter.WithFilter(
+		context.Background(),
+		semanticrouter.HasTags("tenant=acme"),
+	)
+	res, err = router.Resolve(ctx, "what is your favorite animal?")
+	require.NoError(t, err)
+	require.NotNil(t, res.Route)
+	a.Equal("what is your favorite color?", res.Matched.Utterance)
+}
+
+// TestFilterMetadata tests combining filters on metadata.
+func TestFilterMetadata(t *testing.T) {
+	a := assert.New(t)
+	router := newTestRouter(t, filterRoutes)
+	ctx := semanticrouter.WithFilter(
+		context.Background(),
+		semanticrouter.MetadataEquals("team", "vets"),
+	)
+	res, err := router.Resolve(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	require.NotNil(t, res.Route)
+	a.Equal("noteworthy", res.Route.Name)
+
+	ctx = semanticrouter.WithFilter(ctx, semanticrouter.MetadataEquals("tool", "treatments"))
+	res, err = router.Resolve(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	require.NotNil(t, res.Matched)
+	a.Equal("what is the best way to treat a dog with a cold?", res.Matched.Utterance)
+
+	route, _, err := router.Match(ctx, "what is your favorite color?")
+	require.NoError(t, err)
+	a.Nil(route)
+}
+
+// TestFilterRules tests that the rules of the routes rejected by the filter
+// do not match.
+func TestFilterRules(t *testing.T) {
+	a := assert.New(t)
+	routes := []semanticrouter.Route{
+		{
+			Name:  "billing",
+			Tags:  []string{"tenant=acme"},
+			Rules: []semanticrouter.Rule{{Name: "refund", Keywords: []string{"refund"}}},
+		},
+		NoteworthyRoutes,
+	}
+	router := newTestRouter(t, routes)
+	route, score, err := router.Match(context.Background(), "I want a refund")
+	require.NoError(t, err)
+	require.NotNil(t, route)
+	a.Equal("billing", route.Name)
+	a.InDelta(1.0, score, 1e-9)
+
+	ctx := semanticrouter.WithFilter(
+		context.Background(),
+		semanticrouter.HasTags("tenant=globex"),
+	)
+	res, err := router.Resolve(ctx, "I want a refund")
+	require.NoError(t, err)
+	a.Nil(res.Rule)
+	a.Nil(res.Route)
+}
@@ -6,14 +6,16 @@ require (
 	github.com/conneroisu/semanticrouter-go/encoders/ollama v0.0.0-20240909025305-0a3db7c99137
 	github.com/conneroisu/semanticrouter-go/stores/memory v0.0.0-20240909020055-7c8ab7483baa
 	github.com/ollama/ollama v0.3.10
+	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/stretchr/testify v1.9.0
 	golang.org/x/sync v0.7.0
+	golang.org/x/sys v0.21.0
 	gonum.org/v1/gonum v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/exp v0.0.0-20240222234643-814bf88cf225 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
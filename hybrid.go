@@ -0,0 +1,175 @@
+package semanticrouter
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// DefaultRRFConstant is the default constant of reciprocal rank fusion,
+// dampening the weight of the top ranks.
+const DefaultRRFConstant = 60
+
+// FusionMode is how the lexical BM25 score of a route is fused with its
+// semantic score.
+type FusionMode int
+
+const (
+	// WeightedFusion adds the BM25 score, times its weight, to the semantic
+	// score.
+	WeightedFusion FusionMode = iota
+	// RRFFusion scores routes by reciprocal rank fusion of their ranks by
+	// semantic and by BM25 score, weighting the BM25 rank by the weight. The
+	// fused score is normalized so that a route ranked first by both scores
+	// 1.
+	RRFFusion
+)
+
+// Fusion configures how the lexical and semantic scores of a route are
+// fused.
+type Fusion struct {
+	Mode   FusionMode // Mode is how the scores are fused.
+	Weight float64    // Weight is the weight of the BM25 score.
+}
+
+// WithBM25 scores the routes by the BM25 score of their utterances against
+// the utterance matched, in addition to the router's similarity functions,
+// with the given coefficient as the weight of the BM25 score.
+//
+// The BM25 scores are normalized between 0 and 1 and fused with the
+// semantic score of each route, its best utterance score, by a weighted sum
+// unless WithRRF is given. Routes can override the fusion with their Fusion
+// field.
+func WithBM25(coefficient float64) Option {
+	return func(r *Router) {
+		r.biFuncCoeffs = append(r.biFuncCoeffs, biFuncCoefficient{
+			name:        "bm25",
+			lexical:     true,
+			coefficient: coefficient,
+		})
+	}
+}
+
+// WithRRF fuses the lexical and semantic scores of the routes by reciprocal
+// rank fusion with the given constant, DefaultRRFConstant if not positive.
+func WithRRF(constant float64) Option {
+	return func(r *Router) {
+		r.rrf = true
+		r.rrfConstant = constant
+	}
+}
+
+// hybrid holds the BM25 index of an index's utterances and the fusion of
+// the scores of each route.
+type hybrid struct {
+	bm25     *bm25Index // bm25 indexes the utterances of the index's entries.
+	routes   []int      // routes holds the route of each entry, -1 for negative utterances.
+	fusions  []Fusion   // fusions holds the fusion of each route.
+	constant float64    // constant is the constant of reciprocal rank fusion.
+}
+
+// newHybrid returns how the routes of the given index are scored lexically
+// by the router, or nil if they are not.
+func newHybrid[T Float](r *Router, idx *index[T], routes []Route) *hybrid {
+	fusion := Fusion{Mode: WeightedFusion}
+	if r.rrf {
+		fusion.Mode = RRFFusion
+	}
+	for _, c := range r.biFuncCoeffs {
+		if c.lexical {
+			fusion.Weight += c.coefficient
+		}
+	}
+	h := &hybrid{
+		fusions:  make([]Fusion, len(routes)),
+		constant: r.rrfConstant,
+	}
+	if h.constant <= 0 {
+		h.constant = DefaultRRFConstant
+	}
+	lexical := false
+	for i := range routes {
+		h.fusions[i] = fusion
+		if routes[i].Fusion != nil {
+			h.fusions[i] = *routes[i].Fusion
+		}
+		lexical = lexical || h.fusions[i].Weight != 0
+	}
+	if !lexical {
+		return nil
+	}
+	documents := make([]string, len(idx.entries))
+	h.routes = make([]int, len(idx.entries))
+	for i, entry := range idx.entries {
+		h.routes[i] = -1
+		if !entry.negative {
+			documents[i] = entry.utterance.Utterance
+			h.routes[i] = entry.route
+		}
+	}
+	h.bm25 = newBM25Index(documents)
+	return h
+}
+
+// fuse fuses the given semantic scores of the routes with the BM25 scores of
+// their utterances against the given text.
+func (h *hybrid) fuse(semantic []float64, text string) []float64 {
+	return h.apply(semantic, h.lexicalScores(text))
+}
+
+// lexicalScores returns the best BM25 score of the utterances of each route
+// against the given text.
+func (h *hybrid) lexicalScores(text string) []float64 {
+	lexical := make([]float64, len(h.fusions))
+	for i, score := range h.bm25.scores(text) {
+		if route := h.routes[i]; route >= 0 && score > lexical[route] {
+			lexical[route] = score
+		}
+	}
+	return lexical
+}
+
+// apply fuses the given semantic scores of the routes with the given
+// lexical scores.
+func (h *hybrid) apply(semantic, lexical []float64) []float64 {
+	fused := slices.Clone(semantic)
+	var semanticRanks, lexicalRanks []int
+	for i, fusion := range h.fusions {
+		if math.IsInf(semantic[i], -1) || fusion.Weight == 0 {
+			continue
+		}
+		switch fusion.Mode {
+		case WeightedFusion:
+			fused[i] += fusion.Weight * lexical[i]
+		case RRFFusion:
+			if semanticRanks == nil {
+				semanticRanks = ranks(semantic)
+				lexicalRanks = ranks(lexical)
+			}
+			k := h.constant
+			score := 1 / (k + float64(semanticRanks[i]))
+			if lexical[i] > 0 {
+				score += fusion.Weight / (k + float64(lexicalRanks[i]))
+			}
+			fused[i] = score * (k + 1) / (1 + fusion.Weight)
+		}
+	}
+	return fused
+}
+
+// ranks returns the rank of each of the given scores, 1 for the highest,
+// ties ranked in order.
+func ranks(scores []float64) []int {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	result := make([]int, len(scores))
+	for rank, i := range order {
+		result[i] = rank + 1
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHybridScoring tests fusing BM25 scores with semantic scores.
+func TestHybridScoring(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float64{
+		"I want to return item SKU-4411-B": {1, 0, 0},
+		"refund my purchase":               {0.9, 0.1, 0},
+		"where is my package":              {0, 1, 0},
+		"has my order shipped yet":         {0.1, 0.9, 0},
+		"sku-4411-b":                       {0.3, 0.6, 0.2},
+		"where is my package now":          {0, 1, 0},
+	}
+	newRouter := func(fusion *semanticrouter.Fusion, opts ...semanticrouter.Option) *semanticrouter.Router {
+		returns := semanticrouter.Route{
+			Name: "returns",
+			Utterances: []semanticrouter.Utterance{
+				{Utterance: "I want to return item SKU-4411-B"},
+				{Utterance: "refund my purchase"},
+			},
+			Fusion: fusion,
+		}
+		router, err := semanticrouter.NewRouter(
+			[]semanticrouter.Route{returns, {
+				Name: "shipping",
+				Utterances: []semanticrouter.Utterance{
+					{Utterance: "where is my package"},
+					{Utterance: "has my order shipped yet"},
+				},
+			}},
+			&batchEncoder{vectors: vectors},
+			memory.NewStore(),
+			append([]semanticrouter.Option{semanticrouter.WithSimilarityDotMatrix(1.0)}, opts...)...,
+		)
+		require.NoError(t, err)
+		return router
+	}
+	for name, tt := range map[string]struct {
+		fusion   *semanticrouter.Fusion
+		opts     []semanticrouter.Option
+		expected string
+	}{
+		"semantic":        {nil, nil, "shipping"},
+		"weighted":        {nil, []semanticrouter.Option{semanticrouter.WithBM25(1.0)}, "returns"},
+		"rrf":             {nil, []semanticrouter.Option{semanticrouter.WithBM25(2.0), semanticrouter.WithRRF(0)}, "returns"},
+		"route disables":  {&semanticrouter.Fusion{}, []semanticrouter.Option{semanticrouter.WithBM25(1.0)}, "shipping"},
+		"route overrides": {&semanticrouter.Fusion{Mode: semanticrouter.WeightedFusion, Weight: 1.0}, nil, "returns"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			router := newRouter(tt.fusion, tt.opts...)
+			route, _, err := router.Match(ctx, "sku-4411-b")
+			require.NoError(t, err)
+			a.Equal(tt.expected, route.Name)
+
+			route, score, err := router.Match(ctx, "where is my package now")
+			require.NoError(t, err)
+			a.Equal("shipping", route.Name)
+			a.Greater(score, 0.9)
+		})
+	}
+}
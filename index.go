@@ -0,0 +1,515 @@
+package semanticrouter
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// indexEntry is the embedding of an utterance of a route.
+type indexEntry[T Float] struct {
+	route     int        // route is the index of the route in the router's routes.
+	utterance *Utterance // utterance is the utterance, in the route.
+	embedding []T        // embedding is the embedding of the utterance.
+	negative  bool       // negative reports whether the utterance is a negative utterance.
+}
+
+// index holds the embeddings of every utterance of the router's routes, in
+// the router's precision, so that matching does not query the store.
+type index[T Float] struct {
+	entries []indexEntry[T]
+	// routes are the routes of the entries.
+	routes []Route
+	// codes holds the quantized embeddings of the entries, if the router
+	// quantizes them.
+	codes Codes
+	// candidates is the number of best quantized matches rescored on the
+	// full embeddings.
+	candidates int
+	// negatives is how the negative utterances affect the scores.
+	negatives negativePolicy
+	// hybrid fuses the scores with BM25 scores, if the router scores
+	// routes lexically.
+	hybrid *hybrid
+}
+
+// embeddingCodec loads, encodes and stores embeddings in a given precision.
+type embeddingCodec[T Float] struct {
+	load   func(ctx context.Context, key string) ([]T, error)
+	encode func(ctx context.Context, documents []string) ([][]T, error)
+	store  func(ctx context.Context, utterance Utterance, embedding []T) error
+	query  func(ctx context.Context, utterance string) ([]T, error)
+	pick   func(c biFuncCoefficient) handler[T]
+}
+
+// buildIndex loads the embeddings of the given routes' utterances and
+// negative utterances from the store, encodes and stores the missing ones,
+// and indexes all of them.
+func buildIndex[T Float](
+	ctx context.Context,
+	routes []Route,
+	codec embeddingCodec[T],
+) (*index[T], error) {
+	embeddings := make(map[string][]T)
+	var missing []Utterance
+	for i := range routes {
+		for _, utter := range slices.Concat(routes[i].Utterances, routes[i].NegativeUtterances) {
+			if _, ok := embeddings[utter.Utterance]; ok {
+				continue
+			}
+			em, err := codec.load(ctx, utter.Utterance)
+			if err != nil {
+				missing = append(missing, utter)
+			}
+			embeddings[utter.Utterance] = em
+		}
+	}
+	if len(missing) > 0 {
+		texts := make([]string, len(missing))
+		for i, utter := range missing {
+			texts[i] = utter.Utterance
+		}
+		encoded, err := codec.encode(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		for i, utter := range missing {
+			err = codec.store(ctx, utter, encoded[i])
+			if err != nil {
+				return nil, fmt.Errorf(
+					"error storing utterance: %s: %w",
+					utter.Utterance,
+					err,
+				)
+			}
+			embeddings[utter.Utterance] = encoded[i]
+		}
+	}
+	idx := &index[T]{routes: routes}
+	for i := range routes {
+		for j := range routes[i].Utterances {
+			utter := &routes[i].Utterances[j]
+			idx.entries = append(idx.entries, indexEntry[T]{
+				route:     i,
+				utterance: utter,
+				embedding: embeddings[utter.Utterance],
+			})
+		}
+		for j := range routes[i].NegativeUtterances {
+			utter := &routes[i].NegativeUtterances[j]
+			idx.entries = append(idx.entries, indexEntry[T]{
+				route:     i,
+				utterance: utter,
+				embedding: embeddings[utter.Utterance],
+				negative:  true,
+			})
+		}
+	}
+	return idx, nil
+}
+
+// buildIndexes builds the index of the given routes and, if any of them has
+// children, the tree of the routes and their descendants, whose root indexes
+// the routes. The embeddings are loaded once, shared by the index and the
+// tree, and quantized if the router quantizes them.
+func buildIndexes[T Float](
+	ctx context.Context,
+	r *Router,
+	routes []Route,
+	codec embeddingCodec[T],
+) (*index[T], *treeNode[T], error) {
+	if !hierarchical(routes) {
+		idx, err := buildIndex(ctx, routes, codec)
+		if err != nil {
+			return nil, nil, err
+		}
+		prepareIndex(r, idx, routes)
+		if r.quantizer != nil {
+			err = idx.quantize(r.quantizer, r.candidates)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return idx, nil, nil
+	}
+	flat := flatten(routes)
+	values := make([]Route, len(flat))
+	for i, route := range flat {
+		values[i] = Route{
+			Name:               route.Name,
+			Utterances:         route.Utterances,
+			NegativeUtterances: route.NegativeUtterances,
+		}
+	}
+	all, err := buildIndex(ctx, values, codec)
+	if err != nil {
+		return nil, nil, err
+	}
+	own := make(map[*Route][]indexEntry[T], len(flat))
+	for _, entry := range all.entries {
+		route := flat[entry.route]
+		own[route] = append(own[route], entry)
+	}
+	tree := buildTree(r, routes, own)
+	if r.quantizer != nil {
+		// The quantizer is fitted on every embedding, so that the indexes
+		// of all the levels share its codebooks.
+		embeddings := make([][]float32, len(all.entries))
+		for i, entry := range all.entries {
+			embeddings[i] = toFloat32(entry.embedding)
+		}
+		err = r.quantizer.Fit(embeddings)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error fitting quantizer: %w", err)
+		}
+		err = tree.quantize(r.quantizer, r.candidates)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return tree.utterances, tree, nil
+}
+
+// scores returns the best score of the utterances of each of the given
+// number of routes against the given query, penalized or vetoed by the best
+// score of their negative utterances, and fused with their BM25 scores
+// against the query's text if the index is hybrid, with the utterance of
+// each route scoring best. Routes without a scored utterance, or vetoed,
+// score negative infinity.
+//
+// Embeddings whose length differs from the query's, and utterances rejected
+// by the filter of the context, are skipped. The entries are split between
+// the given number of workers.
+func (idx *index[T]) scores(
+	ctx context.Context,
+	query []T,
+	text string,
+	coeffs []biFuncCoefficient,
+	pick func(c biFuncCoefficient) handler[T],
+	workers int,
+	routes int,
+) ([]float64, []*Utterance, error) {
+	t, err := idx.semanticScores(ctx, query, coeffs, pick, workers, routes)
+	if err != nil {
+		return nil, nil, err
+	}
+	scores := idx.negatives.apply(t.scores)
+	hits := make([]*Utterance, routes)
+	for i, entry := range t.entries[:routes] {
+		if entry >= 0 {
+			hits[i] = idx.entries[entry].utterance
+		}
+	}
+	if idx.hybrid != nil {
+		scores = idx.hybrid.fuse(scores, text)
+	}
+	return scores, hits, nil
+}
+
+// tally holds the best score of each route, followed by the best score of
+// their negative utterances, with the entries scoring them.
+type tally struct {
+	scores  []float64
+	entries []int
+}
+
+// newTally returns the tally of the given number of routes without a
+// scored utterance.
+func newTally(routes int) *tally {
+	t := &tally{
+		scores:  emptyScores(2 * routes),
+		entries: make([]int, 2*routes),
+	}
+	for i := range t.entries {
+		t.entries[i] = -1
+	}
+	return t
+}
+
+// add records the score of the given entry in the given slot if it is the
+// best.
+func (t *tally) add(slot, entry int, score float64) {
+	if score > t.scores[slot] {
+		t.scores[slot] = score
+		t.entries[slot] = entry
+	}
+}
+
+// merge records the best scores of the given tally.
+func (t *tally) merge(other *tally) {
+	for slot, score := range other.scores {
+		t.add(slot, other.entries[slot], score)
+	}
+}
+
+// skip reports whether the given entry is skipped for the given query and
+// filter.
+func (idx *index[T]) skip(entry indexEntry[T], query []T, filter Filter) bool {
+	return len(entry.embedding) != len(query) ||
+		(filter != nil && !filter(&idx.routes[entry.route], entry.utterance))
+}
+
+// semanticScores returns the tally of the scores of the given number of
+// routes against the given query, before the negative utterances are
+// applied and the scores fused with their BM25 scores.
+func (idx *index[T]) semanticScores(
+	ctx context.Context,
+	query []T,
+	coeffs []biFuncCoefficient,
+	pick func(c biFuncCoefficient) handler[T],
+	workers int,
+	routes int,
+) (*tally, error) {
+	if idx.codes != nil {
+		return idx.scoresQuantized(ctx, query, coeffs, pick, routes)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	filter := filterFrom(ctx)
+	chunk := max((len(idx.entries)+workers-1)/workers, 1)
+	results := make([]*tally, (len(idx.entries)+chunk-1)/chunk)
+	eg, ctx := errgroup.WithContext(ctx)
+	for w := range results {
+		start := w * chunk
+		end := min(start+chunk, len(idx.entries))
+		eg.Go(func() error {
+			best := newTally(routes)
+			for i := start; i < end; i++ {
+				entry := idx.entries[i]
+				if idx.skip(entry, query, filter) {
+					continue
+				}
+				score, err := computeScore(coeffs, pick, query, entry.embedding)
+				if err != nil {
+					return err
+				}
+				best.add(entry.slot(routes), i, score)
+			}
+			results[w] = best
+			return ctx.Err()
+		})
+	}
+	err := eg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	t := newTally(routes)
+	for _, res := range results {
+		t.merge(res)
+	}
+	return t, nil
+}
+
+// slot returns the position of the best score of the entry's route among
+// the scores of the given number of routes, followed by the scores of their
+// negative utterances.
+func (e indexEntry[T]) slot(routes int) int {
+	if e.negative {
+		return routes + e.route
+	}
+	return e.route
+}
+
+// negativePolicy is how the negative utterances of a route affect its score.
+type negativePolicy struct {
+	penalty float64 // penalty weighs the negative score subtracted from the score.
+	veto    bool    // veto reports whether routes can be vetoed.
+	margin  float64 // margin is the veto margin.
+}
+
+// apply returns the scores of the routes given their scores followed by the
+// scores of their negative utterances.
+func (p negativePolicy) apply(scores []float64) []float64 {
+	routes := len(scores) / 2
+	for i, negative := range scores[routes:] {
+		if math.IsInf(negative, -1) || math.IsInf(scores[i], -1) {
+			continue
+		}
+		if p.veto && negative+p.margin >= scores[i] {
+			scores[i] = math.Inf(-1)
+			continue
+		}
+		if negative > 0 {
+			scores[i] -= p.penalty * negative
+		}
+	}
+	return scores[:routes]
+}
+
+// emptyScores returns the scores of the given number of routes without a
+// scored utterance.
+func emptyScores(routes int) []float64 {
+	scores := make([]float64, routes)
+	for i := range scores {
+		scores[i] = math.Inf(-1)
+	}
+	return scores
+}
+
+// pickBest returns the index of the route with the best score above zero,
+// the first one in case of ties, or -1 if none scores above zero.
+func pickBest(scores []float64) (int, float64) {
+	best, bestScore := -1, 0.0
+	for i, score := range scores {
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best, bestScore
+}
+
+// quantize compresses the embeddings of the index with the given quantizer,
+// fitting it on them first, and drops them unless candidates is positive.
+func (idx *index[T]) quantize(quantizer Quantizer, candidates int) error {
+	embeddings := make([][]float32, len(idx.entries))
+	for i, entry := range idx.entries {
+		embeddings[i] = toFloat32(entry.embedding)
+	}
+	err := quantizer.Fit(embeddings)
+	if err != nil {
+		return fmt.Errorf("error fitting quantizer: %w", err)
+	}
+	idx.codes, err = quantizer.Quantize(embeddings)
+	if err != nil {
+		return fmt.Errorf("error quantizing embeddings: %w", err)
+	}
+	idx.candidates = candidates
+	if candidates <= 0 {
+		for i := range idx.entries {
+			idx.entries[i].embedding = nil
+		}
+	}
+	return nil
+}
+
+// scoresQuantized returns the tally of the scores of each route against the
+// given query, scanning the quantized embeddings and rescoring the best
+// candidates on the full embeddings. Only the candidates are scored if
+// rescoring.
+func (idx *index[T]) scoresQuantized(
+	ctx context.Context,
+	query []T,
+	coeffs []biFuncCoefficient,
+	pick func(c biFuncCoefficient) handler[T],
+	routes int,
+) (*tally, error) {
+	approx := make([]float32, idx.codes.Len())
+	err := idx.codes.Scores(toFloat32(query), approx)
+	if err != nil {
+		return nil, err
+	}
+	filter := filterFrom(ctx)
+	for i, entry := range idx.entries {
+		if filter != nil && !filter(&idx.routes[entry.route], entry.utterance) {
+			approx[i] = float32(math.Inf(-1))
+		}
+	}
+	t := newTally(routes)
+	if idx.candidates <= 0 {
+		for i, score := range approx {
+			t.add(idx.entries[i].slot(routes), i, float64(score))
+		}
+		return t, nil
+	}
+	for _, i := range topCandidates(approx, idx.candidates) {
+		entry := idx.entries[i]
+		if idx.skip(entry, query, filter) {
+			continue
+		}
+		score, err := computeScore(coeffs, pick, query, entry.embedding)
+		if err != nil {
+			return nil, err
+		}
+		t.add(entry.slot(routes), i, score)
+	}
+	return t, nil
+}
+
+// candidateHeap is a min-heap of the indices of the best scores seen.
+type candidateHeap struct {
+	indices []int
+	scores  []float32
+}
+
+// Len returns the number of candidates.
+func (h *candidateHeap) Len() int {
+	return len(h.indices)
+}
+
+// Less reports whether the i-th candidate scores lower than the j-th.
+func (h *candidateHeap) Less(i, j int) bool {
+	return h.scores[h.indices[i]] < h.scores[h.indices[j]]
+}
+
+// Swap swaps the i-th and j-th candidates.
+func (h *candidateHeap) Swap(i, j int) {
+	h.indices[i], h.indices[j] = h.indices[j], h.indices[i]
+}
+
+// Push adds a candidate.
+func (h *candidateHeap) Push(x any) {
+	h.indices = append(h.indices, x.(int))
+}
+
+// Pop removes the last candidate.
+func (h *candidateHeap) Pop() any {
+	last := h.indices[len(h.indices)-1]
+	h.indices = h.indices[:len(h.indices)-1]
+	return last
+}
+
+// topCandidates returns the indices of the n highest scores, in increasing
+// order of index so that ties are broken as in an exhaustive scan.
+func topCandidates(scores []float32, n int) []int {
+	h := &candidateHeap{indices: make([]int, 0, n), scores: scores}
+	for i, score := range scores {
+		if h.Len() < n {
+			heap.Push(h, i)
+		} else if score > scores[h.indices[0]] {
+			h.indices[0] = i
+			heap.Fix(h, 0)
+		}
+	}
+	slices.Sort(h.indices)
+	return h.indices
+}
+
+// toFloat32 returns the given embedding as float32 values, converting it
+// only if needed.
+func toFloat32[T Float](embedding []T) []float32 {
+	if v, ok := any(embedding).([]float32); ok {
+		return v
+	}
+	return Convert[float32](embedding)
+}
+
+// computeScore computes the score for a given utterance and route.
+//
+// It takes a query vector and an index vector as input and returns a score.
+//
+// Additionally, it leverages the router's biFuncCoefficients to apply different
+// weighting factors to functions to get the similarity score. The lexical
+// coefficients are skipped, as BM25 scores are fused per route.
+func computeScore[T Float](
+	coeffs []biFuncCoefficient,
+	pick func(c biFuncCoefficient) handler[T],
+	queryVec, indexVec []T,
+) (float64, error) {
+	score := 0.0
+	for _, fn := range coeffs {
+		if fn.lexical {
+			continue
+		}
+		interScore, err := pick(fn)(queryVec, indexVec)
+		if err != nil {
+			return 0, err
+		}
+		score += fn.coefficient * interScore
+	}
+	return score, nil
+}
@@ -0,0 +1,177 @@
+package semanticrouter
+
+// kernelSet holds the kernels the similarity functions are built on.
+//
+// Every kernel reads len(x) elements of x and y, which must be at least as
+// long as x, and neither allocates nor retains its arguments.
+type kernelSet[T Float] struct {
+	// dot returns the dot product of x and y.
+	dot func(x, y []T) T
+	// sum returns the sum of the elements of x.
+	sum func(x []T) T
+	// l1 returns the sum of the absolute differences of x and y.
+	l1 func(x, y []T) T
+	// l2 returns the sum of the squared differences of x and y.
+	l2 func(x, y []T) T
+	// moments returns the sums of (x-mx)(y-my), (x-mx)² and (y-my)².
+	moments func(x, y []T, mx, my T) (xy, xx, yy T)
+	// minMax returns the sums of the element-wise minimums and maximums of x
+	// and y.
+	minMax func(x, y []T) (mins, maxs T)
+}
+
+// genericKernels returns the pure Go kernels.
+func genericKernels[T Float]() kernelSet[T] {
+	return kernelSet[T]{
+		dot:     dotGeneric[T],
+		sum:     sumGeneric[T],
+		l1:      l1Generic[T],
+		l2:      l2Generic[T],
+		moments: momentsGeneric[T],
+		minMax:  minMaxGeneric[T],
+	}
+}
+
+// kernels32 and kernels64 are the kernels used for float32 and float64
+// embeddings. They are replaced by assembly kernels on CPUs supporting them.
+var (
+	kernels32 = genericKernels[float32]()
+	kernels64 = genericKernels[float64]()
+)
+
+// kernelsFor returns the kernels for embeddings with elements of type T.
+func kernelsFor[T Float]() *kernelSet[T] {
+	var zero T
+	switch any(zero).(type) {
+	case float32:
+		return any(&kernels32).(*kernelSet[T])
+	case float64:
+		return any(&kernels64).(*kernelSet[T])
+	default:
+		k := genericKernels[T]()
+		return &k
+	}
+}
+
+// dotGeneric returns the dot product of x and y.
+func dotGeneric[T Float](x, y []T) T {
+	y = y[:len(x)]
+	var s0, s1, s2, s3 T
+	i := 0
+	for ; i <= len(x)-4; i += 4 {
+		s0 += x[i] * y[i]
+		s1 += x[i+1] * y[i+1]
+		s2 += x[i+2] * y[i+2]
+		s3 += x[i+3] * y[i+3]
+	}
+	for ; i < len(x); i++ {
+		s0 += x[i] * y[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// sumGeneric returns the sum of the elements of x.
+func sumGeneric[T Float](x []T) T {
+	var s0, s1, s2, s3 T
+	i := 0
+	for ; i <= len(x)-4; i += 4 {
+		s0 += x[i]
+		s1 += x[i+1]
+		s2 += x[i+2]
+		s3 += x[i+3]
+	}
+	for ; i < len(x); i++ {
+		s0 += x[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// abs returns the absolute value of x.
+func abs[T Float](x T) T {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// l1Generic returns the sum of the absolute differences of x and y.
+func l1Generic[T Float](x, y []T) T {
+	y = y[:len(x)]
+	var s0, s1, s2, s3 T
+	i := 0
+	for ; i <= len(x)-4; i += 4 {
+		s0 += abs(x[i] - y[i])
+		s1 += abs(x[i+1] - y[i+1])
+		s2 += abs(x[i+2] - y[i+2])
+		s3 += abs(x[i+3] - y[i+3])
+	}
+	for ; i < len(x); i++ {
+		s0 += abs(x[i] - y[i])
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// l2Generic returns the sum of the squared differences of x and y.
+func l2Generic[T Float](x, y []T) T {
+	y = y[:len(x)]
+	var s0, s1, s2, s3 T
+	i := 0
+	for ; i <= len(x)-4; i += 4 {
+		d0 := x[i] - y[i]
+		d1 := x[i+1] - y[i+1]
+		d2 := x[i+2] - y[i+2]
+		d3 := x[i+3] - y[i+3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < len(x); i++ {
+		d := x[i] - y[i]
+		s0 += d * d
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// momentsGeneric returns the sums of (x-mx)(y-my), (x-mx)² and (y-my)².
+func momentsGeneric[T Float](x, y []T, mx, my T) (xy, xx, yy T) {
+	y = y[:len(x)]
+	var xy1, xx1, yy1 T
+	i := 0
+	for ; i <= len(x)-2; i += 2 {
+		a0, b0 := x[i]-mx, y[i]-my
+		a1, b1 := x[i+1]-mx, y[i+1]-my
+		xy += a0 * b0
+		xx += a0 * a0
+		yy += b0 * b0
+		xy1 += a1 * b1
+		xx1 += a1 * a1
+		yy1 += b1 * b1
+	}
+	for ; i < len(x); i++ {
+		a, b := x[i]-mx, y[i]-my
+		xy += a * b
+		xx += a * a
+		yy += b * b
+	}
+	return xy + xy1, xx + xx1, yy + yy1
+}
+
+// minMaxGeneric returns the sums of the element-wise minimums and maximums
+// of x and y.
+func minMaxGeneric[T Float](x, y []T) (mins, maxs T) {
+	y = y[:len(x)]
+	var mins1, maxs1 T
+	i := 0
+	for ; i <= len(x)-2; i += 2 {
+		mins += min(x[i], y[i])
+		maxs += max(x[i], y[i])
+		mins1 += min(x[i+1], y[i+1])
+		maxs1 += max(x[i+1], y[i+1])
+	}
+	for ; i < len(x); i++ {
+		mins += min(x[i], y[i])
+		maxs += max(x[i], y[i])
+	}
+	return mins + mins1, maxs + maxs1
+}
@@ -0,0 +1,63 @@
+//go:build !purego
+
+package semanticrouter
+
+import "golang.org/x/sys/cpu"
+
+func init() {
+	if !cpu.X86.HasAVX2 || !cpu.X86.HasFMA {
+		return
+	}
+	kernels32 = kernelSet[float32]{
+		dot:     dotFloat32AVX2,
+		sum:     sumFloat32AVX2,
+		l1:      l1Float32AVX2,
+		l2:      l2Float32AVX2,
+		moments: momentsFloat32AVX2,
+		minMax:  minMaxFloat32AVX2,
+	}
+	kernels64 = kernelSet[float64]{
+		dot:     dotFloat64AVX2,
+		sum:     sumFloat64AVX2,
+		l1:      l1Float64AVX2,
+		l2:      l2Float64AVX2,
+		moments: momentsFloat64AVX2,
+		minMax:  minMaxFloat64AVX2,
+	}
+}
+
+//go:noescape
+func dotFloat32AVX2(x, y []float32) float32
+
+//go:noescape
+func sumFloat32AVX2(x []float32) float32
+
+//go:noescape
+func l1Float32AVX2(x, y []float32) float32
+
+//go:noescape
+func l2Float32AVX2(x, y []float32) float32
+
+//go:noescape
+func momentsFloat32AVX2(x, y []float32, mx, my float32) (xy, xx, yy float32)
+
+//go:noescape
+func minMaxFloat32AVX2(x, y []float32) (mins, maxs float32)
+
+//go:noescape
+func dotFloat64AVX2(x, y []float64) float64
+
+//go:noescape
+func sumFloat64AVX2(x []float64) float64
+
+//go:noescape
+func l1Float64AVX2(x, y []float64) float64
+
+//go:noescape
+func l2Float64AVX2(x, y []float64) float64
+
+//go:noescape
+func momentsFloat64AVX2(x, y []float64, mx, my float64) (xy, xx, yy float64)
+
+//go:noescape
+func minMaxFloat64AVX2(x, y []float64) (mins, maxs float64)
@@ -0,0 +1,798 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// The kernels process 32 float32 or 16 float64 elements per iteration with
+// four independent accumulators, then one vector of 8 float32 or 4 float64
+// elements at a time, and finally the remaining elements one at a time.
+//
+// Registers: SI = x, DI = y, CX = remaining elements.
+
+// HSUMPS reduces the eight float32 lanes of y into the low lane of x, the
+// low half of y, using t as scratch.
+#define HSUMPS(y, x, t) \
+	VEXTRACTF128 $1, y, t; \
+	VADDPS       t, x, x;  \
+	VHADDPS      x, x, x;  \
+	VHADDPS      x, x, x
+
+// HSUMPD reduces the four float64 lanes of y into the low lane of x, the low
+// half of y, using t as scratch.
+#define HSUMPD(y, x, t) \
+	VEXTRACTF128 $1, y, t; \
+	VADDPD       t, x, x;  \
+	VHADDPD      x, x, x
+
+// func dotFloat32AVX2(x, y []float32) float32
+TEXT ·dotFloat32AVX2(SB), NOSPLIT, $0-52
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y2, Y2, Y2
+	VXORPS Y3, Y3, Y3
+
+loop32:
+	CMPQ        CX, $32
+	JL          loop8
+	VMOVUPS     (SI), Y4
+	VMOVUPS     32(SI), Y5
+	VMOVUPS     64(SI), Y6
+	VMOVUPS     96(SI), Y7
+	VFMADD231PS (DI), Y4, Y0
+	VFMADD231PS 32(DI), Y5, Y1
+	VFMADD231PS 64(DI), Y6, Y2
+	VFMADD231PS 96(DI), Y7, Y3
+	ADDQ        $128, SI
+	ADDQ        $128, DI
+	SUBQ        $32, CX
+	JMP         loop32
+
+loop8:
+	CMPQ        CX, $8
+	JL          reduce
+	VMOVUPS     (SI), Y4
+	VFMADD231PS (DI), Y4, Y0
+	ADDQ        $32, SI
+	ADDQ        $32, DI
+	SUBQ        $8, CX
+	JMP         loop8
+
+reduce:
+	VADDPS Y1, Y0, Y0
+	VADDPS Y3, Y2, Y2
+	VADDPS Y2, Y0, Y0
+	HSUMPS(Y0, X0, X1)
+
+tail:
+	CMPQ        CX, $0
+	JE          done
+	VMOVSS      (SI), X4
+	VFMADD231SS (DI), X4, X0
+	ADDQ        $4, SI
+	ADDQ        $4, DI
+	DECQ        CX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSS X0, ret+48(FP)
+	RET
+
+// func sumFloat32AVX2(x []float32) float32
+TEXT ·sumFloat32AVX2(SB), NOSPLIT, $0-28
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y2, Y2, Y2
+	VXORPS Y3, Y3, Y3
+
+loop32:
+	CMPQ   CX, $32
+	JL     loop8
+	VADDPS (SI), Y0, Y0
+	VADDPS 32(SI), Y1, Y1
+	VADDPS 64(SI), Y2, Y2
+	VADDPS 96(SI), Y3, Y3
+	ADDQ   $128, SI
+	SUBQ   $32, CX
+	JMP    loop32
+
+loop8:
+	CMPQ   CX, $8
+	JL     reduce
+	VADDPS (SI), Y0, Y0
+	ADDQ   $32, SI
+	SUBQ   $8, CX
+	JMP    loop8
+
+reduce:
+	VADDPS Y1, Y0, Y0
+	VADDPS Y3, Y2, Y2
+	VADDPS Y2, Y0, Y0
+	HSUMPS(Y0, X0, X1)
+
+tail:
+	CMPQ   CX, $0
+	JE     done
+	VADDSS (SI), X0, X0
+	ADDQ   $4, SI
+	DECQ   CX
+	JMP    tail
+
+done:
+	VZEROUPPER
+	MOVSS X0, ret+24(FP)
+	RET
+
+// func l1Float32AVX2(x, y []float32) float32
+TEXT ·l1Float32AVX2(SB), NOSPLIT, $0-52
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y2, Y2, Y2
+	VXORPS Y3, Y3, Y3
+
+	// Y15 = 0x7fffffff in every lane, the mask clearing the sign bit.
+	VPCMPEQD Y15, Y15, Y15
+	VPSRLD   $1, Y15, Y15
+
+loop32:
+	CMPQ    CX, $32
+	JL      loop8
+	VMOVUPS (SI), Y4
+	VMOVUPS 32(SI), Y5
+	VMOVUPS 64(SI), Y6
+	VMOVUPS 96(SI), Y7
+	VSUBPS  (DI), Y4, Y4
+	VSUBPS  32(DI), Y5, Y5
+	VSUBPS  64(DI), Y6, Y6
+	VSUBPS  96(DI), Y7, Y7
+	VANDPS  Y15, Y4, Y4
+	VANDPS  Y15, Y5, Y5
+	VANDPS  Y15, Y6, Y6
+	VANDPS  Y15, Y7, Y7
+	VADDPS  Y4, Y0, Y0
+	VADDPS  Y5, Y1, Y1
+	VADDPS  Y6, Y2, Y2
+	VADDPS  Y7, Y3, Y3
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	SUBQ    $32, CX
+	JMP     loop32
+
+loop8:
+	CMPQ    CX, $8
+	JL      reduce
+	VMOVUPS (SI), Y4
+	VSUBPS  (DI), Y4, Y4
+	VANDPS  Y15, Y4, Y4
+	VADDPS  Y4, Y0, Y0
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $8, CX
+	JMP     loop8
+
+reduce:
+	VADDPS Y1, Y0, Y0
+	VADDPS Y3, Y2, Y2
+	VADDPS Y2, Y0, Y0
+	HSUMPS(Y0, X0, X1)
+
+tail:
+	CMPQ   CX, $0
+	JE     done
+	VMOVSS (SI), X4
+	VSUBSS (DI), X4, X4
+	VANDPS X15, X4, X4
+	VADDSS X4, X0, X0
+	ADDQ   $4, SI
+	ADDQ   $4, DI
+	DECQ   CX
+	JMP    tail
+
+done:
+	VZEROUPPER
+	MOVSS X0, ret+48(FP)
+	RET
+
+// func l2Float32AVX2(x, y []float32) float32
+TEXT ·l2Float32AVX2(SB), NOSPLIT, $0-52
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y2, Y2, Y2
+	VXORPS Y3, Y3, Y3
+
+loop32:
+	CMPQ        CX, $32
+	JL          loop8
+	VMOVUPS     (SI), Y4
+	VMOVUPS     32(SI), Y5
+	VMOVUPS     64(SI), Y6
+	VMOVUPS     96(SI), Y7
+	VSUBPS      (DI), Y4, Y4
+	VSUBPS      32(DI), Y5, Y5
+	VSUBPS      64(DI), Y6, Y6
+	VSUBPS      96(DI), Y7, Y7
+	VFMADD231PS Y4, Y4, Y0
+	VFMADD231PS Y5, Y5, Y1
+	VFMADD231PS Y6, Y6, Y2
+	VFMADD231PS Y7, Y7, Y3
+	ADDQ        $128, SI
+	ADDQ        $128, DI
+	SUBQ        $32, CX
+	JMP         loop32
+
+loop8:
+	CMPQ        CX, $8
+	JL          reduce
+	VMOVUPS     (SI), Y4
+	VSUBPS      (DI), Y4, Y4
+	VFMADD231PS Y4, Y4, Y0
+	ADDQ        $32, SI
+	ADDQ        $32, DI
+	SUBQ        $8, CX
+	JMP         loop8
+
+reduce:
+	VADDPS Y1, Y0, Y0
+	VADDPS Y3, Y2, Y2
+	VADDPS Y2, Y0, Y0
+	HSUMPS(Y0, X0, X1)
+
+tail:
+	CMPQ        CX, $0
+	JE          done
+	VMOVSS      (SI), X4
+	VSUBSS      (DI), X4, X4
+	VFMADD231SS X4, X4, X0
+	ADDQ        $4, SI
+	ADDQ        $4, DI
+	DECQ        CX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSS X0, ret+48(FP)
+	RET
+
+// func momentsFloat32AVX2(x, y []float32, mx, my float32) (xy, xx, yy float32)
+TEXT ·momentsFloat32AVX2(SB), NOSPLIT, $0-68
+	MOVQ         x_base+0(FP), SI
+	MOVQ         x_len+8(FP), CX
+	MOVQ         y_base+24(FP), DI
+	VBROADCASTSS mx+48(FP), Y13
+	VBROADCASTSS my+52(FP), Y14
+	VXORPS       Y0, Y0, Y0
+	VXORPS       Y1, Y1, Y1
+	VXORPS       Y2, Y2, Y2
+	VXORPS       Y8, Y8, Y8
+	VXORPS       Y9, Y9, Y9
+	VXORPS       Y10, Y10, Y10
+
+loop16:
+	CMPQ        CX, $16
+	JL          loop8
+	VMOVUPS     (SI), Y4
+	VMOVUPS     32(SI), Y6
+	VMOVUPS     (DI), Y5
+	VMOVUPS     32(DI), Y7
+	VSUBPS      Y13, Y4, Y4
+	VSUBPS      Y13, Y6, Y6
+	VSUBPS      Y14, Y5, Y5
+	VSUBPS      Y14, Y7, Y7
+	VFMADD231PS Y5, Y4, Y0
+	VFMADD231PS Y4, Y4, Y1
+	VFMADD231PS Y5, Y5, Y2
+	VFMADD231PS Y7, Y6, Y8
+	VFMADD231PS Y6, Y6, Y9
+	VFMADD231PS Y7, Y7, Y10
+	ADDQ        $64, SI
+	ADDQ        $64, DI
+	SUBQ        $16, CX
+	JMP         loop16
+
+loop8:
+	CMPQ        CX, $8
+	JL          reduce
+	VMOVUPS     (SI), Y4
+	VMOVUPS     (DI), Y5
+	VSUBPS      Y13, Y4, Y4
+	VSUBPS      Y14, Y5, Y5
+	VFMADD231PS Y5, Y4, Y0
+	VFMADD231PS Y4, Y4, Y1
+	VFMADD231PS Y5, Y5, Y2
+	ADDQ        $32, SI
+	ADDQ        $32, DI
+	SUBQ        $8, CX
+	JMP         loop8
+
+reduce:
+	VADDPS Y8, Y0, Y0
+	VADDPS Y9, Y1, Y1
+	VADDPS Y10, Y2, Y2
+	HSUMPS(Y0, X0, X3)
+	HSUMPS(Y1, X1, X3)
+	HSUMPS(Y2, X2, X3)
+
+tail:
+	CMPQ        CX, $0
+	JE          done
+	VMOVSS      (SI), X4
+	VMOVSS      (DI), X5
+	VSUBSS      X13, X4, X4
+	VSUBSS      X14, X5, X5
+	VFMADD231SS X5, X4, X0
+	VFMADD231SS X4, X4, X1
+	VFMADD231SS X5, X5, X2
+	ADDQ        $4, SI
+	ADDQ        $4, DI
+	DECQ        CX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSS X0, xy+56(FP)
+	MOVSS X1, xx+60(FP)
+	MOVSS X2, yy+64(FP)
+	RET
+
+// func minMaxFloat32AVX2(x, y []float32) (mins, maxs float32)
+TEXT ·minMaxFloat32AVX2(SB), NOSPLIT, $0-56
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPS Y0, Y0, Y0
+	VXORPS Y1, Y1, Y1
+	VXORPS Y8, Y8, Y8
+	VXORPS Y9, Y9, Y9
+
+loop16:
+	CMPQ    CX, $16
+	JL      loop8
+	VMOVUPS (SI), Y4
+	VMOVUPS 32(SI), Y6
+	VMINPS  (DI), Y4, Y5
+	VMINPS  32(DI), Y6, Y7
+	VMAXPS  (DI), Y4, Y4
+	VMAXPS  32(DI), Y6, Y6
+	VADDPS  Y5, Y0, Y0
+	VADDPS  Y4, Y1, Y1
+	VADDPS  Y7, Y8, Y8
+	VADDPS  Y6, Y9, Y9
+	ADDQ    $64, SI
+	ADDQ    $64, DI
+	SUBQ    $16, CX
+	JMP     loop16
+
+loop8:
+	CMPQ    CX, $8
+	JL      reduce
+	VMOVUPS (SI), Y4
+	VMINPS  (DI), Y4, Y5
+	VMAXPS  (DI), Y4, Y4
+	VADDPS  Y5, Y0, Y0
+	VADDPS  Y4, Y1, Y1
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $8, CX
+	JMP     loop8
+
+reduce:
+	VADDPS Y8, Y0, Y0
+	VADDPS Y9, Y1, Y1
+	HSUMPS(Y0, X0, X3)
+	HSUMPS(Y1, X1, X3)
+
+tail:
+	CMPQ   CX, $0
+	JE     done
+	VMOVSS (SI), X4
+	VMINSS (DI), X4, X5
+	VMAXSS (DI), X4, X4
+	VADDSS X5, X0, X0
+	VADDSS X4, X1, X1
+	ADDQ   $4, SI
+	ADDQ   $4, DI
+	DECQ   CX
+	JMP    tail
+
+done:
+	VZEROUPPER
+	MOVSS X0, mins+48(FP)
+	MOVSS X1, maxs+52(FP)
+	RET
+
+// func dotFloat64AVX2(x, y []float64) float64
+TEXT ·dotFloat64AVX2(SB), NOSPLIT, $0-56
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+	VXORPD Y2, Y2, Y2
+	VXORPD Y3, Y3, Y3
+
+loop16:
+	CMPQ        CX, $16
+	JL          loop4
+	VMOVUPD     (SI), Y4
+	VMOVUPD     32(SI), Y5
+	VMOVUPD     64(SI), Y6
+	VMOVUPD     96(SI), Y7
+	VFMADD231PD (DI), Y4, Y0
+	VFMADD231PD 32(DI), Y5, Y1
+	VFMADD231PD 64(DI), Y6, Y2
+	VFMADD231PD 96(DI), Y7, Y3
+	ADDQ        $128, SI
+	ADDQ        $128, DI
+	SUBQ        $16, CX
+	JMP         loop16
+
+loop4:
+	CMPQ        CX, $4
+	JL          reduce
+	VMOVUPD     (SI), Y4
+	VFMADD231PD (DI), Y4, Y0
+	ADDQ        $32, SI
+	ADDQ        $32, DI
+	SUBQ        $4, CX
+	JMP         loop4
+
+reduce:
+	VADDPD Y1, Y0, Y0
+	VADDPD Y3, Y2, Y2
+	VADDPD Y2, Y0, Y0
+	HSUMPD(Y0, X0, X1)
+
+tail:
+	CMPQ        CX, $0
+	JE          done
+	VMOVSD      (SI), X4
+	VFMADD231SD (DI), X4, X0
+	ADDQ        $8, SI
+	ADDQ        $8, DI
+	DECQ        CX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, ret+48(FP)
+	RET
+
+// func sumFloat64AVX2(x []float64) float64
+TEXT ·sumFloat64AVX2(SB), NOSPLIT, $0-32
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+	VXORPD Y2, Y2, Y2
+	VXORPD Y3, Y3, Y3
+
+loop16:
+	CMPQ   CX, $16
+	JL     loop4
+	VADDPD (SI), Y0, Y0
+	VADDPD 32(SI), Y1, Y1
+	VADDPD 64(SI), Y2, Y2
+	VADDPD 96(SI), Y3, Y3
+	ADDQ   $128, SI
+	SUBQ   $16, CX
+	JMP    loop16
+
+loop4:
+	CMPQ   CX, $4
+	JL     reduce
+	VADDPD (SI), Y0, Y0
+	ADDQ   $32, SI
+	SUBQ   $4, CX
+	JMP    loop4
+
+reduce:
+	VADDPD Y1, Y0, Y0
+	VADDPD Y3, Y2, Y2
+	VADDPD Y2, Y0, Y0
+	HSUMPD(Y0, X0, X1)
+
+tail:
+	CMPQ   CX, $0
+	JE     done
+	VADDSD (SI), X0, X0
+	ADDQ   $8, SI
+	DECQ   CX
+	JMP    tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, ret+24(FP)
+	RET
+
+// func l1Float64AVX2(x, y []float64) float64
+TEXT ·l1Float64AVX2(SB), NOSPLIT, $0-56
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+	VXORPD Y2, Y2, Y2
+	VXORPD Y3, Y3, Y3
+
+	// Y15 = 0x7fffffffffffffff in every lane, the mask clearing the sign bit.
+	VPCMPEQQ Y15, Y15, Y15
+	VPSRLQ   $1, Y15, Y15
+
+loop16:
+	CMPQ    CX, $16
+	JL      loop4
+	VMOVUPD (SI), Y4
+	VMOVUPD 32(SI), Y5
+	VMOVUPD 64(SI), Y6
+	VMOVUPD 96(SI), Y7
+	VSUBPD  (DI), Y4, Y4
+	VSUBPD  32(DI), Y5, Y5
+	VSUBPD  64(DI), Y6, Y6
+	VSUBPD  96(DI), Y7, Y7
+	VANDPD  Y15, Y4, Y4
+	VANDPD  Y15, Y5, Y5
+	VANDPD  Y15, Y6, Y6
+	VANDPD  Y15, Y7, Y7
+	VADDPD  Y4, Y0, Y0
+	VADDPD  Y5, Y1, Y1
+	VADDPD  Y6, Y2, Y2
+	VADDPD  Y7, Y3, Y3
+	ADDQ    $128, SI
+	ADDQ    $128, DI
+	SUBQ    $16, CX
+	JMP     loop16
+
+loop4:
+	CMPQ    CX, $4
+	JL      reduce
+	VMOVUPD (SI), Y4
+	VSUBPD  (DI), Y4, Y4
+	VANDPD  Y15, Y4, Y4
+	VADDPD  Y4, Y0, Y0
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $4, CX
+	JMP     loop4
+
+reduce:
+	VADDPD Y1, Y0, Y0
+	VADDPD Y3, Y2, Y2
+	VADDPD Y2, Y0, Y0
+	HSUMPD(Y0, X0, X1)
+
+tail:
+	CMPQ   CX, $0
+	JE     done
+	VMOVSD (SI), X4
+	VSUBSD (DI), X4, X4
+	VANDPD X15, X4, X4
+	VADDSD X4, X0, X0
+	ADDQ   $8, SI
+	ADDQ   $8, DI
+	DECQ   CX
+	JMP    tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, ret+48(FP)
+	RET
+
+// func l2Float64AVX2(x, y []float64) float64
+TEXT ·l2Float64AVX2(SB), NOSPLIT, $0-56
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+	VXORPD Y2, Y2, Y2
+	VXORPD Y3, Y3, Y3
+
+loop16:
+	CMPQ        CX, $16
+	JL          loop4
+	VMOVUPD     (SI), Y4
+	VMOVUPD     32(SI), Y5
+	VMOVUPD     64(SI), Y6
+	VMOVUPD     96(SI), Y7
+	VSUBPD      (DI), Y4, Y4
+	VSUBPD      32(DI), Y5, Y5
+	VSUBPD      64(DI), Y6, Y6
+	VSUBPD      96(DI), Y7, Y7
+	VFMADD231PD Y4, Y4, Y0
+	VFMADD231PD Y5, Y5, Y1
+	VFMADD231PD Y6, Y6, Y2
+	VFMADD231PD Y7, Y7, Y3
+	ADDQ        $128, SI
+	ADDQ        $128, DI
+	SUBQ        $16, CX
+	JMP         loop16
+
+loop4:
+	CMPQ        CX, $4
+	JL          reduce
+	VMOVUPD     (SI), Y4
+	VSUBPD      (DI), Y4, Y4
+	VFMADD231PD Y4, Y4, Y0
+	ADDQ        $32, SI
+	ADDQ        $32, DI
+	SUBQ        $4, CX
+	JMP         loop4
+
+reduce:
+	VADDPD Y1, Y0, Y0
+	VADDPD Y3, Y2, Y2
+	VADDPD Y2, Y0, Y0
+	HSUMPD(Y0, X0, X1)
+
+tail:
+	CMPQ        CX, $0
+	JE          done
+	VMOVSD      (SI), X4
+	VSUBSD      (DI), X4, X4
+	VFMADD231SD X4, X4, X0
+	ADDQ        $8, SI
+	ADDQ        $8, DI
+	DECQ        CX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, ret+48(FP)
+	RET
+
+// func momentsFloat64AVX2(x, y []float64, mx, my float64) (xy, xx, yy float64)
+TEXT ·momentsFloat64AVX2(SB), NOSPLIT, $0-88
+	MOVQ         x_base+0(FP), SI
+	MOVQ         x_len+8(FP), CX
+	MOVQ         y_base+24(FP), DI
+	VBROADCASTSD mx+48(FP), Y13
+	VBROADCASTSD my+56(FP), Y14
+	VXORPD       Y0, Y0, Y0
+	VXORPD       Y1, Y1, Y1
+	VXORPD       Y2, Y2, Y2
+	VXORPD       Y8, Y8, Y8
+	VXORPD       Y9, Y9, Y9
+	VXORPD       Y10, Y10, Y10
+
+loop8:
+	CMPQ        CX, $8
+	JL          loop4
+	VMOVUPD     (SI), Y4
+	VMOVUPD     32(SI), Y6
+	VMOVUPD     (DI), Y5
+	VMOVUPD     32(DI), Y7
+	VSUBPD      Y13, Y4, Y4
+	VSUBPD      Y13, Y6, Y6
+	VSUBPD      Y14, Y5, Y5
+	VSUBPD      Y14, Y7, Y7
+	VFMADD231PD Y5, Y4, Y0
+	VFMADD231PD Y4, Y4, Y1
+	VFMADD231PD Y5, Y5, Y2
+	VFMADD231PD Y7, Y6, Y8
+	VFMADD231PD Y6, Y6, Y9
+	VFMADD231PD Y7, Y7, Y10
+	ADDQ        $64, SI
+	ADDQ        $64, DI
+	SUBQ        $8, CX
+	JMP         loop8
+
+loop4:
+	CMPQ        CX, $4
+	JL          reduce
+	VMOVUPD     (SI), Y4
+	VMOVUPD     (DI), Y5
+	VSUBPD      Y13, Y4, Y4
+	VSUBPD      Y14, Y5, Y5
+	VFMADD231PD Y5, Y4, Y0
+	VFMADD231PD Y4, Y4, Y1
+	VFMADD231PD Y5, Y5, Y2
+	ADDQ        $32, SI
+	ADDQ        $32, DI
+	SUBQ        $4, CX
+	JMP         loop4
+
+reduce:
+	VADDPD Y8, Y0, Y0
+	VADDPD Y9, Y1, Y1
+	VADDPD Y10, Y2, Y2
+	HSUMPD(Y0, X0, X3)
+	HSUMPD(Y1, X1, X3)
+	HSUMPD(Y2, X2, X3)
+
+tail:
+	CMPQ        CX, $0
+	JE          done
+	VMOVSD      (SI), X4
+	VMOVSD      (DI), X5
+	VSUBSD      X13, X4, X4
+	VSUBSD      X14, X5, X5
+	VFMADD231SD X5, X4, X0
+	VFMADD231SD X4, X4, X1
+	VFMADD231SD X5, X5, X2
+	ADDQ        $8, SI
+	ADDQ        $8, DI
+	DECQ        CX
+	JMP         tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, xy+64(FP)
+	MOVSD X1, xx+72(FP)
+	MOVSD X2, yy+80(FP)
+	RET
+
+// func minMaxFloat64AVX2(x, y []float64) (mins, maxs float64)
+TEXT ·minMaxFloat64AVX2(SB), NOSPLIT, $0-64
+	MOVQ   x_base+0(FP), SI
+	MOVQ   x_len+8(FP), CX
+	MOVQ   y_base+24(FP), DI
+	VXORPD Y0, Y0, Y0
+	VXORPD Y1, Y1, Y1
+	VXORPD Y8, Y8, Y8
+	VXORPD Y9, Y9, Y9
+
+loop8:
+	CMPQ    CX, $8
+	JL      loop4
+	VMOVUPD (SI), Y4
+	VMOVUPD 32(SI), Y6
+	VMINPD  (DI), Y4, Y5
+	VMINPD  32(DI), Y6, Y7
+	VMAXPD  (DI), Y4, Y4
+	VMAXPD  32(DI), Y6, Y6
+	VADDPD  Y5, Y0, Y0
+	VADDPD  Y4, Y1, Y1
+	VADDPD  Y7, Y8, Y8
+	VADDPD  Y6, Y9, Y9
+	ADDQ    $64, SI
+	ADDQ    $64, DI
+	SUBQ    $8, CX
+	JMP     loop8
+
+loop4:
+	CMPQ    CX, $4
+	JL      reduce
+	VMOVUPD (SI), Y4
+	VMINPD  (DI), Y4, Y5
+	VMAXPD  (DI), Y4, Y4
+	VADDPD  Y5, Y0, Y0
+	VADDPD  Y4, Y1, Y1
+	ADDQ    $32, SI
+	ADDQ    $32, DI
+	SUBQ    $4, CX
+	JMP     loop4
+
+reduce:
+	VADDPD Y8, Y0, Y0
+	VADDPD Y9, Y1, Y1
+	HSUMPD(Y0, X0, X3)
+	HSUMPD(Y1, X1, X3)
+
+tail:
+	CMPQ   CX, $0
+	JE     done
+	VMOVSD (SI), X4
+	VMINSD (DI), X4, X5
+	VMAXSD (DI), X4, X4
+	VADDSD X5, X0, X0
+	VADDSD X4, X1, X1
+	ADDQ   $8, SI
+	ADDQ   $8, DI
+	DECQ   CX
+	JMP    tail
+
+done:
+	VZEROUPPER
+	MOVSD X0, mins+48(FP)
+	MOVSD X1, maxs+56(FP)
+	RET
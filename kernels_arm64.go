@@ -0,0 +1,63 @@
+//go:build !purego
+
+package semanticrouter
+
+import "golang.org/x/sys/cpu"
+
+func init() {
+	if !cpu.ARM64.HasASIMD {
+		return
+	}
+	kernels32 = kernelSet[float32]{
+		dot:     dotFloat32NEON,
+		sum:     sumFloat32NEON,
+		l1:      l1Float32NEON,
+		l2:      l2Float32NEON,
+		moments: momentsFloat32NEON,
+		minMax:  minMaxFloat32NEON,
+	}
+	kernels64 = kernelSet[float64]{
+		dot:     dotFloat64NEON,
+		sum:     sumFloat64NEON,
+		l1:      l1Float64NEON,
+		l2:      l2Float64NEON,
+		moments: momentsFloat64NEON,
+		minMax:  minMaxFloat64NEON,
+	}
+}
+
+//go:noescape
+func dotFloat32NEON(x, y []float32) float32
+
+//go:noescape
+func sumFloat32NEON(x []float32) float32
+
+//go:noescape
+func l1Float32NEON(x, y []float32) float32
+
+//go:noescape
+func l2Float32NEON(x, y []float32) float32
+
+//go:noescape
+func momentsFloat32NEON(x, y []float32, mx, my float32) (xy, xx, yy float32)
+
+//go:noescape
+func minMaxFloat32NEON(x, y []float32) (mins, maxs float32)
+
+//go:noescape
+func dotFloat64NEON(x, y []float64) float64
+
+//go:noescape
+func sumFloat64NEON(x []float64) float64
+
+//go:noescape
+func l1Float64NEON(x, y []float64) float64
+
+//go:noescape
+func l2Float64NEON(x, y []float64) float64
+
+//go:noescape
+func momentsFloat64NEON(x, y []float64, mx, my float64) (xy, xx, yy float64)
+
+//go:noescape
+func minMaxFloat64NEON(x, y []float64) (mins, maxs float64)
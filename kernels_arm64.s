@@ -0,0 +1,697 @@
+//go:build !purego
+
+#include "textflag.h"
+
+// The kernels process 16 float32 or 8 float64 elements per iteration with
+// four independent accumulators, then one vector of 4 float32 or 2 float64
+// elements at a time, and finally the remaining elements one at a time.
+//
+// Registers: R0 = x, R2 = y, R1 = remaining elements.
+//
+// The vector floating-point instructions missing from older Go assemblers are
+// encoded with WORD.
+
+// func dotFloat32NEON(x, y []float32) float32
+TEXT ·dotFloat32NEON(SB), NOSPLIT, $0-52
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop16:
+	CMP    $16, R1
+	BLT    loop4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R2), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VFMLA  V4.S4, V8.S4, V0.S4
+	VFMLA  V5.S4, V9.S4, V1.S4
+	VFMLA  V6.S4, V10.S4, V2.S4
+	VFMLA  V7.S4, V11.S4, V3.S4
+	SUB    $16, R1
+	B      loop16
+
+loop4:
+	CMP    $4, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R2), [V8.S4]
+	VFMLA  V4.S4, V8.S4, V0.S4
+	SUB    $4, R1
+	B      loop4
+
+reduce:
+	WORD $0x4e21d400 // fadd v0.4s, v0.4s, v1.4s
+	WORD $0x4e23d442 // fadd v2.4s, v2.4s, v3.4s
+	WORD $0x4e22d400 // fadd v0.4s, v0.4s, v2.4s
+	WORD $0x6e20d400 // faddp v0.4s, v0.4s, v0.4s
+	WORD $0x7e30d800 // faddp s0, v0.2s
+
+tail:
+	CBZ     R1, done
+	FMOVS.P 4(R0), F4
+	FMOVS.P 4(R2), F8
+	FMADDS  F8, F0, F4, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVS F0, ret+48(FP)
+	RET
+
+// func sumFloat32NEON(x []float32) float32
+TEXT ·sumFloat32NEON(SB), NOSPLIT, $0-28
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop16:
+	CMP    $16, R1
+	BLT    loop4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	WORD $0x4e24d400 // fadd v0.4s, v0.4s, v4.4s
+	WORD $0x4e25d421 // fadd v1.4s, v1.4s, v5.4s
+	WORD $0x4e26d442 // fadd v2.4s, v2.4s, v6.4s
+	WORD $0x4e27d463 // fadd v3.4s, v3.4s, v7.4s
+	SUB    $16, R1
+	B      loop16
+
+loop4:
+	CMP    $4, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.S4]
+	WORD $0x4e24d400 // fadd v0.4s, v0.4s, v4.4s
+	SUB    $4, R1
+	B      loop4
+
+reduce:
+	WORD $0x4e21d400 // fadd v0.4s, v0.4s, v1.4s
+	WORD $0x4e23d442 // fadd v2.4s, v2.4s, v3.4s
+	WORD $0x4e22d400 // fadd v0.4s, v0.4s, v2.4s
+	WORD $0x6e20d400 // faddp v0.4s, v0.4s, v0.4s
+	WORD $0x7e30d800 // faddp s0, v0.2s
+
+tail:
+	CBZ     R1, done
+	FMOVS.P 4(R0), F4
+	FADDS   F4, F0, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVS F0, ret+24(FP)
+	RET
+
+// func l1Float32NEON(x, y []float32) float32
+TEXT ·l1Float32NEON(SB), NOSPLIT, $0-52
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop16:
+	CMP    $16, R1
+	BLT    loop4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R2), [V8.S4, V9.S4, V10.S4, V11.S4]
+	WORD $0x6ea8d484 // fabd v4.4s, v4.4s, v8.4s
+	WORD $0x6ea9d4a5 // fabd v5.4s, v5.4s, v9.4s
+	WORD $0x6eaad4c6 // fabd v6.4s, v6.4s, v10.4s
+	WORD $0x6eabd4e7 // fabd v7.4s, v7.4s, v11.4s
+	WORD $0x4e24d400 // fadd v0.4s, v0.4s, v4.4s
+	WORD $0x4e25d421 // fadd v1.4s, v1.4s, v5.4s
+	WORD $0x4e26d442 // fadd v2.4s, v2.4s, v6.4s
+	WORD $0x4e27d463 // fadd v3.4s, v3.4s, v7.4s
+	SUB    $16, R1
+	B      loop16
+
+loop4:
+	CMP    $4, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R2), [V8.S4]
+	WORD $0x6ea8d484 // fabd v4.4s, v4.4s, v8.4s
+	WORD $0x4e24d400 // fadd v0.4s, v0.4s, v4.4s
+	SUB    $4, R1
+	B      loop4
+
+reduce:
+	WORD $0x4e21d400 // fadd v0.4s, v0.4s, v1.4s
+	WORD $0x4e23d442 // fadd v2.4s, v2.4s, v3.4s
+	WORD $0x4e22d400 // fadd v0.4s, v0.4s, v2.4s
+	WORD $0x6e20d400 // faddp v0.4s, v0.4s, v0.4s
+	WORD $0x7e30d800 // faddp s0, v0.2s
+
+tail:
+	CBZ     R1, done
+	FMOVS.P 4(R0), F4
+	FMOVS.P 4(R2), F8
+	FSUBS   F8, F4, F4
+	FABSS   F4, F4
+	FADDS   F4, F0, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVS F0, ret+48(FP)
+	RET
+
+// func l2Float32NEON(x, y []float32) float32
+TEXT ·l2Float32NEON(SB), NOSPLIT, $0-52
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop16:
+	CMP    $16, R1
+	BLT    loop4
+	VLD1.P 64(R0), [V4.S4, V5.S4, V6.S4, V7.S4]
+	VLD1.P 64(R2), [V8.S4, V9.S4, V10.S4, V11.S4]
+	WORD $0x4ea8d484 // fsub v4.4s, v4.4s, v8.4s
+	WORD $0x4ea9d4a5 // fsub v5.4s, v5.4s, v9.4s
+	WORD $0x4eaad4c6 // fsub v6.4s, v6.4s, v10.4s
+	WORD $0x4eabd4e7 // fsub v7.4s, v7.4s, v11.4s
+	VFMLA  V4.S4, V4.S4, V0.S4
+	VFMLA  V5.S4, V5.S4, V1.S4
+	VFMLA  V6.S4, V6.S4, V2.S4
+	VFMLA  V7.S4, V7.S4, V3.S4
+	SUB    $16, R1
+	B      loop16
+
+loop4:
+	CMP    $4, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R2), [V8.S4]
+	WORD $0x4ea8d484 // fsub v4.4s, v4.4s, v8.4s
+	VFMLA  V4.S4, V4.S4, V0.S4
+	SUB    $4, R1
+	B      loop4
+
+reduce:
+	WORD $0x4e21d400 // fadd v0.4s, v0.4s, v1.4s
+	WORD $0x4e23d442 // fadd v2.4s, v2.4s, v3.4s
+	WORD $0x4e22d400 // fadd v0.4s, v0.4s, v2.4s
+	WORD $0x6e20d400 // faddp v0.4s, v0.4s, v0.4s
+	WORD $0x7e30d800 // faddp s0, v0.2s
+
+tail:
+	CBZ     R1, done
+	FMOVS.P 4(R0), F4
+	FMOVS.P 4(R2), F8
+	FSUBS   F8, F4, F4
+	FMADDS  F4, F0, F4, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVS F0, ret+48(FP)
+	RET
+
+// func momentsFloat32NEON(x, y []float32, mx, my float32) (xy, xx, yy float32)
+TEXT ·momentsFloat32NEON(SB), NOSPLIT, $0-68
+	MOVD  x_base+0(FP), R0
+	MOVD  x_len+8(FP), R1
+	MOVD  y_base+24(FP), R2
+	FMOVS mx+48(FP), F13
+	FMOVS my+52(FP), F14
+	VDUP  V13.S[0], V13.S4
+	VDUP  V14.S[0], V14.S4
+	VEOR  V0.B16, V0.B16, V0.B16
+	VEOR  V1.B16, V1.B16, V1.B16
+	VEOR  V2.B16, V2.B16, V2.B16
+	VEOR  V16.B16, V16.B16, V16.B16
+	VEOR  V17.B16, V17.B16, V17.B16
+	VEOR  V18.B16, V18.B16, V18.B16
+
+loop8:
+	CMP    $8, R1
+	BLT    loop4
+	VLD1.P 32(R0), [V4.S4, V5.S4]
+	VLD1.P 32(R2), [V8.S4, V9.S4]
+	WORD $0x4eadd484 // fsub v4.4s, v4.4s, v13.4s
+	WORD $0x4eadd4a5 // fsub v5.4s, v5.4s, v13.4s
+	WORD $0x4eaed508 // fsub v8.4s, v8.4s, v14.4s
+	WORD $0x4eaed529 // fsub v9.4s, v9.4s, v14.4s
+	VFMLA  V4.S4, V8.S4, V0.S4
+	VFMLA  V4.S4, V4.S4, V1.S4
+	VFMLA  V8.S4, V8.S4, V2.S4
+	VFMLA  V5.S4, V9.S4, V16.S4
+	VFMLA  V5.S4, V5.S4, V17.S4
+	VFMLA  V9.S4, V9.S4, V18.S4
+	SUB    $8, R1
+	B      loop8
+
+loop4:
+	CMP    $4, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R2), [V8.S4]
+	WORD $0x4eadd484 // fsub v4.4s, v4.4s, v13.4s
+	WORD $0x4eaed508 // fsub v8.4s, v8.4s, v14.4s
+	VFMLA  V4.S4, V8.S4, V0.S4
+	VFMLA  V4.S4, V4.S4, V1.S4
+	VFMLA  V8.S4, V8.S4, V2.S4
+	SUB    $4, R1
+	B      loop4
+
+reduce:
+	WORD $0x4e30d400 // fadd v0.4s, v0.4s, v16.4s
+	WORD $0x4e31d421 // fadd v1.4s, v1.4s, v17.4s
+	WORD $0x4e32d442 // fadd v2.4s, v2.4s, v18.4s
+	WORD $0x6e20d400 // faddp v0.4s, v0.4s, v0.4s
+	WORD $0x6e21d421 // faddp v1.4s, v1.4s, v1.4s
+	WORD $0x6e22d442 // faddp v2.4s, v2.4s, v2.4s
+	WORD $0x7e30d800 // faddp s0, v0.2s
+	WORD $0x7e30d821 // faddp s1, v1.2s
+	WORD $0x7e30d842 // faddp s2, v2.2s
+
+tail:
+	CBZ     R1, done
+	FMOVS.P 4(R0), F4
+	FMOVS.P 4(R2), F8
+	FSUBS   F13, F4, F4
+	FSUBS   F14, F8, F8
+	FMADDS  F8, F0, F4, F0
+	FMADDS  F4, F1, F4, F1
+	FMADDS  F8, F2, F8, F2
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVS F0, xy+56(FP)
+	FMOVS F1, xx+60(FP)
+	FMOVS F2, yy+64(FP)
+	RET
+
+// func minMaxFloat32NEON(x, y []float32) (mins, maxs float32)
+TEXT ·minMaxFloat32NEON(SB), NOSPLIT, $0-56
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V16.B16, V16.B16, V16.B16
+	VEOR V17.B16, V17.B16, V17.B16
+
+loop8:
+	CMP    $8, R1
+	BLT    loop4
+	VLD1.P 32(R0), [V4.S4, V5.S4]
+	VLD1.P 32(R2), [V8.S4, V9.S4]
+	WORD $0x4ea8f486 // fmin v6.4s, v4.4s, v8.4s
+	WORD $0x4e28f484 // fmax v4.4s, v4.4s, v8.4s
+	WORD $0x4ea9f4a7 // fmin v7.4s, v5.4s, v9.4s
+	WORD $0x4e29f4a5 // fmax v5.4s, v5.4s, v9.4s
+	WORD $0x4e26d400 // fadd v0.4s, v0.4s, v6.4s
+	WORD $0x4e24d421 // fadd v1.4s, v1.4s, v4.4s
+	WORD $0x4e27d610 // fadd v16.4s, v16.4s, v7.4s
+	WORD $0x4e25d631 // fadd v17.4s, v17.4s, v5.4s
+	SUB    $8, R1
+	B      loop8
+
+loop4:
+	CMP    $4, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.S4]
+	VLD1.P 16(R2), [V8.S4]
+	WORD $0x4ea8f486 // fmin v6.4s, v4.4s, v8.4s
+	WORD $0x4e28f484 // fmax v4.4s, v4.4s, v8.4s
+	WORD $0x4e26d400 // fadd v0.4s, v0.4s, v6.4s
+	WORD $0x4e24d421 // fadd v1.4s, v1.4s, v4.4s
+	SUB    $4, R1
+	B      loop4
+
+reduce:
+	WORD $0x4e30d400 // fadd v0.4s, v0.4s, v16.4s
+	WORD $0x4e31d421 // fadd v1.4s, v1.4s, v17.4s
+	WORD $0x6e20d400 // faddp v0.4s, v0.4s, v0.4s
+	WORD $0x6e21d421 // faddp v1.4s, v1.4s, v1.4s
+	WORD $0x7e30d800 // faddp s0, v0.2s
+	WORD $0x7e30d821 // faddp s1, v1.2s
+
+tail:
+	CBZ     R1, done
+	FMOVS.P 4(R0), F4
+	FMOVS.P 4(R2), F8
+	FMINS   F8, F4, F5
+	FMAXS   F8, F4, F4
+	FADDS   F5, F0, F0
+	FADDS   F4, F1, F1
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVS F0, mins+48(FP)
+	FMOVS F1, maxs+52(FP)
+	RET
+
+// func dotFloat64NEON(x, y []float64) float64
+TEXT ·dotFloat64NEON(SB), NOSPLIT, $0-56
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop8:
+	CMP    $8, R1
+	BLT    loop2
+	VLD1.P 64(R0), [V4.D2, V5.D2, V6.D2, V7.D2]
+	VLD1.P 64(R2), [V8.D2, V9.D2, V10.D2, V11.D2]
+	VFMLA  V4.D2, V8.D2, V0.D2
+	VFMLA  V5.D2, V9.D2, V1.D2
+	VFMLA  V6.D2, V10.D2, V2.D2
+	VFMLA  V7.D2, V11.D2, V3.D2
+	SUB    $8, R1
+	B      loop8
+
+loop2:
+	CMP    $2, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.D2]
+	VLD1.P 16(R2), [V8.D2]
+	VFMLA  V4.D2, V8.D2, V0.D2
+	SUB    $2, R1
+	B      loop2
+
+reduce:
+	WORD $0x4e61d400 // fadd v0.2d, v0.2d, v1.2d
+	WORD $0x4e63d442 // fadd v2.2d, v2.2d, v3.2d
+	WORD $0x4e62d400 // fadd v0.2d, v0.2d, v2.2d
+	WORD $0x7e70d800 // faddp d0, v0.2d
+
+tail:
+	CBZ     R1, done
+	FMOVD.P 8(R0), F4
+	FMOVD.P 8(R2), F8
+	FMADDD  F8, F0, F4, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVD F0, ret+48(FP)
+	RET
+
+// func sumFloat64NEON(x []float64) float64
+TEXT ·sumFloat64NEON(SB), NOSPLIT, $0-32
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop8:
+	CMP    $8, R1
+	BLT    loop2
+	VLD1.P 64(R0), [V4.D2, V5.D2, V6.D2, V7.D2]
+	WORD $0x4e64d400 // fadd v0.2d, v0.2d, v4.2d
+	WORD $0x4e65d421 // fadd v1.2d, v1.2d, v5.2d
+	WORD $0x4e66d442 // fadd v2.2d, v2.2d, v6.2d
+	WORD $0x4e67d463 // fadd v3.2d, v3.2d, v7.2d
+	SUB    $8, R1
+	B      loop8
+
+loop2:
+	CMP    $2, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.D2]
+	WORD $0x4e64d400 // fadd v0.2d, v0.2d, v4.2d
+	SUB    $2, R1
+	B      loop2
+
+reduce:
+	WORD $0x4e61d400 // fadd v0.2d, v0.2d, v1.2d
+	WORD $0x4e63d442 // fadd v2.2d, v2.2d, v3.2d
+	WORD $0x4e62d400 // fadd v0.2d, v0.2d, v2.2d
+	WORD $0x7e70d800 // faddp d0, v0.2d
+
+tail:
+	CBZ     R1, done
+	FMOVD.P 8(R0), F4
+	FADDD   F4, F0, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVD F0, ret+24(FP)
+	RET
+
+// func l1Float64NEON(x, y []float64) float64
+TEXT ·l1Float64NEON(SB), NOSPLIT, $0-56
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop8:
+	CMP    $8, R1
+	BLT    loop2
+	VLD1.P 64(R0), [V4.D2, V5.D2, V6.D2, V7.D2]
+	VLD1.P 64(R2), [V8.D2, V9.D2, V10.D2, V11.D2]
+	WORD $0x6ee8d484 // fabd v4.2d, v4.2d, v8.2d
+	WORD $0x6ee9d4a5 // fabd v5.2d, v5.2d, v9.2d
+	WORD $0x6eead4c6 // fabd v6.2d, v6.2d, v10.2d
+	WORD $0x6eebd4e7 // fabd v7.2d, v7.2d, v11.2d
+	WORD $0x4e64d400 // fadd v0.2d, v0.2d, v4.2d
+	WORD $0x4e65d421 // fadd v1.2d, v1.2d, v5.2d
+	WORD $0x4e66d442 // fadd v2.2d, v2.2d, v6.2d
+	WORD $0x4e67d463 // fadd v3.2d, v3.2d, v7.2d
+	SUB    $8, R1
+	B      loop8
+
+loop2:
+	CMP    $2, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.D2]
+	VLD1.P 16(R2), [V8.D2]
+	WORD $0x6ee8d484 // fabd v4.2d, v4.2d, v8.2d
+	WORD $0x4e64d400 // fadd v0.2d, v0.2d, v4.2d
+	SUB    $2, R1
+	B      loop2
+
+reduce:
+	WORD $0x4e61d400 // fadd v0.2d, v0.2d, v1.2d
+	WORD $0x4e63d442 // fadd v2.2d, v2.2d, v3.2d
+	WORD $0x4e62d400 // fadd v0.2d, v0.2d, v2.2d
+	WORD $0x7e70d800 // faddp d0, v0.2d
+
+tail:
+	CBZ     R1, done
+	FMOVD.P 8(R0), F4
+	FMOVD.P 8(R2), F8
+	FSUBD   F8, F4, F4
+	FABSD   F4, F4
+	FADDD   F4, F0, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVD F0, ret+48(FP)
+	RET
+
+// func l2Float64NEON(x, y []float64) float64
+TEXT ·l2Float64NEON(SB), NOSPLIT, $0-56
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V2.B16, V2.B16, V2.B16
+	VEOR V3.B16, V3.B16, V3.B16
+
+loop8:
+	CMP    $8, R1
+	BLT    loop2
+	VLD1.P 64(R0), [V4.D2, V5.D2, V6.D2, V7.D2]
+	VLD1.P 64(R2), [V8.D2, V9.D2, V10.D2, V11.D2]
+	WORD $0x4ee8d484 // fsub v4.2d, v4.2d, v8.2d
+	WORD $0x4ee9d4a5 // fsub v5.2d, v5.2d, v9.2d
+	WORD $0x4eead4c6 // fsub v6.2d, v6.2d, v10.2d
+	WORD $0x4eebd4e7 // fsub v7.2d, v7.2d, v11.2d
+	VFMLA  V4.D2, V4.D2, V0.D2
+	VFMLA  V5.D2, V5.D2, V1.D2
+	VFMLA  V6.D2, V6.D2, V2.D2
+	VFMLA  V7.D2, V7.D2, V3.D2
+	SUB    $8, R1
+	B      loop8
+
+loop2:
+	CMP    $2, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.D2]
+	VLD1.P 16(R2), [V8.D2]
+	WORD $0x4ee8d484 // fsub v4.2d, v4.2d, v8.2d
+	VFMLA  V4.D2, V4.D2, V0.D2
+	SUB    $2, R1
+	B      loop2
+
+reduce:
+	WORD $0x4e61d400 // fadd v0.2d, v0.2d, v1.2d
+	WORD $0x4e63d442 // fadd v2.2d, v2.2d, v3.2d
+	WORD $0x4e62d400 // fadd v0.2d, v0.2d, v2.2d
+	WORD $0x7e70d800 // faddp d0, v0.2d
+
+tail:
+	CBZ     R1, done
+	FMOVD.P 8(R0), F4
+	FMOVD.P 8(R2), F8
+	FSUBD   F8, F4, F4
+	FMADDD  F4, F0, F4, F0
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVD F0, ret+48(FP)
+	RET
+
+// func momentsFloat64NEON(x, y []float64, mx, my float64) (xy, xx, yy float64)
+TEXT ·momentsFloat64NEON(SB), NOSPLIT, $0-88
+	MOVD  x_base+0(FP), R0
+	MOVD  x_len+8(FP), R1
+	MOVD  y_base+24(FP), R2
+	FMOVD mx+48(FP), F13
+	FMOVD my+56(FP), F14
+	VDUP  V13.D[0], V13.D2
+	VDUP  V14.D[0], V14.D2
+	VEOR  V0.B16, V0.B16, V0.B16
+	VEOR  V1.B16, V1.B16, V1.B16
+	VEOR  V2.B16, V2.B16, V2.B16
+	VEOR  V16.B16, V16.B16, V16.B16
+	VEOR  V17.B16, V17.B16, V17.B16
+	VEOR  V18.B16, V18.B16, V18.B16
+
+loop4:
+	CMP    $4, R1
+	BLT    loop2
+	VLD1.P 32(R0), [V4.D2, V5.D2]
+	VLD1.P 32(R2), [V8.D2, V9.D2]
+	WORD $0x4eedd484 // fsub v4.2d, v4.2d, v13.2d
+	WORD $0x4eedd4a5 // fsub v5.2d, v5.2d, v13.2d
+	WORD $0x4eeed508 // fsub v8.2d, v8.2d, v14.2d
+	WORD $0x4eeed529 // fsub v9.2d, v9.2d, v14.2d
+	VFMLA  V4.D2, V8.D2, V0.D2
+	VFMLA  V4.D2, V4.D2, V1.D2
+	VFMLA  V8.D2, V8.D2, V2.D2
+	VFMLA  V5.D2, V9.D2, V16.D2
+	VFMLA  V5.D2, V5.D2, V17.D2
+	VFMLA  V9.D2, V9.D2, V18.D2
+	SUB    $4, R1
+	B      loop4
+
+loop2:
+	CMP    $2, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.D2]
+	VLD1.P 16(R2), [V8.D2]
+	WORD $0x4eedd484 // fsub v4.2d, v4.2d, v13.2d
+	WORD $0x4eeed508 // fsub v8.2d, v8.2d, v14.2d
+	VFMLA  V4.D2, V8.D2, V0.D2
+	VFMLA  V4.D2, V4.D2, V1.D2
+	VFMLA  V8.D2, V8.D2, V2.D2
+	SUB    $2, R1
+	B      loop2
+
+reduce:
+	WORD $0x4e70d400 // fadd v0.2d, v0.2d, v16.2d
+	WORD $0x4e71d421 // fadd v1.2d, v1.2d, v17.2d
+	WORD $0x4e72d442 // fadd v2.2d, v2.2d, v18.2d
+	WORD $0x7e70d800 // faddp d0, v0.2d
+	WORD $0x7e70d821 // faddp d1, v1.2d
+	WORD $0x7e70d842 // faddp d2, v2.2d
+
+tail:
+	CBZ     R1, done
+	FMOVD.P 8(R0), F4
+	FMOVD.P 8(R2), F8
+	FSUBD   F13, F4, F4
+	FSUBD   F14, F8, F8
+	FMADDD  F8, F0, F4, F0
+	FMADDD  F4, F1, F4, F1
+	FMADDD  F8, F2, F8, F2
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVD F0, xy+64(FP)
+	FMOVD F1, xx+72(FP)
+	FMOVD F2, yy+80(FP)
+	RET
+
+// func minMaxFloat64NEON(x, y []float64) (mins, maxs float64)
+TEXT ·minMaxFloat64NEON(SB), NOSPLIT, $0-64
+	MOVD x_base+0(FP), R0
+	MOVD x_len+8(FP), R1
+	MOVD y_base+24(FP), R2
+	VEOR V0.B16, V0.B16, V0.B16
+	VEOR V1.B16, V1.B16, V1.B16
+	VEOR V16.B16, V16.B16, V16.B16
+	VEOR V17.B16, V17.B16, V17.B16
+
+loop4:
+	CMP    $4, R1
+	BLT    loop2
+	VLD1.P 32(R0), [V4.D2, V5.D2]
+	VLD1.P 32(R2), [V8.D2, V9.D2]
+	WORD $0x4ee8f486 // fmin v6.2d, v4.2d, v8.2d
+	WORD $0x4e68f484 // fmax v4.2d, v4.2d, v8.2d
+	WORD $0x4ee9f4a7 // fmin v7.2d, v5.2d, v9.2d
+	WORD $0x4e69f4a5 // fmax v5.2d, v5.2d, v9.2d
+	WORD $0x4e66d400 // fadd v0.2d, v0.2d, v6.2d
+	WORD $0x4e64d421 // fadd v1.2d, v1.2d, v4.2d
+	WORD $0x4e67d610 // fadd v16.2d, v16.2d, v7.2d
+	WORD $0x4e65d631 // fadd v17.2d, v17.2d, v5.2d
+	SUB    $4, R1
+	B      loop4
+
+loop2:
+	CMP    $2, R1
+	BLT    reduce
+	VLD1.P 16(R0), [V4.D2]
+	VLD1.P 16(R2), [V8.D2]
+	WORD $0x4ee8f486 // fmin v6.2d, v4.2d, v8.2d
+	WORD $0x4e68f484 // fmax v4.2d, v4.2d, v8.2d
+	WORD $0x4e66d400 // fadd v0.2d, v0.2d, v6.2d
+	WORD $0x4e64d421 // fadd v1.2d, v1.2d, v4.2d
+	SUB    $2, R1
+	B      loop2
+
+reduce:
+	WORD $0x4e70d400 // fadd v0.2d, v0.2d, v16.2d
+	WORD $0x4e71d421 // fadd v1.2d, v1.2d, v17.2d
+	WORD $0x7e70d800 // faddp d0, v0.2d
+	WORD $0x7e70d821 // faddp d1, v1.2d
+
+tail:
+	CBZ     R1, done
+	FMOVD.P 8(R0), F4
+	FMOVD.P 8(R2), F8
+	FMIND   F8, F4, F5
+	FMAXD   F8, F4, F4
+	FADDD   F5, F0, F0
+	FADDD   F4, F1, F1
+	SUB     $1, R1
+	B       tail
+
+done:
+	FMOVD F0, mins+48(FP)
+	FMOVD F1, maxs+56(FP)
+	RET
@@ -0,0 +1,135 @@
+package semanticrouter
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// benchmarkDimensions are the dimensions of common embedding models.
+var benchmarkDimensions = []int{384, 768, 1536}
+
+// randomVector returns a vector of n random values in [-1, 1).
+func randomVector[T Float](rng *rand.Rand, n int) []T {
+	v := make([]T, n)
+	for i := range v {
+		v[i] = T(rng.Float64()*2 - 1)
+	}
+	return v
+}
+
+// testKernels tests that the kernels in use agree with the pure Go kernels
+// for every length up to 100 and for unaligned slices.
+func testKernels[T Float](t *testing.T, tolerance float64) {
+	rng := rand.New(rand.NewSource(1))
+	k, g := kernelsFor[T](), genericKernels[T]()
+	for n := 0; n <= 100; n++ {
+		for _, offset := range []int{0, 1} {
+			x := randomVector[T](rng, n+offset)[offset:]
+			y := randomVector[T](rng, n+offset)[offset:]
+			a := assert.New(t)
+			delta := tolerance * float64(n+1)
+			a.InDelta(g.dot(x, y), k.dot(x, y), delta, "dot %d", n)
+			a.InDelta(g.sum(x), k.sum(x), delta, "sum %d", n)
+			a.InDelta(g.l1(x, y), k.l1(x, y), delta, "l1 %d", n)
+			a.InDelta(g.l2(x, y), k.l2(x, y), delta, "l2 %d", n)
+			gxy, gxx, gyy := g.moments(x, y, 0.25, -0.5)
+			kxy, kxx, kyy := k.moments(x, y, 0.25, -0.5)
+			a.InDelta(gxy, kxy, delta, "moments xy %d", n)
+			a.InDelta(gxx, kxx, delta, "moments xx %d", n)
+			a.InDelta(gyy, kyy, delta, "moments yy %d", n)
+			gmin, gmax := g.minMax(x, y)
+			kmin, kmax := k.minMax(x, y)
+			a.InDelta(gmin, kmin, delta, "minMax mins %d", n)
+			a.InDelta(gmax, kmax, delta, "minMax maxs %d", n)
+		}
+	}
+}
+
+// TestKernels tests the kernels used for float32 and float64 embeddings.
+func TestKernels(t *testing.T) {
+	t.Run("float32", func(t *testing.T) { testKernels[float32](t, 1e-5) })
+	t.Run("float64", func(t *testing.T) { testKernels[float64](t, 1e-12) })
+}
+
+// TestKernelsAllocations tests that the similarity functions do not
+// allocate.
+func TestKernelsAllocations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x32, y32 := randomVector[float32](rng, 384), randomVector[float32](rng, 384)
+	x64, y64 := randomVector[float64](rng, 384), randomVector[float64](rng, 384)
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, c := range []biFuncCoefficient{
+			{handler64: dotProduct[float64], handler32: dotProduct[float32]},
+			{handler64: similarityDotMatrix[float64], handler32: similarityDotMatrix[float32]},
+			{handler64: euclideanDistance[float64], handler32: euclideanDistance[float32]},
+			{handler64: manhattanDistance[float64], handler32: manhattanDistance[float32]},
+			{handler64: jaccardSimilarity[float64], handler32: jaccardSimilarity[float32]},
+			{handler64: pearsonCorrelation[float64], handler32: pearsonCorrelation[float32]},
+		} {
+			_, _ = c.handler32(x32, y32)
+			_, _ = c.handler64(x64, y64)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+// benchmarkKernels benchmarks the pure Go kernels against the kernels in use
+// at the dimensions of common embedding models.
+func benchmarkKernels[T Float](b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range benchmarkDimensions {
+		x, y := randomVector[T](rng, n), randomVector[T](rng, n)
+		for _, impl := range []struct {
+			name    string
+			kernels kernelSet[T]
+		}{
+			{"generic", genericKernels[T]()},
+			{"simd", *kernelsFor[T]()},
+		} {
+			k := impl.kernels
+			var sink T
+			for _, kernel := range []struct {
+				name string
+				fn   func()
+			}{
+				{"dot", func() { sink += k.dot(x, y) }},
+				{"cosine", func() { xy, _, _ := k.moments(x, y, 0, 0); sink += xy }},
+				{"l1", func() { sink += k.l1(x, y) }},
+				{"l2", func() { sink += k.l2(x, y) }},
+				{"pearson", func() {
+					mx, my := k.sum(x)/T(n), k.sum(y)/T(n)
+					xy, _, _ := k.moments(x, y, mx, my)
+					sink += xy
+				}},
+				{"jaccard", func() { mins, _ := k.minMax(x, y); sink += mins }},
+			} {
+				b.Run(fmt.Sprintf("%s/%d/%s", kernel.name, n, impl.name), func(b *testing.B) {
+					b.ReportAllocs()
+					b.SetBytes(int64(2 * n * sizeOf[T]()))
+					for range b.N {
+						kernel.fn()
+					}
+				})
+			}
+			_ = sink
+		}
+	}
+}
+
+// sizeOf returns the size in bytes of a value of type T.
+func sizeOf[T Float]() int {
+	var zero T
+	if _, ok := any(zero).(float32); ok {
+		return 4
+	}
+	return 8
+}
+
+// BenchmarkKernelsFloat32 benchmarks the float32 kernels.
+func BenchmarkKernelsFloat32(b *testing.B) { benchmarkKernels[float32](b) }
+
+// BenchmarkKernelsFloat64 benchmarks the float64 kernels.
+func BenchmarkKernelsFloat64(b *testing.B) { benchmarkKernels[float64](b) }
@@ -0,0 +1,87 @@
+package semanticrouter
+
+import (
+	"context"
+	"fmt"
+)
+
+// Function is the signature of a function whose arguments are extracted from
+// the utterances matched to a dynamic route, in the format of the function
+// calling APIs of LLMs.
+type Function struct {
+	// Name is the name of the function.
+	Name string `json:"name"`
+	// Description describes what the function does.
+	Description string `json:"description,omitempty"`
+	// Parameters is the schema of the arguments of the function, an object
+	// whose properties are the parameters.
+	Parameters Schema `json:"parameters"`
+}
+
+// LLM extracts the arguments of a function from an utterance.
+type LLM interface {
+	// Arguments returns the arguments of the given function found in the
+	// given utterance, as JSON values keyed by parameter name.
+	Arguments(
+		ctx context.Context,
+		utterance string,
+		function Function,
+	) (map[string]any, error)
+}
+
+// WithLLM sets the LLM extracting the arguments of the functions of dynamic
+// routes.
+func WithLLM(llm LLM) Option {
+	return func(r *Router) {
+		r.llm = llm
+	}
+}
+
+// Resolve matches the given utterance, consulting the router's classifier if
+// the match is uncertain, and, if the best matching route is dynamic,
+// extracts the arguments of its function with the router's LLM and validates
+// them against the function's parameters.
+//
+// An ErrInvalidArguments is returned if the extraction fails or the
+// arguments do not conform to the schema.
+func (r *Router) Resolve(
+	ctx context.Context,
+	utterance string,
+) (MatchResult, error) {
+	result, err := r.match(ctx, utterance)
+	if err != nil {
+		return MatchResult{}, err
+	}
+	if result.Rule == nil {
+		err = r.classify(ctx, &result)
+		if err != nil {
+			return result, err
+		}
+	}
+	route := result.Route
+	if route == nil || route.Function == nil {
+		return result, nil
+	}
+	if r.llm == nil {
+		return result, fmt.Errorf(
+			"error resolving route %s: router has no LLM",
+			route.Name,
+		)
+	}
+	args, err := r.llm.Arguments(ctx, utterance, *route.Function)
+	if err == nil {
+		err = route.Function.Parameters.validate("$", args, r.patterns)
+	}
+	if err != nil {
+		return result, ErrInvalidArguments{
+			Message: fmt.Sprintf(
+				"error extracting arguments of %s",
+				route.Function.Name,
+			),
+			Route: route.Name,
+			Err:   err,
+		}
+	}
+	result.Arguments = args
+	return result, nil
+}
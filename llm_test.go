@@ -0,0 +1,127 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedLLM is an LLM returning fixed arguments.
+type fixedLLM struct {
+	args     map[string]any
+	err      error
+	calls    int
+	function semanticrouter.Function // function is the last function given.
+}
+
+// Arguments returns the fixed arguments.
+func (l *fixedLLM) Arguments(
+	_ context.Context,
+	_ string,
+	function semanticrouter.Function,
+) (map[string]any, error) {
+	l.calls++
+	l.function = function
+	return l.args, l.err
+}
+
+// TestSchemaValidate tests validating JSON values against schemas.
+func TestSchemaValidate(t *testing.T) {
+	var schema semanticrouter.Schema
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "pattern": "^[a-z]+$"},
+			"age": {"type": "integer", "minimum": 0, "maximum": 30},
+			"weight": {"type": "number"},
+			"species": {"enum": ["dog", "cat"]},
+			"vaccinated": {"type": "boolean"},
+			"toys": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["name", "species"]
+	}`), &schema))
+	for value, expected := range map[string]string{
+		`{"name": "rex", "species": "dog", "age": 3, "weight": 12.5, "vaccinated": true, "toys": ["ball"]}`: "",
+		`{"name": "rex"}`:                                "$: missing required property species",
+		`{"name": "Rex", "species": "dog"}`:              `$.name: "Rex" does not match "^[a-z]+$"`,
+		`{"name": "rex", "species": "bird"}`:             "$.species: bird is not one of [dog cat]",
+		`{"name": "rex", "species": "dog", "age": 3.5}`:  "$.age: expected integer, got float64",
+		`{"name": "rex", "species": "dog", "age": 31}`:   "$.age: 31 is greater than 30",
+		`{"name": "rex", "species": "dog", "toys": [1]}`: "$.toys[0]: expected string, got float64",
+		`[]`: "$: expected object, got []interface {}",
+	} {
+		var v any
+		require.NoError(t, json.Unmarshal([]byte(value), &v))
+		err := schema.Validate(v)
+		if expected == "" {
+			assert.NoError(t, err, value)
+			continue
+		}
+		assert.EqualError(t, err, expected, value)
+	}
+}
+
+// TestResolve tests extracting and validating the arguments of a dynamic
+// route.
+func TestResolve(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	noteworthy := NoteworthyRoutes
+	noteworthy.Function = &semanticrouter.Function{
+		Name: "book_appointment",
+		Parameters: semanticrouter.Schema{
+			Type:       "object",
+			Properties: map[string]*semanticrouter.Schema{"pet": {Type: "string"}},
+			Required:   []string{"pet"},
+		},
+	}
+	newRouter := func(opts ...semanticrouter.Option) *semanticrouter.Router {
+		router, err := semanticrouter.NewRouter(
+			[]semanticrouter.Route{noteworthy, ChitchatRoutes},
+			&batchEncoder{vectors: dispatchVectors},
+			memory.NewStore(),
+			append([]semanticrouter.Option{semanticrouter.WithSimilarityDotMatrix(1.0)}, opts...)...,
+		)
+		require.NoError(t, err)
+		return router
+	}
+
+	llm := &fixedLLM{args: map[string]any{"pet": "dog"}}
+	res, err := newRouter(semanticrouter.WithLLM(llm)).Resolve(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	a.Equal("noteworthy", res.Route.Name)
+	a.Equal(map[string]any{"pet": "dog"}, res.Arguments)
+
+	res, err = newRouter(semanticrouter.WithLLM(llm)).Resolve(ctx, "what is your favorite color?")
+	require.NoError(t, err)
+	a.Equal("chitchat", res.Route.Name)
+	a.Equal(1, llm.calls)
+
+	var invalid semanticrouter.ErrInvalidArguments
+	_, err = newRouter(semanticrouter.WithLLM(&fixedLLM{args: map[string]any{"pet": 1}})).
+		Resolve(ctx, "my dog is sneezing")
+	require.ErrorAs(t, err, &invalid)
+	a.Equal("noteworthy", invalid.Route)
+
+	errOffline := errors.New("offline")
+	_, err = newRouter(semanticrouter.WithLLM(&fixedLLM{err: errOffline})).
+		Resolve(ctx, "my dog is sneezing")
+	a.ErrorIs(err, errOffline)
+
+	_, err = newRouter().Resolve(ctx, "my dog is sneezing")
+	a.Error(err)
+
+	noteworthy.Function.Parameters.Properties["pet"].Pattern = "("
+	_, err = semanticrouter.NewRouter(
+		[]semanticrouter.Route{noteworthy},
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+	)
+	a.ErrorContains(err, "error compiling parameters of function book_appointment of route noteworthy")
+}
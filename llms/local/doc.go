@@ -0,0 +1,9 @@
+// Package local provides a deterministic, dependency-free stand-in for an
+// LLM extracting the arguments of the functions of dynamic routes.
+//
+// Arguments are extracted with simple rules driven by the parameter schemas:
+// regular expressions, enum values mentioned in the utterance, numbers,
+// quoted strings and flag words. The same utterance always yields the same
+// arguments, which makes the stand-in useful for tests and offline
+// deployments of dynamic routes.
+package local
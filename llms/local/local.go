@@ -0,0 +1,200 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/conneroisu/semanticrouter-go"
+)
+
+var (
+	// numberPattern matches the numbers of an utterance.
+	numberPattern = regexp.MustCompile(`-?\d+(?:\.\d+)?`)
+	// quotedPattern matches the double or single quoted strings of an
+	// utterance.
+	quotedPattern = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+)
+
+// LLM extracts the arguments of a function from an utterance with rules.
+//
+// Parameters are extracted one after another, the required ones first in
+// order, then the others in alphabetical order. The value of a parameter is:
+//
+//   - the first submatch, or the match, of its pattern, if one was given with
+//     WithPattern;
+//   - otherwise the first of its enum values mentioned as a whole word,
+//     ignoring case, or all of them for an array of enum values;
+//   - otherwise the next number of the utterance for a number or integer;
+//   - otherwise the next quoted string of the utterance for a string;
+//   - otherwise true for a boolean whose name, with underscores read as
+//     spaces, is mentioned.
+//
+// Parameters without a value are left out.
+type LLM struct {
+	patterns map[string]*regexp.Regexp
+}
+
+// Option is a function that configures an LLM.
+type Option func(*LLM)
+
+// WithPattern sets the regular expression extracting the given parameter.
+func WithPattern(parameter string, pattern *regexp.Regexp) Option {
+	return func(l *LLM) {
+		l.patterns[parameter] = pattern
+	}
+}
+
+// New creates a new LLM.
+func New(opts ...Option) *LLM {
+	l := &LLM{patterns: make(map[string]*regexp.Regexp)}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// extraction is the state of the extraction of the arguments of an
+// utterance.
+type extraction struct {
+	utterance string
+	numbers   []string
+	quoted    []string
+}
+
+// Arguments returns the arguments of the given function found in the given
+// utterance.
+func (l *LLM) Arguments(
+	ctx context.Context,
+	utterance string,
+	function semanticrouter.Function,
+) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ex := &extraction{
+		utterance: utterance,
+		numbers:   numberPattern.FindAllString(utterance, -1),
+	}
+	for _, m := range quotedPattern.FindAllStringSubmatch(utterance, -1) {
+		ex.quoted = append(ex.quoted, m[1]+m[2])
+	}
+	args := make(map[string]any)
+	for _, name := range parameters(function.Parameters) {
+		schema := function.Parameters.Properties[name]
+		if schema == nil {
+			continue
+		}
+		value, ok := l.extract(ex, name, schema)
+		if ok {
+			args[name] = value
+		}
+	}
+	return args, nil
+}
+
+// parameters returns the names of the parameters of the given schema in
+// extraction order.
+func parameters(schema semanticrouter.Schema) []string {
+	names := slices.Clone(schema.Required)
+	var optional []string
+	for name := range schema.Properties {
+		if !slices.Contains(names, name) {
+			optional = append(optional, name)
+		}
+	}
+	slices.Sort(optional)
+	return append(names, optional...)
+}
+
+// extract returns the value of the given parameter, if found.
+func (l *LLM) extract(
+	ex *extraction,
+	name string,
+	schema *semanticrouter.Schema,
+) (any, bool) {
+	if pattern, ok := l.patterns[name]; ok {
+		m := pattern.FindStringSubmatch(ex.utterance)
+		if m == nil {
+			return nil, false
+		}
+		text := m[0]
+		if len(m) > 1 {
+			text = m[1]
+		}
+		return convert(text, schema.Type)
+	}
+	if len(schema.Enum) > 0 {
+		values := mentioned(ex.utterance, schema.Enum)
+		if len(values) == 0 {
+			return nil, false
+		}
+		return values[0], true
+	}
+	switch schema.Type {
+	case "array":
+		if schema.Items == nil || len(schema.Items.Enum) == 0 {
+			return nil, false
+		}
+		values := mentioned(ex.utterance, schema.Items.Enum)
+		return values, len(values) > 0
+	case "number", "integer":
+		if len(ex.numbers) == 0 {
+			return nil, false
+		}
+		text := ex.numbers[0]
+		ex.numbers = ex.numbers[1:]
+		return convert(text, schema.Type)
+	case "string":
+		if len(ex.quoted) == 0 {
+			return nil, false
+		}
+		text := ex.quoted[0]
+		ex.quoted = ex.quoted[1:]
+		return text, true
+	case "boolean":
+		word := strings.ReplaceAll(name, "_", " ")
+		return true, containsWord(ex.utterance, word)
+	}
+	return nil, false
+}
+
+// mentioned returns the given enum values mentioned in the utterance.
+func mentioned(utterance string, enum []any) []any {
+	var values []any
+	for _, value := range enum {
+		if containsWord(utterance, fmt.Sprint(value)) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// containsWord reports whether the utterance contains the given words as
+// whole words, ignoring case.
+func containsWord(utterance, words string) bool {
+	pattern := `(?i)\b` + regexp.QuoteMeta(words) + `\b`
+	return regexp.MustCompile(pattern).MatchString(utterance)
+}
+
+// convert converts the given text to a JSON value of the given type.
+func convert(text, typ string) (any, bool) {
+	switch typ {
+	case "number", "integer":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, false
+		}
+		return n, true
+	case "boolean":
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	}
+	return text, true
+}
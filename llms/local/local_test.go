@@ -0,0 +1,121 @@
+package local_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/lexical"
+	"github.com/conneroisu/semanticrouter-go/llms/local"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ semanticrouter.LLM = (*local.LLM)(nil)
+
+// forecast is the function of the weather route of the tests.
+var forecast = semanticrouter.Function{
+	Name:        "get_forecast",
+	Description: "Get the weather forecast of a city.",
+	Parameters: semanticrouter.Schema{
+		Type: "object",
+		Properties: map[string]*semanticrouter.Schema{
+			"city":   {Type: "string"},
+			"days":   {Type: "integer"},
+			"unit":   {Type: "string", Enum: []any{"celsius", "fahrenheit"}},
+			"hourly": {Type: "boolean"},
+			"alerts": {
+				Type:  "array",
+				Items: &semanticrouter.Schema{Type: "string", Enum: []any{"rain", "snow", "wind"}},
+			},
+		},
+		Required: []string{"city", "days"},
+	},
+}
+
+// TestArguments tests extracting arguments with rules.
+func TestArguments(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	llm := local.New(local.WithPattern("city", regexp.MustCompile(`in ([A-Z]\w+)`)))
+	args, err := llm.Arguments(
+		ctx,
+		"hourly forecast in Paris for 3 days in Celsius, warn me about snow or rain",
+		forecast,
+	)
+	require.NoError(t, err)
+	a.Equal(map[string]any{
+		"city":   "Paris",
+		"days":   3.0,
+		"unit":   "celsius",
+		"hourly": true,
+		"alerts": []any{"rain", "snow"},
+	}, args)
+	a.NoError(forecast.Parameters.Validate(args))
+
+	args, err = local.New().Arguments(ctx, `weather for "New York" over 2 days`, forecast)
+	require.NoError(t, err)
+	a.Equal(map[string]any{"city": "New York", "days": 2.0}, args)
+
+	args, err = local.New().Arguments(ctx, "what's the weather like?", forecast)
+	require.NoError(t, err)
+	a.Empty(args)
+	a.Error(forecast.Parameters.Validate(args))
+}
+
+// TestDynamicRoute tests resolving and dispatching a dynamic route.
+func TestDynamicRoute(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{
+			{
+				Name: "weather",
+				Utterances: []semanticrouter.Utterance{
+					{Utterance: "what is the weather forecast in London"},
+					{Utterance: "will it rain in Berlin tomorrow"},
+				},
+				Function: &forecast,
+				Handler: func(_ context.Context, res semanticrouter.MatchResult) (any, error) {
+					return res.Arguments["city"], nil
+				},
+			},
+			{
+				Name: "chitchat",
+				Utterances: []semanticrouter.Utterance{
+					{Utterance: "how are you doing today"},
+					{Utterance: "what is your favorite color"},
+				},
+			},
+		},
+		lexical.NewHashingEncoder(256),
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+		semanticrouter.WithLLM(local.New(
+			local.WithPattern("city", regexp.MustCompile(`in ([A-Z]\w+)`)),
+		)),
+	)
+	require.NoError(t, err)
+
+	res, err := router.Resolve(ctx, "what is the weather forecast in Oslo for 5 days")
+	require.NoError(t, err)
+	require.NotNil(t, res.Route)
+	a.Equal("weather", res.Route.Name)
+	a.Equal(map[string]any{"city": "Oslo", "days": 5.0}, res.Arguments)
+
+	out, err := router.Dispatch(ctx, "what is the weather forecast in Oslo for 5 days")
+	require.NoError(t, err)
+	a.Equal("Oslo", out)
+
+	_, err = router.Resolve(ctx, "what is the weather forecast in Oslo")
+	var invalid semanticrouter.ErrInvalidArguments
+	require.ErrorAs(t, err, &invalid)
+	a.Equal("weather", invalid.Route)
+
+	res, err = router.Resolve(ctx, "how are you doing today")
+	require.NoError(t, err)
+	a.Equal("chitchat", res.Route.Name)
+	a.Nil(res.Arguments)
+}
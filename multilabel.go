@@ -0,0 +1,145 @@
+package semanticrouter
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Span is a part of an utterance.
+type Span struct {
+	Start int    // Start is the byte offset of the start of the span.
+	End   int    // End is the byte offset of the end of the span.
+	Text  string // Text is the text of the span.
+}
+
+// Splitter splits an utterance into the spans routed separately by
+// Router.MatchAll.
+type Splitter func(utterance string) []Span
+
+// WithSplitter splits the utterances matched by MatchAll with the given
+// splitter, such as SplitClauses, and routes each span separately.
+func WithSplitter(splitter Splitter) Option {
+	return func(r *Router) {
+		r.splitter = splitter
+	}
+}
+
+// clauseBoundary matches the punctuation and coordinating conjunctions
+// separating clauses.
+var clauseBoundary = regexp.MustCompile(
+	`(?i)[.!?;]+\s*|,?\s+\b(?:and|but|then|also|plus)\b\s+`,
+)
+
+// SplitClauses splits an utterance into sentences and clauses at sentence
+// punctuation, semicolons and the conjunctions "and", "but", "then", "also"
+// and "plus", trimming the spaces around them.
+func SplitClauses(utterance string) []Span {
+	var spans []Span
+	add := func(start, end int) {
+		text := utterance[start:end]
+		trimmed := strings.TrimLeft(text, " \t\n,")
+		start += len(text) - len(trimmed)
+		trimmed = strings.TrimRight(trimmed, " \t\n,")
+		if trimmed == "" {
+			return
+		}
+		spans = append(spans, Span{
+			Start: start,
+			End:   start + len(trimmed),
+			Text:  trimmed,
+		})
+	}
+	start := 0
+	for _, loc := range clauseBoundary.FindAllStringIndex(utterance, -1) {
+		add(start, loc[0])
+		start = loc[1]
+	}
+	add(start, len(utterance))
+	return spans
+}
+
+// MatchAll returns every route whose score against the given utterance
+// clears its threshold: the route's Threshold if positive, otherwise the
+// given threshold.
+//
+// The routes with a rule matching a span score 1 and the span is not matched
+// semantically. If the router has a splitter, each span of the utterance is
+// routed separately and a route is returned once, with the span scoring best.
+// Otherwise the span of every result is the whole utterance. The results are
+// ordered by span, then by decreasing score.
+//
+// The hierarchy of the routes is not descended: only the top-level routes
+// are scored, by their own utterances, see MatchPath.
+func (r *Router) MatchAll(
+	ctx context.Context,
+	utterance string,
+	threshold float64,
+) ([]MatchResult, error) {
+	spans := []Span{{End: len(utterance), Text: utterance}}
+	if r.splitter != nil {
+		spans = r.splitter(utterance)
+	}
+	best := make(map[int]MatchResult)
+	for _, span := range spans {
+		rules := make(map[int]*Rule)
+		for i := range r.Routes {
+			if !accepts(ctx, &r.Routes[i], nil) {
+				continue
+			}
+			if rule := r.matchRule(&r.Routes[i], span.Text); rule != nil {
+				rules[i] = rule
+			}
+		}
+		scores := emptyScores(len(r.Routes))
+		matched := make([]*Utterance, len(r.Routes))
+		for i := range rules {
+			scores[i] = 1
+		}
+		if len(rules) == 0 {
+			var err error
+			scores, matched, err = r.scores(ctx, span.Text)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i, score := range scores {
+			limit := threshold
+			if r.Routes[i].Threshold > 0 {
+				limit = r.Routes[i].Threshold
+			}
+			if score < limit {
+				continue
+			}
+			if res, ok := best[i]; ok && res.Score >= score {
+				continue
+			}
+			best[i] = MatchResult{
+				Utterance: utterance,
+				Route:     &r.Routes[i],
+				Score:     score,
+				Matched:   matched[i],
+				Span:      span,
+				Rule:      rules[i],
+			}
+		}
+	}
+	results := make([]MatchResult, 0, len(best))
+	for _, res := range best {
+		results = append(results, res)
+	}
+	slices.SortFunc(results, func(a, b MatchResult) int {
+		if a.Span.Start != b.Span.Start {
+			return a.Span.Start - b.Span.Start
+		}
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Route.Name, b.Route.Name)
+	})
+	return results, nil
+}
@@ -0,0 +1,84 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSplitClauses tests splitting utterances into clauses.
+func TestSplitClauses(t *testing.T) {
+	a := assert.New(t)
+	utterance := "Cancel my order and update my address. Thanks!"
+	spans := semanticrouter.SplitClauses(utterance)
+	a.Equal([]semanticrouter.Span{
+		{Start: 0, End: 15, Text: "Cancel my order"},
+		{Start: 20, End: 37, Text: "update my address"},
+		{Start: 39, End: 45, Text: "Thanks"},
+	}, spans)
+	for _, span := range spans {
+		a.Equal(span.Text, utterance[span.Start:span.End])
+	}
+	a.Equal(
+		[]semanticrouter.Span{{End: 5, Text: "hello"}},
+		semanticrouter.SplitClauses("hello"),
+	)
+	a.Empty(semanticrouter.SplitClauses(" ... "))
+}
+
+// TestMatchAll tests returning every route clearing its threshold.
+func TestMatchAll(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float64{
+		"my dog is sneezing and what is your favorite color?":  {0.7, 0.7, 0},
+		"my dog is sneezing, and what is your favorite color?": {0.7, 0.7, 0},
+		"what is your favorite color":                          {0, 1, 0},
+	}
+	for k, v := range dispatchVectors {
+		vectors[k] = v
+	}
+	newRouter := func(opts ...semanticrouter.Option) *semanticrouter.Router {
+		chitchat := ChitchatRoutes
+		chitchat.Threshold = 0.95
+		router, err := semanticrouter.NewRouter(
+			[]semanticrouter.Route{NoteworthyRoutes, chitchat},
+			&batchEncoder{vectors: vectors},
+			memory.NewStore(),
+			append([]semanticrouter.Option{semanticrouter.WithSimilarityDotMatrix(1.0)}, opts...)...,
+		)
+		require.NoError(t, err)
+		return router
+	}
+
+	t.Run("whole", func(t *testing.T) {
+		a := assert.New(t)
+		utterance := "my dog is sneezing and what is your favorite color?"
+		results, err := newRouter().MatchAll(ctx, utterance, 0.5)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		a.Equal("noteworthy", results[0].Route.Name)
+		a.Equal(utterance, results[0].Span.Text)
+
+		results, err = newRouter().MatchAll(ctx, "how do I file my taxes?", 0.5)
+		require.NoError(t, err)
+		a.Empty(results)
+	})
+	t.Run("clauses", func(t *testing.T) {
+		a := assert.New(t)
+		utterance := "my dog is sneezing, and what is your favorite color?"
+		results, err := newRouter(semanticrouter.WithSplitter(semanticrouter.SplitClauses)).
+			MatchAll(ctx, utterance, 0.5)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		a.Equal("noteworthy", results[0].Route.Name)
+		a.Equal(semanticrouter.Span{Start: 0, End: 18, Text: "my dog is sneezing"}, results[0].Span)
+		a.Greater(results[0].Score, 0.9)
+		a.Equal("chitchat", results[1].Route.Name)
+		a.Equal("what is your favorite color", results[1].Span.Text)
+		a.Equal(utterance, results[1].Utterance)
+	})
+}
@@ -0,0 +1,28 @@
+package semanticrouter
+
+// WithNegativePenalty subtracts the best score of the negative utterances of
+// a route, times the given weight, from the route's score.
+//
+// Routes are not vetoed by their negative utterances unless WithNegativeVeto
+// is also given.
+func WithNegativePenalty(weight float64) Option {
+	return func(r *Router) {
+		r.negatives.penalty = weight
+		r.negativesSet = true
+	}
+}
+
+// WithNegativeVeto vetoes a route, so that it does not match, when the best
+// score of its negative utterances is above its score or within the given
+// margin of it.
+//
+// It is the default, with a zero margin, if WithNegativePenalty is not
+// given: a route does not match utterances closer to one of its negative
+// utterances than to its utterances.
+func WithNegativeVeto(margin float64) Option {
+	return func(r *Router) {
+		r.negatives.veto = true
+		r.negatives.margin = margin
+		r.negativesSet = true
+	}
+}
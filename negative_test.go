@@ -0,0 +1,74 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNegativeUtterances tests penalizing and vetoing routes with negative
+// utterances.
+func TestNegativeUtterances(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float64{
+		"how do I cancel my order":        {1, 0, 0},
+		"I don't want to cancel":          {0.8, 0.6, 0},
+		"keep my subscription":            {0, 1, 0},
+		"I don't want to cancel my order": {0.82, 0.57, 0},
+		"cancel it now":                   {0.99, 0.1, 0},
+	}
+	newRouter := func(negatives bool, opts ...semanticrouter.Option) (*semanticrouter.Router, semanticrouter.Store) {
+		cancel := semanticrouter.Route{
+			Name:       "cancel",
+			Utterances: []semanticrouter.Utterance{{Utterance: "how do I cancel my order"}},
+		}
+		if negatives {
+			cancel.NegativeUtterances = []semanticrouter.Utterance{{Utterance: "I don't want to cancel"}}
+		}
+		store := memory.NewStore()
+		router, err := semanticrouter.NewRouter(
+			[]semanticrouter.Route{cancel, {
+				Name:       "keep",
+				Utterances: []semanticrouter.Utterance{{Utterance: "keep my subscription"}},
+			}},
+			&batchEncoder{vectors: vectors},
+			store,
+			append([]semanticrouter.Option{semanticrouter.WithSimilarityDotMatrix(1.0)}, opts...)...,
+		)
+		require.NoError(t, err)
+		return router, store
+	}
+	for name, tt := range map[string]struct {
+		negatives bool
+		opts      []semanticrouter.Option
+		expected  string
+	}{
+		"without negatives": {false, nil, "cancel"},
+		"default veto":      {true, nil, "keep"},
+		"penalty":           {true, []semanticrouter.Option{semanticrouter.WithNegativePenalty(0.5)}, "keep"},
+		"small penalty":     {true, []semanticrouter.Option{semanticrouter.WithNegativePenalty(0.1)}, "cancel"},
+		"veto margin":       {true, []semanticrouter.Option{semanticrouter.WithNegativeVeto(0.1)}, "keep"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			router, store := newRouter(tt.negatives, tt.opts...)
+			route, _, err := router.Match(ctx, "I don't want to cancel my order")
+			require.NoError(t, err)
+			a.Equal(tt.expected, route.Name)
+
+			route, _, err = router.Match(ctx, "cancel it now")
+			require.NoError(t, err)
+			a.Equal("cancel", route.Name)
+
+			if tt.negatives {
+				embedding, err := store.Get(ctx, "I don't want to cancel")
+				require.NoError(t, err)
+				a.InDeltaSlice([]float64{0.8, 0.6, 0}, embedding, 1e-6)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package semanticrouter
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	// DefaultCentroids is the default number of centroids of each subspace
+	// of a ProductQuantizer, so that each subvector is encoded as one byte.
+	DefaultCentroids = 256
+	// DefaultIterations is the default number of k-means iterations used to
+	// train a ProductQuantizer.
+	DefaultIterations = 25
+)
+
+// ProductQuantizer quantizes embeddings by splitting them into subvectors
+// and encoding each subvector as the index of its nearest centroid in the
+// codebook of its subspace, one byte per subspace.
+//
+// A 768-dimension float32 embedding quantized with 96 subspaces takes 96
+// bytes instead of 3072. The codebooks are trained with k-means and can be
+// marshaled to JSON to be reused.
+type ProductQuantizer struct {
+	// Subspaces is the number of subvectors each embedding is split into.
+	Subspaces int `json:"subspaces"`
+	// Centroids is the number of centroids of each codebook, at most 256.
+	// Fewer are trained if there are fewer training embeddings.
+	Centroids int `json:"centroids"`
+	// Iterations is the number of k-means iterations used for training.
+	Iterations int `json:"iterations"`
+	// Seed seeds the choice of the initial centroids.
+	Seed int64 `json:"seed"`
+	// Dimensions is the dimension of the embeddings the codebooks were
+	// trained on.
+	Dimensions int `json:"dimensions,omitempty"`
+	// Codebooks holds the centroids of each subspace, one after another.
+	Codebooks [][]float32 `json:"codebooks,omitempty"`
+}
+
+// ProductQuantizerOption is a function that configures a ProductQuantizer.
+type ProductQuantizerOption func(*ProductQuantizer)
+
+// WithCentroids sets the number of centroids of each codebook.
+func WithCentroids(centroids int) ProductQuantizerOption {
+	return func(q *ProductQuantizer) {
+		q.Centroids = centroids
+	}
+}
+
+// WithIterations sets the number of k-means iterations used for training.
+func WithIterations(iterations int) ProductQuantizerOption {
+	return func(q *ProductQuantizer) {
+		q.Iterations = iterations
+	}
+}
+
+// WithSeed sets the seed of the choice of the initial centroids.
+func WithSeed(seed int64) ProductQuantizerOption {
+	return func(q *ProductQuantizer) {
+		q.Seed = seed
+	}
+}
+
+// NewProductQuantizer creates a new ProductQuantizer splitting embeddings
+// into the given number of subspaces.
+func NewProductQuantizer(
+	subspaces int,
+	opts ...ProductQuantizerOption,
+) *ProductQuantizer {
+	q := &ProductQuantizer{
+		Subspaces:  subspaces,
+		Centroids:  DefaultCentroids,
+		Iterations: DefaultIterations,
+		Seed:       1,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Fitted reports whether the codebooks have been trained.
+func (q *ProductQuantizer) Fitted() bool {
+	return q.Codebooks != nil
+}
+
+// subspace returns the bounds of the m-th subspace.
+func (q *ProductQuantizer) subspace(m int) (int, int) {
+	return m * q.Dimensions / q.Subspaces, (m + 1) * q.Dimensions / q.Subspaces
+}
+
+// Fit trains the codebooks on the given embeddings with k-means, unless they
+// have already been trained.
+func (q *ProductQuantizer) Fit(embeddings [][]float32) error {
+	if q.Fitted() {
+		return nil
+	}
+	if len(embeddings) == 0 {
+		return fmt.Errorf("error fitting product quantizer: no embeddings")
+	}
+	if q.Centroids < 1 || q.Centroids > 256 {
+		return fmt.Errorf(
+			"error fitting product quantizer: %d centroids, expected 1 to 256",
+			q.Centroids,
+		)
+	}
+	dimensions := len(embeddings[0])
+	if q.Subspaces < 1 || q.Subspaces > dimensions {
+		return fmt.Errorf(
+			"error fitting product quantizer: %d subspaces for dimension %d",
+			q.Subspaces,
+			dimensions,
+		)
+	}
+	for i, embedding := range embeddings {
+		if len(embedding) != dimensions {
+			return fmt.Errorf(
+				"error fitting product quantizer: embedding %d has dimension %d, expected %d",
+				i,
+				len(embedding),
+				dimensions,
+			)
+		}
+	}
+	q.Dimensions = dimensions
+	rng := rand.New(rand.NewSource(q.Seed))
+	codebooks := make([][]float32, q.Subspaces)
+	points := make([][]float32, len(embeddings))
+	for m := range codebooks {
+		start, end := q.subspace(m)
+		for i, embedding := range embeddings {
+			points[i] = embedding[start:end]
+		}
+		codebooks[m] = kmeans(points, min(q.Centroids, len(points)), q.Iterations, rng)
+	}
+	q.Codebooks = codebooks
+	return nil
+}
+
+// Encode returns the code of the given embedding, the index of the nearest
+// centroid of each subspace.
+func (q *ProductQuantizer) Encode(embedding []float32) ([]uint8, error) {
+	if !q.Fitted() {
+		return nil, fmt.Errorf("error encoding embedding: product quantizer is not fitted")
+	}
+	if len(embedding) != q.Dimensions {
+		return nil, fmt.Errorf(
+			"error encoding embedding: dimension %d, expected %d",
+			len(embedding),
+			q.Dimensions,
+		)
+	}
+	code := make([]uint8, q.Subspaces)
+	for m := range code {
+		start, end := q.subspace(m)
+		code[m] = uint8(nearest(embedding[start:end], q.Codebooks[m]))
+	}
+	return code, nil
+}
+
+// Decode returns the approximate embedding of the given code, the
+// concatenation of its centroids.
+func (q *ProductQuantizer) Decode(code []uint8) []float32 {
+	embedding := make([]float32, q.Dimensions)
+	for m, c := range code {
+		start, end := q.subspace(m)
+		width := end - start
+		copy(embedding[start:end], q.Codebooks[m][int(c)*width:(int(c)+1)*width])
+	}
+	return embedding
+}
+
+// Quantize encodes the given embeddings.
+func (q *ProductQuantizer) Quantize(embeddings [][]float32) (Codes, error) {
+	codes := &ProductCodes{
+		Quantizer: q,
+		Codes:     make([]uint8, 0, q.Subspaces*len(embeddings)),
+		Norms:     make([]float32, len(embeddings)),
+	}
+	for i, embedding := range embeddings {
+		code, err := q.Encode(embedding)
+		if err != nil {
+			return nil, err
+		}
+		codes.Codes = append(codes.Codes, code...)
+		decoded := q.Decode(code)
+		codes.Norms[i] = float32(math.Sqrt(float64(kernels32.dot(decoded, decoded))))
+	}
+	return codes, nil
+}
+
+// ProductCodes holds embeddings quantized by a ProductQuantizer.
+type ProductCodes struct {
+	// Quantizer is the quantizer of the codes.
+	Quantizer *ProductQuantizer
+	// Codes holds the code of each embedding, one after another.
+	Codes []uint8
+	// Norms holds the norm of the approximate embedding of each code.
+	Norms []float32
+}
+
+// Len returns the number of embeddings.
+func (c *ProductCodes) Len() int {
+	return len(c.Norms)
+}
+
+// Scores sets scores[i] to the cosine similarity of the query and the
+// approximate i-th embedding, computed from a table of the dot products of
+// the query subvectors with every centroid.
+func (c *ProductCodes) Scores(query []float32, scores []float32) error {
+	q := c.Quantizer
+	if len(query) != q.Dimensions {
+		return fmt.Errorf(
+			"error scoring query: dimension %d, expected %d",
+			len(query),
+			q.Dimensions,
+		)
+	}
+	tables := make([][]float32, q.Subspaces)
+	for m := range tables {
+		start, end := q.subspace(m)
+		width := end - start
+		codebook := q.Codebooks[m]
+		tables[m] = make([]float32, len(codebook)/width)
+		for k := range tables[m] {
+			tables[m][k] = kernels32.dot(query[start:end], codebook[k*width:(k+1)*width])
+		}
+	}
+	norm := float32(math.Sqrt(float64(kernels32.dot(query, query))))
+	for i := range c.Len() {
+		code := c.Codes[i*q.Subspaces : (i+1)*q.Subspaces]
+		var dot float32
+		for m, k := range code {
+			dot += tables[m][k]
+		}
+		if norm == 0 || c.Norms[i] == 0 {
+			scores[i] = 0
+			continue
+		}
+		scores[i] = dot / (norm * c.Norms[i])
+	}
+	return nil
+}
+
+// kmeans returns k centroids of the given points, flattened, chosen with
+// k-means++ and refined with the given number of Lloyd iterations.
+func kmeans(points [][]float32, k, iterations int, rng *rand.Rand) []float32 {
+	width := len(points[0])
+	centroids := make([]float32, 0, k*width)
+	centroids = append(centroids, points[rng.Intn(len(points))]...)
+	distances := make([]float64, len(points))
+	for len(centroids) < k*width {
+		var total float64
+		for i, p := range points {
+			c := nearest(p, centroids)
+			distances[i] = float64(kernels32.l2(p, centroids[c*width:(c+1)*width]))
+			total += distances[i]
+		}
+		next := rng.Intn(len(points))
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, d := range distances {
+				target -= d
+				if target <= 0 {
+					next = i
+					break
+				}
+			}
+		}
+		centroids = append(centroids, points[next]...)
+	}
+	assignments := make([]int, len(points))
+	for i := range assignments {
+		assignments[i] = -1
+	}
+	sums := make([]float64, k*width)
+	counts := make([]int, k)
+	for range iterations {
+		changed := false
+		for i, p := range points {
+			c := nearest(p, centroids)
+			if c != assignments[i] {
+				changed = true
+				assignments[i] = c
+			}
+		}
+		clear(sums)
+		clear(counts)
+		for i, p := range points {
+			c := assignments[i]
+			counts[c]++
+			for j, v := range p {
+				sums[c*width+j] += float64(v)
+			}
+		}
+		for c := range counts {
+			if counts[c] == 0 {
+				copy(centroids[c*width:(c+1)*width], points[rng.Intn(len(points))])
+				continue
+			}
+			for j := range width {
+				centroids[c*width+j] = float32(sums[c*width+j] / float64(counts[c]))
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return centroids
+}
+
+// nearest returns the index of the centroid nearest to the given point.
+func nearest(point, centroids []float32) int {
+	width := len(point)
+	best, bestDistance := 0, float32(math.Inf(1))
+	for c := 0; c*width < len(centroids); c++ {
+		d := kernels32.l2(point, centroids[c*width:(c+1)*width])
+		if d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,57 @@
+package semanticrouter
+
+// WithAmbiguityMargin sets the margin within which routes scoring below the
+// best route contend with it.
+//
+// Among the contenders, the route with the highest Priority wins, with its
+// own score. If several contenders share the highest priority, matching
+// fails with an ErrAmbiguousRoute listing them. Without a margin, only the
+// routes with the best score contend.
+func WithAmbiguityMargin(margin float64) Option {
+	return func(r *Router) {
+		r.margin = margin
+	}
+}
+
+// pickRoute returns the index of the route matching the given utterance
+// given the scores of the given routes, or -1 if none scores above zero,
+// resolving the contention of the routes scoring within the router's
+// ambiguity margin of the best score by their priorities.
+func (r *Router) pickRoute(
+	scores []float64,
+	routes []Route,
+	utterance string,
+) (int, float64, error) {
+	best, bestScore := pickBest(scores)
+	if best < 0 {
+		return best, bestScore, nil
+	}
+	var contenders []int
+	for i, score := range scores {
+		if score > 0 && score >= bestScore-r.margin {
+			contenders = append(contenders, i)
+		}
+	}
+	winner, tied := best, false
+	for _, i := range contenders {
+		switch {
+		case routes[i].Priority > routes[winner].Priority:
+			winner, tied = i, false
+		case i != winner && routes[i].Priority == routes[winner].Priority:
+			tied = true
+		}
+	}
+	if tied && r.margin > 0 {
+		err := ErrAmbiguousRoute{
+			Message:   "ambiguous route",
+			Utterance: utterance,
+		}
+		for _, i := range contenders {
+			if routes[i].Priority == routes[winner].Priority {
+				err.Routes = append(err.Routes, routes[i].Name)
+			}
+		}
+		return -1, bestScore, err
+	}
+	return winner, scores[winner], nil
+}
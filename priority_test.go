@@ -0,0 +1,63 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAmbiguityMargin tests resolving routes scoring within the ambiguity
+// margin by priority.
+func TestAmbiguityMargin(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float64{"it is complicated": {0.7, 0.71, 0}}
+	for k, v := range dispatchVectors {
+		vectors[k] = v
+	}
+	newRouter := func(priority int, opts ...semanticrouter.Option) *semanticrouter.Router {
+		chitchat := ChitchatRoutes
+		chitchat.Priority = priority
+		router, err := semanticrouter.NewRouter(
+			[]semanticrouter.Route{NoteworthyRoutes, chitchat},
+			&batchEncoder{vectors: vectors},
+			memory.NewStore(),
+			append([]semanticrouter.Option{semanticrouter.WithSimilarityDotMatrix(1.0)}, opts...)...,
+		)
+		require.NoError(t, err)
+		return router
+	}
+
+	t.Run("without margin", func(t *testing.T) {
+		route, _, err := newRouter(1).Match(ctx, "it is complicated")
+		require.NoError(t, err)
+		assert.Equal(t, "noteworthy", route.Name)
+	})
+	t.Run("priority", func(t *testing.T) {
+		a := assert.New(t)
+		router := newRouter(1, semanticrouter.WithAmbiguityMargin(0.1))
+		route, score, err := router.Match(ctx, "it is complicated")
+		require.NoError(t, err)
+		a.Equal("chitchat", route.Name)
+		a.InDelta(0.71, score, 0.01)
+
+		route, _, err = router.Match(ctx, "my dog is sneezing")
+		require.NoError(t, err)
+		a.Equal("noteworthy", route.Name)
+	})
+	t.Run("ambiguous", func(t *testing.T) {
+		a := assert.New(t)
+		router := newRouter(0, semanticrouter.WithAmbiguityMargin(0.1))
+		_, _, err := router.Match(ctx, "it is complicated")
+		var ambiguous semanticrouter.ErrAmbiguousRoute
+		require.ErrorAs(t, err, &ambiguous)
+		a.Equal([]string{"noteworthy", "chitchat"}, ambiguous.Routes)
+		a.Equal("it is complicated", ambiguous.Utterance)
+
+		_, err = router.Dispatch(ctx, "it is complicated")
+		a.ErrorAs(err, &ambiguous)
+	})
+}
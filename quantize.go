@@ -0,0 +1,147 @@
+package semanticrouter
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Quantizer compresses embeddings into codes that are smaller and faster to
+// scan than the embeddings themselves, at the cost of approximate scores.
+type Quantizer interface {
+	// Fit trains the quantizer on the given embeddings. It does nothing if
+	// the quantizer needs no training or has already been trained.
+	Fit(embeddings [][]float32) error
+	// Quantize compresses the given embeddings.
+	Quantize(embeddings [][]float32) (Codes, error)
+}
+
+// Codes is a set of embeddings compressed by a Quantizer.
+type Codes interface {
+	// Len returns the number of embeddings.
+	Len() int
+	// Scores sets scores[i] to the approximate cosine similarity of the
+	// query and the i-th embedding.
+	Scores(query []float32, scores []float32) error
+}
+
+// WithQuantization compresses the route embeddings with the given quantizer
+// when the router is created, fitting it on them if it needs training.
+//
+// Match scans the codes first and rescores the given number of best
+// candidates with the router's similarity functions on the full embeddings.
+// If candidates is zero, the approximate cosine similarity of the best code is
+// the score and the full embeddings are not kept in memory.
+func WithQuantization(quantizer Quantizer, candidates int) Option {
+	return func(r *Router) {
+		r.quantizer = quantizer
+		r.candidates = candidates
+	}
+}
+
+// Binarize returns the sign bits of the given embedding packed into 64-bit
+// words: bit i%64 of word i/64 is set if the i-th element is positive.
+func Binarize[T Float](embedding []T) []uint64 {
+	code := make([]uint64, (len(embedding)+63)/64)
+	for i, v := range embedding {
+		if v > 0 {
+			code[i/64] |= 1 << (i % 64)
+		}
+	}
+	return code
+}
+
+// Hamming returns the number of bits that differ between the given codes,
+// which must have the same length.
+func Hamming(a, b []uint64) int {
+	b = b[:len(a)]
+	var d0, d1, d2, d3 int
+	i := 0
+	for ; i <= len(a)-4; i += 4 {
+		d0 += bits.OnesCount64(a[i] ^ b[i])
+		d1 += bits.OnesCount64(a[i+1] ^ b[i+1])
+		d2 += bits.OnesCount64(a[i+2] ^ b[i+2])
+		d3 += bits.OnesCount64(a[i+3] ^ b[i+3])
+	}
+	for ; i < len(a); i++ {
+		d0 += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return d0 + d1 + d2 + d3
+}
+
+// BinaryQuantizer quantizes embeddings to their sign bits, one bit per
+// dimension, compressing float32 embeddings 32 times.
+//
+// The approximate cosine similarity of two codes is 1 - 2h/d, where h is
+// their Hamming distance and d the dimension of the embeddings. It works
+// best with embeddings centered around zero.
+type BinaryQuantizer struct{}
+
+// NewBinaryQuantizer creates a new BinaryQuantizer.
+func NewBinaryQuantizer() *BinaryQuantizer {
+	return &BinaryQuantizer{}
+}
+
+// Fit does nothing, as binary quantization needs no training.
+func (q *BinaryQuantizer) Fit([][]float32) error {
+	return nil
+}
+
+// Quantize packs the sign bits of the given embeddings, which must have the
+// same dimension.
+func (q *BinaryQuantizer) Quantize(embeddings [][]float32) (Codes, error) {
+	codes := &BinaryCodes{}
+	if len(embeddings) == 0 {
+		return codes, nil
+	}
+	codes.Dimensions = len(embeddings[0])
+	words := (codes.Dimensions + 63) / 64
+	codes.Bits = make([]uint64, 0, words*len(embeddings))
+	for i, embedding := range embeddings {
+		if len(embedding) != codes.Dimensions {
+			return nil, fmt.Errorf(
+				"error quantizing embedding %d: dimension %d, expected %d",
+				i,
+				len(embedding),
+				codes.Dimensions,
+			)
+		}
+		codes.Bits = append(codes.Bits, Binarize(embedding)...)
+	}
+	return codes, nil
+}
+
+// BinaryCodes holds embeddings quantized by a BinaryQuantizer.
+type BinaryCodes struct {
+	// Dimensions is the dimension of the quantized embeddings.
+	Dimensions int
+	// Bits holds the packed sign bits of each embedding, one after another.
+	Bits []uint64
+}
+
+// Len returns the number of embeddings.
+func (c *BinaryCodes) Len() int {
+	words := (c.Dimensions + 63) / 64
+	if words == 0 {
+		return 0
+	}
+	return len(c.Bits) / words
+}
+
+// Scores sets scores[i] to 1 - 2h/d, where h is the Hamming distance between
+// the sign bits of the query and of the i-th embedding.
+func (c *BinaryCodes) Scores(query []float32, scores []float32) error {
+	if len(query) != c.Dimensions {
+		return fmt.Errorf(
+			"error scoring query: dimension %d, expected %d",
+			len(query),
+			c.Dimensions,
+		)
+	}
+	words := (c.Dimensions + 63) / 64
+	code := Binarize(query)
+	scale := 2 / float32(c.Dimensions)
+	for i := range c.Len() {
+		scores[i] = 1 - scale*float32(Hamming(code, c.Bits[i*words:(i+1)*words]))
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ semanticrouter.Quantizer = (*semanticrouter.BinaryQuantizer)(nil)
+	_ semanticrouter.Quantizer = (*semanticrouter.ProductQuantizer)(nil)
+	_ semanticrouter.Codes     = (*semanticrouter.BinaryCodes)(nil)
+	_ semanticrouter.Codes     = (*semanticrouter.ProductCodes)(nil)
+)
+
+// clusteredEmbeddings returns n embeddings of the given dimension scattered
+// around a few random centers.
+func clusteredEmbeddings(rng *rand.Rand, n, dimensions int) [][]float32 {
+	centers := make([][]float32, 4)
+	for i := range centers {
+		centers[i] = make([]float32, dimensions)
+		for j := range centers[i] {
+			centers[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	embeddings := make([][]float32, n)
+	for i := range embeddings {
+		center := centers[i%len(centers)]
+		embeddings[i] = make([]float32, dimensions)
+		for j := range embeddings[i] {
+			embeddings[i][j] = center[j] + 0.05*float32(rng.NormFloat64())
+		}
+	}
+	return embeddings
+}
+
+// cosine returns the cosine similarity of two float32 vectors.
+func cosine(a, b []float32) float64 {
+	var dot, aa, bb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		aa += float64(a[i]) * float64(a[i])
+		bb += float64(b[i]) * float64(b[i])
+	}
+	return dot / math.Sqrt(aa*bb)
+}
+
+// TestBinarize tests packing sign bits and their Hamming distance.
+func TestBinarize(t *testing.T) {
+	a := assert.New(t)
+	a.Equal([]uint64{0b1001}, semanticrouter.Binarize([]float32{1, -1, 0, 2}))
+
+	x := make([]float64, 130)
+	x[0], x[64], x[129] = 1, 1, 1
+	code := semanticrouter.Binarize(x)
+	a.Equal([]uint64{1, 1, 2}, code)
+	a.Zero(semanticrouter.Hamming(code, code))
+	a.Equal(3, semanticrouter.Hamming(code, make([]uint64, 3)))
+}
+
+// TestBinaryQuantizer tests the approximate cosine similarity of binary
+// codes.
+func TestBinaryQuantizer(t *testing.T) {
+	a := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+	embeddings := clusteredEmbeddings(rng, 8, 130)
+	negated := make([]float32, 130)
+	for i, v := range embeddings[0] {
+		negated[i] = -v
+	}
+	q := semanticrouter.NewBinaryQuantizer()
+	require.NoError(t, q.Fit(embeddings))
+	codes, err := q.Quantize(embeddings)
+	require.NoError(t, err)
+	a.Equal(8, codes.Len())
+
+	scores := make([]float32, codes.Len())
+	require.NoError(t, codes.Scores(embeddings[0], scores))
+	a.Equal(float32(1), scores[0])
+	a.Greater(scores[4], float32(0.9))
+	require.NoError(t, codes.Scores(negated, scores))
+	a.Equal(float32(-1), scores[0])
+
+	a.Error(codes.Scores(make([]float32, 3), scores))
+	_, err = q.Quantize([][]float32{{1, 2}, {1}})
+	a.Error(err)
+}
+
+// TestProductQuantizer tests training, encoding and scoring with a product
+// quantizer, and reusing its marshaled codebooks.
+func TestProductQuantizer(t *testing.T) {
+	a := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+	embeddings := clusteredEmbeddings(rng, 200, 32)
+	q := semanticrouter.NewProductQuantizer(8, semanticrouter.WithCentroids(16))
+	_, err := q.Encode(embeddings[0])
+	a.Error(err)
+	require.NoError(t, q.Fit(embeddings))
+	a.True(q.Fitted())
+	a.Len(q.Codebooks, 8)
+	a.Len(q.Codebooks[0], 16*4)
+
+	code, err := q.Encode(embeddings[0])
+	require.NoError(t, err)
+	a.Len(code, 8)
+	a.Greater(cosine(embeddings[0], q.Decode(code)), 0.99)
+
+	codes, err := q.Quantize(embeddings)
+	require.NoError(t, err)
+	scores := make([]float32, codes.Len())
+	require.NoError(t, codes.Scores(embeddings[1], scores))
+	for i, score := range scores {
+		a.InDelta(cosine(embeddings[1], embeddings[i]), score, 0.05)
+	}
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	var loaded semanticrouter.ProductQuantizer
+	require.NoError(t, json.Unmarshal(data, &loaded))
+	require.NoError(t, loaded.Fit(clusteredEmbeddings(rng, 10, 32)))
+	reloaded, err := loaded.Encode(embeddings[0])
+	require.NoError(t, err)
+	a.Equal(code, reloaded)
+}
+
+// TestNewRouterQuantization tests matching against quantized route
+// embeddings, with and without rescoring.
+func TestNewRouterQuantization(t *testing.T) {
+	vectors := map[string][]float64{
+		"what is the best way to treat a dog with a cold?": {1, -0.1, -0.2},
+		"my cat has been limping, what should I do?":       {0.9, 0.1, -0.1},
+		"what is your favorite color?":                     {-0.1, 1, -0.3},
+		"what is your favorite animal?":                    {-0.2, 0.9, 0.1},
+		"my dog is sneezing":                               {0.95, 0.05, -0.1},
+	}
+	for name, tt := range map[string]struct {
+		quantizer  semanticrouter.Quantizer
+		candidates int
+	}{
+		"binary":          {semanticrouter.NewBinaryQuantizer(), 0},
+		"binary rescored": {semanticrouter.NewBinaryQuantizer(), 2},
+		"product":         {semanticrouter.NewProductQuantizer(3), 0},
+		"product rescore": {semanticrouter.NewProductQuantizer(3), 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			router, err := semanticrouter.NewRouter(
+				[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+				&batchEncoder{vectors: vectors},
+				memory.NewStore(),
+				semanticrouter.WithSimilarityDotMatrix(1.0),
+				semanticrouter.WithQuantization(tt.quantizer, tt.candidates),
+			)
+			require.NoError(t, err)
+			route, score, err := router.Match(context.Background(), "my dog is sneezing")
+			require.NoError(t, err)
+			a.Equal("noteworthy", route.Name)
+			a.Greater(score, 0.9)
+		})
+	}
+}
+
+// BenchmarkQuantizedScan benchmarks scanning 100,000 768-dimension
+// embeddings for the best cosine similarity with a query, exactly and with
+// binary codes.
+func BenchmarkQuantizedScan(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	embeddings := clusteredEmbeddings(rng, 100_000, 768)
+	query := embeddings[0]
+	b.Run("float32", func(b *testing.B) {
+		for range b.N {
+			best := -1.0
+			for _, embedding := range embeddings {
+				best = max(best, cosine(query, embedding))
+			}
+		}
+	})
+	b.Run("binary", func(b *testing.B) {
+		codes, err := semanticrouter.NewBinaryQuantizer().Quantize(embeddings)
+		require.NoError(b, err)
+		scores := make([]float32, codes.Len())
+		b.ResetTimer()
+		for range b.N {
+			_ = codes.Scores(query, scores)
+		}
+	})
+}
@@ -3,9 +3,7 @@ package semanticrouter
 import (
 	"context"
 	"fmt"
-
-	"golang.org/x/sync/errgroup"
-	"gonum.org/v1/gonum/mat"
+	"regexp"
 )
 
 // Router represents a semantic router.
@@ -13,16 +11,40 @@ import (
 // Router is a struct that contains a slice of Routes and an Encoder.
 //
 // Match can be called on a Router to find the best route for a given utterance.
+//
+// The embeddings of the routes' utterances are indexed once, by NewRouter, so
+// modifying Routes afterwards has no effect on matching.
 type Router struct {
 	Routes  []Route // Routes is a slice of Routes.
 	Encoder Encoder // Encoder is an Encoder that encodes utterances into vectors.
 	Storage Store   // Storage is a Store that stores the utterances.
 
-	biFuncCoeffs []biFuncCoefficient // biFuncCoefficients is a slice of biFuncCoefficients that represent the bi-function coefficients.
-	workers      int                 // workers is the number of workers to use for computing similarity scores.
+	biFuncCoeffs []biFuncCoefficient       // biFuncCoefficients is a slice of biFuncCoefficients that represent the bi-function coefficients.
+	workers      int                       // workers is the number of workers to use for computing similarity scores.
+	precision    Precision                 // precision is the element type embeddings are kept in.
+	index32      *index[float32]           // index32 holds the route embeddings with the Float32 precision.
+	index64      *index[float64]           // index64 holds the route embeddings with the Float64 precision.
+	quantizer    Quantizer                 // quantizer compresses the route embeddings, if set.
+	candidates   int                       // candidates is the number of quantized matches rescored.
+	fallback     Handler                   // fallback handles utterances no route handles.
+	middleware   []Middleware              // middleware wraps the dispatched handlers.
+	llm          LLM                       // llm extracts the arguments of dynamic routes.
+	classifier   Classifier                // classifier chooses the route of uncertain matches.
+	uncertain    float64                   // uncertain is the score below which the classifier is consulted.
+	splitter     Splitter                  // splitter splits the utterances matched by MatchAll.
+	tree32       *treeNode[float32]        // tree32 holds the route tree with the Float32 precision, if hierarchical.
+	tree64       *treeNode[float64]        // tree64 holds the route tree with the Float64 precision, if hierarchical.
+	negatives    negativePolicy            // negatives is how negative utterances affect the scores.
+	negativesSet bool                      // negativesSet reports whether negatives was configured.
+	rrf          bool                      // rrf reports whether lexical scores are fused by reciprocal rank fusion.
+	rrfConstant  float64                   // rrfConstant is the constant of reciprocal rank fusion.
+	patterns     map[string]*regexp.Regexp // patterns holds the compiled patterns of the routes' rules and functions.
+	margin       float64                   // margin is the ambiguity margin of the best route's score.
 }
 
 // WithWorkers sets the number of workers to use for computing similarity scores.
+//
+// The indexed utterances are split evenly between the workers.
 func WithWorkers(workers int) Option {
 	return func(r *Router) {
 		r.workers = workers
@@ -31,27 +53,77 @@ func WithWorkers(workers int) Option {
 
 // Route represents a route in the semantic router.
 //
-// It is a struct that contains a name and a slice of Utterances.
+// It is a struct that contains a name, a slice of Utterances and an optional
+// Handler invoked by Router.Dispatch.
+//
+// A route with a Function is dynamic: the arguments of the function are
+// extracted from the utterances matched to it by Router.Resolve.
 type Route struct {
 	Name       string      // Name is the name of the route.
 	Utterances []Utterance // Utterances is a slice of Utterances.
+	Handler    Handler     // Handler handles the utterances dispatched to the route.
+	Function   *Function   // Function is the function of a dynamic route.
+	Threshold  float64     // Threshold is the minimum score of the route in MatchAll, if positive.
+	Children   []Route     // Children are the sub-routes of the route, matched by MatchPath.
+	// NegativeUtterances are utterances that must not match the route. Their
+	// similarity penalizes or vetoes the route, see WithNegativePenalty and
+	// WithNegativeVeto.
+	NegativeUtterances []Utterance
+	// Fusion overrides how the router fuses the BM25 and semantic scores of
+	// the route, see WithBM25.
+	Fusion *Fusion
+	// Rules route the utterances they match to the route before the
+	// semantic match, which is skipped.
+	Rules []Rule
+	// Priority is the priority of the route over the routes scoring within
+	// the router's ambiguity margin of it, see WithAmbiguityMargin.
+	Priority int
+	// Description is a free-form description of the route.
+	Description string
+	// Metadata holds free-form data about the route, such as the tool it
+	// calls or the team owning it.
+	Metadata map[string]any
+	// Tags label the route, such as "tenant=acme", see HasTags.
+	Tags []string
 }
 
 // biFuncCoefficient is an struct that represents a function and it's coefficient.
+//
+// It holds an instantiation of the function for each precision.
 type biFuncCoefficient struct {
-	handler     handler
+	name        string // name is the name of the function in configurations.
+	handler64   handler[float64]
+	handler32   handler[float32]
 	coefficient float64
+	lexical     bool // lexical reports whether the coefficient weighs BM25 scores instead of a handler.
 }
 
 // NewRouter creates a new semantic router.
+//
+// The embeddings of the utterances of the given routes are loaded from the
+// store, in the router's precision, and indexed for matching. The utterances
+// not yet present in the store are encoded and stored. If the encoder
+// implements QueryDocumentEncoder, they are encoded as documents, otherwise if
+// the precision is Float32 and it implements Float32Encoder, they are encoded
+// as float32 embeddings, otherwise if it implements BatchEncoder, they are
+// encoded with a single batch call.
+//
+// If any route has children, the utterances of all the descendants of the
+// routes are indexed too, as a tree matched by MatchPath, sharing the
+// embeddings of the top-level routes with their index.
 func NewRouter(
 	routes []Route,
 	encoder Encoder,
 	store Store,
 	opts ...Option,
 ) (router *Router, err error) {
-	router = &Router{}
-	routesLen := len(routes)
+	router = &Router{
+		Routes:   routes,
+		Encoder:  encoder,
+		Storage:  store,
+		workers:  1,
+		patterns: make(map[string]*regexp.Regexp),
+	}
 	ctx := context.Background()
 	if len(opts) == 0 {
 		opts = []Option{
@@ -66,33 +138,151 @@ func NewRouter(
 	for _, opt := range opts {
 		opt(router)
 	}
-	for i := 0; i < routesLen; i++ {
-		for _, utter := range routes[i].Utterances {
-			_, err = store.Get(ctx, utter.Utterance)
-			if err == nil {
-				continue
-			}
-			en, err := encoder.Encode(ctx, utter.Utterance)
-			if err != nil {
-				return nil, fmt.Errorf("error encoding utterance: %w", err)
-			}
-			utter.Embed = en
-			err = store.Set(ctx, utter)
+	if !router.negativesSet {
+		router.negatives = negativePolicy{veto: true}
+	}
+	err = compilePatterns(routes, router.patterns)
+	if err != nil {
+		return nil, err
+	}
+	switch router.precision {
+	case Float32:
+		router.index32, router.tree32, err = buildIndexes(ctx, router, routes, router.codec32())
+	case Float64:
+		router.index64, router.tree64, err = buildIndexes(ctx, router, routes, router.codec64())
+	default:
+		return nil, fmt.Errorf("unknown precision: %s", router.precision)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return router, nil
+}
+
+// prepareIndex configures how the given index of the given routes scores
+// them, according to the router's options.
+func prepareIndex[T Float](r *Router, idx *index[T], routes []Route) {
+	idx.negatives = r.negatives
+	idx.hybrid = newHybrid(r, idx, routes)
+}
+
+// codec64 returns how the router loads, encodes and stores float64
+// embeddings.
+func (r *Router) codec64() embeddingCodec[float64] {
+	return embeddingCodec[float64]{
+		load:   r.Storage.Get,
+		encode: r.encodeAll,
+		store: func(ctx context.Context, utter Utterance, em []float64) error {
+			utter.Embed = em
+			return r.Storage.Set(ctx, utter)
+		},
+		query: r.encodeQuery,
+		pick:  func(c biFuncCoefficient) handler[float64] { return c.handler64 },
+	}
+}
+
+// codec32 returns how the router loads, encodes and stores float32
+// embeddings, natively if its store implements Float32Store.
+func (r *Router) codec32() embeddingCodec[float32] {
+	codec := embeddingCodec[float32]{
+		load: func(ctx context.Context, key string) ([]float32, error) {
+			em, err := r.Storage.Get(ctx, key)
 			if err != nil {
-				return nil,
-					fmt.Errorf(
-						"error storing utterance: %s: %w",
-						utter.Utterance,
-						err,
-					)
+				return nil, err
 			}
+			return Convert[float32](em), nil
+		},
+		encode: r.encodeAll32,
+		store: func(ctx context.Context, utter Utterance, em []float32) error {
+			utter.Embed = Convert[float64](em)
+			return r.Storage.Set(ctx, utter)
+		},
+		query: r.encodeQuery32,
+		pick:  func(c biFuncCoefficient) handler[float32] { return c.handler32 },
+	}
+	if store, ok := r.Storage.(Float32Store); ok {
+		codec.load = store.GetFloat32
+		codec.store = store.SetFloat32
+	}
+	return codec
+}
+
+// encodeAll encodes the given utterances as documents, using a single batch
+// call if the router's encoder supports it.
+func (r *Router) encodeAll(
+	ctx context.Context,
+	texts []string,
+) ([][]float64, error) {
+	var batch func(context.Context, []string) ([][]float64, error)
+	switch enc := r.Encoder.(type) {
+	case QueryDocumentEncoder:
+		batch = enc.EncodeDocuments
+	case BatchEncoder:
+		batch = enc.EncodeBatch
+	}
+	if batch != nil {
+		embeddings, err := batch(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding utterances: %w", err)
+		}
+		err = checkEmbeddings(len(embeddings), len(texts))
+		if err != nil {
+			return nil, err
+		}
+		return embeddings, nil
+	}
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		en, err := r.Encoder.Encode(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding utterance: %w", err)
+		}
+		embeddings[i] = en
+	}
+	return embeddings, nil
+}
+
+// encodeAll32 encodes the given utterances as float32 documents, natively if
+// the router's encoder implements Float32Encoder but not
+// QueryDocumentEncoder.
+func (r *Router) encodeAll32(
+	ctx context.Context,
+	texts []string,
+) ([][]float32, error) {
+	_, qd := r.Encoder.(QueryDocumentEncoder)
+	if enc, ok := r.Encoder.(Float32Encoder); ok && !qd {
+		embeddings, err := enc.EncodeFloat32(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding utterances: %w", err)
+		}
+		err = checkEmbeddings(len(embeddings), len(texts))
+		if err != nil {
+			return nil, err
 		}
+		return embeddings, nil
+	}
+	embeddings, err := r.encodeAll(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]float32, len(embeddings))
+	for i, em := range embeddings {
+		result[i] = Convert[float32](em)
+	}
+	return result, nil
+}
+
+// checkEmbeddings returns an error if an encoder returned a number of
+// embeddings different from the number of utterances it was given.
+func checkEmbeddings(embeddings, utterances int) error {
+	if embeddings != utterances {
+		return fmt.Errorf(
+			"error encoding utterances: got %d embeddings for %d utterances",
+			embeddings,
+			utterances,
+		)
 	}
-	return &Router{
-		Routes:  routes,
-		Encoder: encoder,
-		Storage: store,
-	}, nil
+	return nil
 }
 
 // Match returns the route that matches the given utterance.
@@ -100,73 +290,132 @@ func NewRouter(
 // The score is the similarity score between the query vector and the index vector.
 //
 // If the given context is canceled, the context's error is returned if it is non-nil.
+//
+// If the routes have children, the last route of the path returned by
+// MatchPath is returned.
+//
+// If a rule of a route matches the utterance, the route is returned with a
+// score of 1 without encoding the utterance.
+//
+// If the given context has a filter, see WithFilter, only the routes and
+// utterances it accepts are matched.
+//
+// If the router has a classifier, see WithClassifier, it chooses the route
+// of uncertain matches.
 func (r *Router) Match(
 	ctx context.Context,
 	utterance string,
 ) (bestRoute *Route, bestScore float64, err error) {
-	encoding, err := r.Encoder.Encode(ctx, utterance)
+	result, err := r.match(ctx, utterance)
+	if err == nil && result.Rule == nil {
+		err = r.classify(ctx, &result)
+	}
+	return result.Route, result.Score, err
+}
+
+// match returns the result of matching the given utterance, with the rule or
+// the utterance of the route that matched it.
+func (r *Router) match(
+	ctx context.Context,
+	utterance string,
+) (MatchResult, error) {
+	result := MatchResult{Utterance: utterance}
+	if path, rule := r.matchRules(ctx, r.Routes, utterance); rule != nil {
+		result.Route, result.Score, result.Rule = path[len(path)-1], 1, rule
+		return result, nil
+	}
+	if r.tree32 != nil || r.tree64 != nil {
+		path, score, matched, err := r.matchPath(ctx, utterance)
+		result.Score = score
+		if err != nil || len(path) == 0 {
+			return result, err
+		}
+		result.Route, result.Matched = path[len(path)-1], matched
+		return result, nil
+	}
+	scores, matched, err := r.scores(ctx, utterance)
 	if err != nil {
-		return nil, 0.0, ErrEncoding{
+		return result, err
+	}
+	best, bestScore, err := r.pickRoute(scores, r.Routes, utterance)
+	result.Score = bestScore
+	if err != nil || best < 0 {
+		return result, err
+	}
+	result.Route, result.Matched = &r.Routes[best], matched[best]
+	return result, nil
+}
+
+// scores returns the best score of the utterances of each route against the
+// given utterance, with the utterance of each route scoring best.
+func (r *Router) scores(
+	ctx context.Context,
+	utterance string,
+) ([]float64, []*Utterance, error) {
+	switch {
+	case r.index32 != nil:
+		return queryScores(ctx, r, r.index32, r.codec32(), utterance)
+	case r.index64 != nil:
+		return queryScores(ctx, r, r.index64, r.codec64(), utterance)
+	default:
+		return nil, nil, fmt.Errorf("router was not created with NewRouter")
+	}
+}
+
+// queryScores encodes the given utterance as a query and returns the best
+// score of each route in the given index, with the utterance scoring it.
+func queryScores[T Float](
+	ctx context.Context,
+	r *Router,
+	idx *index[T],
+	codec embeddingCodec[T],
+	utterance string,
+) ([]float64, []*Utterance, error) {
+	encoding, err := codec.query(ctx, utterance)
+	if err != nil {
+		return nil, nil, ErrEncoding{
 			Message: fmt.Sprintf(
 				"error encoding utterance: %s",
 				utterance,
 			),
 		}
 	}
-	queryVec := mat.NewVecDense(len(encoding), encoding)
-	var simScore float64
-	var indexVec *mat.VecDense
-	for _, route := range r.Routes {
-		for _, ut := range route.Utterances {
-			em, err := r.Storage.Get(ctx, ut.Utterance)
-			if err != nil {
-				return nil, 0.0, ErrGetEmbedding{
-					Message: fmt.Sprintf(
-						"error getting embedding: %s",
-						ut.Utterance,
-					),
-				}
-			}
-			emLen := len(em)
-			if emLen != queryVec.Len() {
-				continue
-			}
-			indexVec = mat.NewVecDense(emLen, em)
-			simScore, err = r.computeScore(queryVec, indexVec)
-			if err != nil {
-				return nil, 0.0, err
-			}
-			if simScore > bestScore {
-				bestScore = simScore
-				bestRoute = &route
-			}
-		}
+	return idx.scores(ctx, encoding, utterance, r.biFuncCoeffs, codec.pick, r.workers, len(r.Routes))
+}
+
+// encodeQuery encodes the given utterance as a query.
+func (r *Router) encodeQuery(
+	ctx context.Context,
+	utterance string,
+) ([]float64, error) {
+	if enc, ok := r.Encoder.(QueryDocumentEncoder); ok {
+		return enc.EncodeQuery(ctx, utterance)
 	}
-	return bestRoute, bestScore, nil
+	return r.Encoder.Encode(ctx, utterance)
 }
 
-// computeScore computes the score for a given utterance and route.
-//
-// It takes a query vector and an index vector as input and returns a score.
-//
-// Additionally, it leverages the router's biFuncCoefficients to apply different
-// weighting factors to functions to get the similarity score.
-func (r *Router) computeScore(
-	queryVec *mat.VecDense,
-	indexVec *mat.VecDense,
-) (float64, error) {
-	score := 0.0
-	eg := errgroup.Group{}
-	eg.SetLimit(r.workers)
-	for _, fn := range r.biFuncCoeffs {
-		eg.Go(func() error {
-			interScore, err := fn.handler(queryVec, indexVec)
-			if err != nil {
-				return err
-			}
-			score += fn.coefficient * interScore
-			return nil
-		})
+// encodeQuery32 encodes the given utterance as a float32 query, natively if
+// the router's encoder implements Float32Encoder but not
+// QueryDocumentEncoder.
+func (r *Router) encodeQuery32(
+	ctx context.Context,
+	utterance string,
+) ([]float32, error) {
+	_, qd := r.Encoder.(QueryDocumentEncoder)
+	if enc, ok := r.Encoder.(Float32Encoder); ok && !qd {
+		embeddings, err := enc.EncodeFloat32(ctx, []string{utterance})
+		if err != nil {
+			return nil, err
+		}
+		err = checkEmbeddings(len(embeddings), 1)
+		if err != nil {
+			return nil, err
+		}
+		return embeddings[0], nil
+	}
+	em, err := r.encodeQuery(ctx, utterance)
+	if err != nil {
+		return nil, err
 	}
-	return score, eg.Wait()
+	return Convert[float32](em), nil
 }
@@ -0,0 +1,35 @@
+//go:build ollama
+
+package semanticrouter_test
+
+import (
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/encoders/ollama"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewRouterOllama tests the NewRouter function with an Ollama encoder.
+//
+// It needs an Ollama server serving the all-minilm model, and runs with the
+// ollama build tag:
+//
+//	go test -tags ollama ./...
+func TestNewRouterOllama(t *testing.T) {
+	a := assert.New(t)
+	client, err := api.ClientFromEnvironment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stor := memory.NewStore()
+	rout, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		ollama.NewEncoder(client, "all-minilm"),
+		stor,
+	)
+	a.NoError(err)
+	a.NotNil(rout)
+}
@@ -1,13 +1,14 @@
 package semanticrouter_test
 
 import (
+	"context"
+	"sync"
 	"testing"
 
 	"github.com/conneroisu/semanticrouter-go"
-	"github.com/conneroisu/semanticrouter-go/encoders/ollama"
 	"github.com/conneroisu/semanticrouter-go/stores/memory"
-	"github.com/ollama/ollama/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // NoteworthyRoutes represents a set of routes that are noteworthy.
@@ -30,20 +31,236 @@ var ChitchatRoutes = semanticrouter.Route{
 	},
 }
 
-// TestNewRouter tests the NewRouter function.
+// TestNewRouter tests the NewRouter function with its default options.
+//
+// TestNewRouterOllama runs the same test against an Ollama server with the
+// ollama build tag.
 func TestNewRouter(t *testing.T) {
 	a := assert.New(t)
-	client, err := api.ClientFromEnvironment()
-	if err != nil {
-		t.Fatal(err)
-	}
 	stor := memory.NewStore()
 	rout, err := semanticrouter.NewRouter(
 		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
-		ollama.NewEncoder(client, "all-minilm"),
+		&batchEncoder{vectors: dispatchVectors},
 		stor,
 	)
 	a.NoError(err)
 	a.NotNil(rout)
 
+	route, _, err := rout.Match(context.Background(), "my dog is sneezing")
+	a.NoError(err)
+	if a.NotNil(route) {
+		a.Equal("noteworthy", route.Name)
+	}
+}
+
+// batchEncoder is a fake encoder that embeds utterances as fixed vectors and
+// counts the calls made to it.
+type batchEncoder struct {
+	mu           sync.Mutex
+	vectors      map[string][]float64
+	encodeCalls  int
+	batchCalls   int
+	batchedTexts []string
+}
+
+// Encode returns the fixed vector of the given utterance.
+func (e *batchEncoder) Encode(_ context.Context, utterance string) ([]float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.encodeCalls++
+	return e.vectors[utterance], nil
+}
+
+// EncodeBatch returns the fixed vectors of the given utterances.
+func (e *batchEncoder) EncodeBatch(_ context.Context, utterances []string) ([][]float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batchCalls++
+	e.batchedTexts = append(e.batchedTexts, utterances...)
+	result := make([][]float64, len(utterances))
+	for i, utterance := range utterances {
+		result[i] = e.vectors[utterance]
+	}
+	return result, nil
+}
+
+// dispatchVectors are the embeddings of the test routes' utterances and of
+// the dispatched utterances.
+var dispatchVectors = map[string][]float64{
+	"what is the best way to treat a dog with a cold?": {1, 0, 0},
+	"my cat has been limping, what should I do?":       {0.9, 0.1, 0},
+	"what is your favorite color?":                     {0, 1, 0},
+	"what is your favorite animal?":                    {0, 0.9, 0.1},
+	"my dog is sneezing":                               {0.95, 0.05, 0},
+	"how do I file my taxes?":                          {0, 0, 0},
+}
+
+// newTestRouter creates a router of the given routes, embedding utterances
+// as dispatchVectors and scoring them with the dot product before applying
+// the given options.
+func newTestRouter(
+	t *testing.T,
+	routes []semanticrouter.Route,
+	opts ...semanticrouter.Option,
+) *semanticrouter.Router {
+	t.Helper()
+	opts = append([]semanticrouter.Option{
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	}, opts...)
+	router, err := semanticrouter.NewRouter(
+		routes,
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		opts...,
+	)
+	require.NoError(t, err)
+	return router
+}
+
+// TestNewRouterBatch tests that NewRouter encodes the utterances of all
+// routes with a single batch call and applies the given options.
+func TestNewRouterBatch(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	encoder := &batchEncoder{vectors: map[string][]float64{
+		"what is the best way to treat a dog with a cold?": {1, 0, 0},
+		"my cat has been limping, what should I do?":       {0.9, 0.1, 0},
+		"what is your favorite color?":                     {0, 1, 0},
+		"what is your favorite animal?":                    {0, 0.9, 0.1},
+		"my dog is sneezing":                               {0.95, 0.05, 0},
+	}}
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		encoder,
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	a.NoError(err)
+	a.Equal(1, encoder.batchCalls)
+	a.Zero(encoder.encodeCalls)
+	a.Len(encoder.batchedTexts, 4)
+
+	route, score, err := router.Match(ctx, "my dog is sneezing")
+	a.NoError(err)
+	a.NotNil(route)
+	a.Equal("noteworthy", route.Name)
+	a.Greater(score, 0.9)
+}
+
+// queryDocumentEncoder is a fake encoder that records whether texts were
+// encoded as queries or documents.
+type queryDocumentEncoder struct {
+	batchEncoder
+	queries   []string
+	documents []string
+}
+
+// EncodeQuery records and encodes the given query.
+func (e *queryDocumentEncoder) EncodeQuery(ctx context.Context, query string) ([]float64, error) {
+	e.queries = append(e.queries, query)
+	return e.vectors[query], nil
+}
+
+// EncodeDocuments records and encodes the given documents.
+func (e *queryDocumentEncoder) EncodeDocuments(ctx context.Context, documents []string) ([][]float64, error) {
+	e.documents = append(e.documents, documents...)
+	return e.EncodeBatch(ctx, documents)
+}
+
+// TestNewRouterQueryDocument tests that route utterances are encoded as
+// documents and matched utterances as queries.
+func TestNewRouterQueryDocument(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	encoder := &queryDocumentEncoder{batchEncoder: batchEncoder{vectors: map[string][]float64{
+		"what is the best way to treat a dog with a cold?": {1, 0, 0},
+		"my cat has been limping, what should I do?":       {0.9, 0.1, 0},
+		"what is your favorite color?":                     {0, 1, 0},
+		"what is your favorite animal?":                    {0, 0.9, 0.1},
+		"my dog is sneezing":                               {0.95, 0.05, 0},
+	}}}
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		encoder,
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	a.NoError(err)
+	a.Len(encoder.documents, 4)
+	a.Empty(encoder.queries)
+
+	route, _, err := router.Match(ctx, "my dog is sneezing")
+	a.NoError(err)
+	a.Equal("noteworthy", route.Name)
+	a.Equal([]string{"my dog is sneezing"}, encoder.queries)
+	a.Zero(encoder.encodeCalls)
+}
+
+// float32Encoder is a fake encoder that also encodes utterances as float32
+// embeddings.
+type float32Encoder struct {
+	batchEncoder
+	float32Calls int
+}
+
+// EncodeFloat32 returns the fixed vectors of the given utterances as float32
+// embeddings.
+func (e *float32Encoder) EncodeFloat32(_ context.Context, utterances []string) ([][]float32, error) {
+	e.float32Calls++
+	result := make([][]float32, len(utterances))
+	for i, utterance := range utterances {
+		result[i] = semanticrouter.Convert[float32](e.vectors[utterance])
+	}
+	return result, nil
+}
+
+// TestNewRouterPrecision tests that routers match the same route with
+// either precision, and that the Float32 precision uses float32 encoders and
+// stores natively.
+func TestNewRouterPrecision(t *testing.T) {
+	vectors := map[string][]float64{
+		"what is the best way to treat a dog with a cold?": {1, 0, 0},
+		"my cat has been limping, what should I do?":       {0.9, 0.1, 0},
+		"what is your favorite color?":                     {0, 1, 0},
+		"what is your favorite animal?":                    {0, 0.9, 0.1},
+		"my dog is sneezing":                               {0.95, 0.05, 0},
+	}
+	for _, precision := range []semanticrouter.Precision{
+		semanticrouter.Float32,
+		semanticrouter.Float64,
+	} {
+		t.Run(precision.String(), func(t *testing.T) {
+			a := assert.New(t)
+			ctx := context.Background()
+			encoder := &float32Encoder{batchEncoder: batchEncoder{vectors: vectors}}
+			store := memory.NewStore()
+			router, err := semanticrouter.NewRouter(
+				[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+				encoder,
+				store,
+				semanticrouter.WithSimilarityDotMatrix(1.0),
+				semanticrouter.WithPrecision(precision),
+				semanticrouter.WithWorkers(3),
+			)
+			a.NoError(err)
+
+			route, score, err := router.Match(ctx, "my dog is sneezing")
+			a.NoError(err)
+			a.Equal("noteworthy", route.Name)
+			a.InDelta(0.9986178293325098, score, 1e-6)
+
+			stored, err := store.GetFloat32(ctx, "what is your favorite color?")
+			a.NoError(err)
+			a.Equal([]float32{0, 1, 0}, stored)
+			if precision == semanticrouter.Float32 {
+				a.Equal(2, encoder.float32Calls)
+				a.Zero(encoder.batchCalls)
+				a.Zero(encoder.encodeCalls)
+			} else {
+				a.Zero(encoder.float32Calls)
+				a.Equal(1, encoder.batchCalls)
+				a.Equal(1, encoder.encodeCalls)
+			}
+		})
+	}
 }
@@ -0,0 +1,621 @@
+package semanticrouter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TenantLoader returns the routes of the router of a tenant, with the options
+// it is created with in addition to the options of the router set.
+type TenantLoader func(
+	ctx context.Context,
+	tenant string,
+) ([]Route, []Option, error)
+
+// SetOption is a function that configures a RouterSet.
+type SetOption func(*RouterSet)
+
+// WithTenantOptions creates the routers of every tenant with the given
+// options, before the options returned by the loader.
+//
+// A ProductQuantizer given with WithQuantization is copied for each tenant,
+// so that every tenant fits codebooks on its own embeddings unless the
+// quantizer was fitted beforehand, in which case its codebooks are shared.
+func WithTenantOptions(opts ...Option) SetOption {
+	return func(s *RouterSet) {
+		s.opts = append(s.opts, opts...)
+	}
+}
+
+// WithMaxTenants keeps at most the given number of routers loaded, evicting
+// the least recently used one when another tenant is loaded. It is
+// unlimited if not positive.
+func WithMaxTenants(tenants int) SetOption {
+	return func(s *RouterSet) {
+		s.capacity = tenants
+	}
+}
+
+// WithIdleTimeout evicts the routers of the tenants not used for the given
+// duration when another router is requested, or when EvictIdle is called.
+func WithIdleTimeout(timeout time.Duration) SetOption {
+	return func(s *RouterSet) {
+		s.idle = timeout
+	}
+}
+
+// RouterSet holds the routers of many tenants, loaded when first used, which
+// share an encoder, a store and an in-memory cache of embeddings but have
+// their own routes and options.
+//
+// The embeddings of a tenant are stored under its namespace: the key of an
+// utterance is prefixed by the tenant and a colon. Tenant names therefore
+// cannot contain a colon, which would let the keys of two tenants collide. The embeddings of the
+// texts of the loaded tenants are cached and shared between their routers,
+// so that a text common to many tenants is encoded and held in memory once.
+type RouterSet struct {
+	Encoder Encoder // Encoder is the encoder shared by the routers.
+	Storage Store   // Storage is the store shared by the routers.
+
+	loader   TenantLoader             // loader loads the routes of the tenants.
+	opts     []Option                 // opts are the options of every router.
+	capacity int                      // capacity is the maximum number of loaded routers, if positive.
+	idle     time.Duration            // idle is the duration after which unused routers are evicted, if positive.
+	cache    *embeddingCache          // cache holds the embeddings of the loaded tenants.
+	mu       sync.Mutex               // mu guards tenants.
+	tenants  map[string]*tenantRouter // tenants holds the loaded and loading tenants.
+}
+
+// tenantRouter is the router of a tenant, or its loading.
+type tenantRouter struct {
+	router *Router       // router is the router of the tenant, once loaded.
+	store  *tenantStore  // store is the namespaced store of the tenant.
+	err    error         // err is the error loading the router.
+	ready  chan struct{} // ready is closed when the router is loaded.
+	used   time.Time     // used is when the router was last requested.
+}
+
+// NewRouterSet creates a new router set loading the routes of its tenants
+// with the given loader, and sharing the given encoder and store.
+func NewRouterSet(
+	encoder Encoder,
+	store Store,
+	loader TenantLoader,
+	opts ...SetOption,
+) *RouterSet {
+	s := &RouterSet{
+		Encoder: encoder,
+		Storage: store,
+		loader:  loader,
+		cache:   newEmbeddingCache(),
+		tenants: make(map[string]*tenantRouter),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Router returns the router of the given tenant, loading it if it is not
+// loaded.
+//
+// Concurrent calls for a tenant being loaded wait for it to be loaded. The
+// router is loaded with a context detached from the cancellation of the
+// given one, so that a cancelled call does not fail the others; it only stops
+// waiting. If loading fails, the error is returned and the next call loads it
+// again.
+func (s *RouterSet) Router(ctx context.Context, name string) (*Router, error) {
+	if strings.Contains(name, ":") {
+		return nil, fmt.Errorf("invalid tenant %q: tenant names cannot contain a colon", name)
+	}
+	s.mu.Lock()
+	now := time.Now()
+	s.evictIdle(now)
+	t, ok := s.tenants[name]
+	if ok {
+		t.used = now
+		s.mu.Unlock()
+		return t.wait(ctx)
+	}
+	t = &tenantRouter{
+		store: &tenantStore{
+			store:  s.Storage,
+			cache:  s.cache,
+			prefix: name + ":",
+			keys:   make(map[string]bool),
+		},
+		ready: make(chan struct{}),
+		used:  now,
+	}
+	s.tenants[name] = t
+	s.evictLeastRecent()
+	s.mu.Unlock()
+
+	go func() {
+		t.router, t.err = s.load(context.WithoutCancel(ctx), name, t.store)
+		if t.err != nil {
+			t.store.release()
+			s.mu.Lock()
+			if s.tenants[name] == t {
+				delete(s.tenants, name)
+			}
+			s.mu.Unlock()
+		}
+		close(t.ready)
+	}()
+	return t.wait(ctx)
+}
+
+// load loads the routes of the given tenant and creates its router.
+func (s *RouterSet) load(
+	ctx context.Context,
+	name string,
+	store *tenantStore,
+) (*Router, error) {
+	routes, opts, err := s.loader(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("error loading tenant %s: %w", name, err)
+	}
+	store.utterances = utterancesByText(routes)
+	opts = append(slices.Clip(s.opts), opts...)
+	opts = append(opts, copyQuantizer)
+	router, err := NewRouter(routes, s.Encoder, store, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating router of tenant %s: %w", name, err)
+	}
+	return router, nil
+}
+
+// Match returns the route of the given tenant that matches the given
+// utterance, see Router.Match.
+func (s *RouterSet) Match(
+	ctx context.Context,
+	tenant string,
+	utterance string,
+) (*Route, float64, error) {
+	router, err := s.Router(ctx, tenant)
+	if err != nil {
+		return nil, 0.0, err
+	}
+	return router.Match(ctx, utterance)
+}
+
+// Resolve resolves the given utterance with the router of the given tenant,
+// see Router.Resolve.
+func (s *RouterSet) Resolve(
+	ctx context.Context,
+	tenant string,
+	utterance string,
+) (MatchResult, error) {
+	router, err := s.Router(ctx, tenant)
+	if err != nil {
+		return MatchResult{}, err
+	}
+	return router.Resolve(ctx, utterance)
+}
+
+// Tenants returns the names of the loaded tenants, in order.
+func (s *RouterSet) Tenants() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.tenants))
+	for name, t := range s.tenants {
+		if t.loaded() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Evict unloads the router of the given tenant, reporting whether it was
+// loaded. Its embeddings stay in the store.
+func (s *RouterSet) Evict(tenant string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tenants[tenant]
+	if !ok || !t.loaded() {
+		return false
+	}
+	s.evict(tenant, t)
+	return true
+}
+
+// EvictIdle unloads the routers of the tenants not used for the set's idle
+// timeout, see WithIdleTimeout, and returns their number.
+func (s *RouterSet) EvictIdle() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evictIdle(time.Now())
+}
+
+// Close unloads the routers of every tenant and closes the shared store.
+func (s *RouterSet) Close() error {
+	s.mu.Lock()
+	for name, t := range s.tenants {
+		if t.loaded() {
+			s.evict(name, t)
+		}
+	}
+	s.mu.Unlock()
+	return s.Storage.Close()
+}
+
+// evictIdle evicts the loaded tenants unused since the idle timeout before
+// the given time and returns their number. The set must be locked.
+func (s *RouterSet) evictIdle(now time.Time) int {
+	if s.idle <= 0 {
+		return 0
+	}
+	evicted := 0
+	for name, t := range s.tenants {
+		if t.loaded() && now.Sub(t.used) > s.idle {
+			s.evict(name, t)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// evictLeastRecent evicts the least recently used loaded tenants while the
+// set holds more tenants than its capacity. The set must be locked.
+func (s *RouterSet) evictLeastRecent() {
+	if s.capacity <= 0 {
+		return
+	}
+	for len(s.tenants) > s.capacity {
+		var oldest string
+		var last *tenantRouter
+		for name, t := range s.tenants {
+			if t.loaded() && (last == nil || t.used.Before(last.used)) {
+				oldest, last = name, t
+			}
+		}
+		if last == nil {
+			return
+		}
+		s.evict(oldest, last)
+	}
+}
+
+// evict removes the given loaded tenant and releases its cached embeddings.
+// The set must be locked.
+func (s *RouterSet) evict(name string, t *tenantRouter) {
+	delete(s.tenants, name)
+	t.store.release()
+}
+
+// copyQuantizer gives the router its own copy of its product quantizer, so
+// that the routers sharing a quantizer option do not fit the same codebooks.
+func copyQuantizer(r *Router) {
+	if q, ok := r.quantizer.(*ProductQuantizer); ok {
+		q := *q
+		r.quantizer = &q
+	}
+}
+
+// utterancesByText returns the utterances of the given routes and of their
+// descendants, by text.
+func utterancesByText(routes []Route) map[string]Utterance {
+	utterances := make(map[string]Utterance)
+	var walk func(routes []Route)
+	walk = func(routes []Route) {
+		for _, route := range routes {
+			for _, utter := range slices.Concat(route.Utterances, route.NegativeUtterances) {
+				utterances[utter.Utterance] = utter
+			}
+			walk(route.Children)
+		}
+	}
+	walk(routes)
+	return utterances
+}
+
+// wait waits for the router of the tenant to be loaded, or for the given
+// context to be done.
+func (t *tenantRouter) wait(ctx context.Context) (*Router, error) {
+	select {
+	case <-t.ready:
+		return t.router, t.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loaded reports whether the router of the tenant is loaded.
+func (t *tenantRouter) loaded() bool {
+	select {
+	case <-t.ready:
+		return t.err == nil
+	default:
+		return false
+	}
+}
+
+// embeddingCache holds the embeddings of texts shared by the routers of a
+// set, counting the tenants using each text.
+type embeddingCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedEmbedding
+}
+
+// cachedEmbedding is an embedding held by a cache in either precision, or
+// both.
+type cachedEmbedding struct {
+	f64  []float64
+	f32  []float32
+	refs int // refs is the number of tenants using the embedding.
+}
+
+// newEmbeddingCache creates a new empty embedding cache.
+func newEmbeddingCache() *embeddingCache {
+	return &embeddingCache{entries: make(map[string]*cachedEmbedding)}
+}
+
+// get returns the cached float64 embedding of the given text, converting it
+// if only its float32 embedding is cached, and acquires the text for the
+// given tenant store if it has not, reporting whether it had.
+func (c *embeddingCache) get(store *tenantStore, text string) (em []float64, ok, held bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, held := c.lookup(store, text)
+	if e == nil {
+		return nil, false, false
+	}
+	if e.f64 == nil {
+		e.f64 = Convert[float64](e.f32)
+	}
+	return e.f64, true, held
+}
+
+// getFloat32 returns the cached float32 embedding of the given text,
+// converting it if only its float64 embedding is cached, and acquires the
+// text for the given tenant store if it has not, reporting whether it had.
+func (c *embeddingCache) getFloat32(store *tenantStore, text string) (em []float32, ok, held bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, held := c.lookup(store, text)
+	if e == nil {
+		return nil, false, false
+	}
+	if e.f32 == nil {
+		e.f32 = Convert[float32](e.f64)
+	}
+	return e.f32, true, held
+}
+
+// lookup returns the entry of the given text if it holds an embedding, and
+// acquires the text for the given tenant store if it has not, reporting
+// whether it had. The cache must be locked.
+func (c *embeddingCache) lookup(store *tenantStore, text string) (*cachedEmbedding, bool) {
+	e, ok := c.entries[text]
+	if !ok || (e.f64 == nil && e.f32 == nil) {
+		return nil, false
+	}
+	return e, c.acquire(store, text, e)
+}
+
+// put caches the given embeddings of the given text, either of which may be
+// nil, and acquires the text for the given tenant store if it has not.
+func (c *embeddingCache) put(
+	store *tenantStore,
+	text string,
+	f64 []float64,
+	f32 []float32,
+) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[text]
+	if !ok {
+		e = &cachedEmbedding{}
+		c.entries[text] = e
+	}
+	if f64 != nil {
+		e.f64 = f64
+	}
+	if f32 != nil {
+		e.f32 = f32
+	}
+	c.acquire(store, text, e)
+}
+
+// acquire acquires the given entry of the given text for the given tenant
+// store if it has not, reporting whether it had. The cache must be locked.
+func (c *embeddingCache) acquire(store *tenantStore, text string, e *cachedEmbedding) bool {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.keys[text] {
+		return true
+	}
+	store.keys[text] = true
+	e.refs++
+	return false
+}
+
+// release releases the given texts, removing the embeddings no tenant uses
+// anymore.
+func (c *embeddingCache) release(texts []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, text := range texts {
+		e, ok := c.entries[text]
+		if !ok {
+			continue
+		}
+		e.refs--
+		if e.refs <= 0 {
+			delete(c.entries, text)
+		}
+	}
+}
+
+// tenantStore is the store of a tenant of a router set: the set's store under
+// the tenant's namespace, in front of which the set's cache is consulted.
+//
+// An embedding first found in the cache, cached by another tenant, is also
+// set under the tenant's namespace if missing, so that the tenant loads it
+// from the store once it is no longer cached.
+//
+// It is not closed by the routers; the set's store is closed by the set.
+type tenantStore struct {
+	store      Store                // store is the store of the set.
+	cache      *embeddingCache      // cache is the cache of the set.
+	prefix     string               // prefix is the namespace of the keys of the tenant.
+	utterances map[string]Utterance // utterances holds the utterances of the tenant's routes, by text.
+
+	mu   sync.Mutex      // mu guards keys.
+	keys map[string]bool // keys holds the texts acquired in the cache.
+}
+
+// Get gets the embedding of the given text from the cache, or from the store
+// under the tenant's namespace.
+func (s *tenantStore) Get(ctx context.Context, key string) ([]float64, error) {
+	if em, ok, held := s.cache.get(s, key); ok {
+		if !held {
+			err := s.persist(ctx, key, func(utterance Utterance) error {
+				utterance.Embed = em
+				return s.store.Set(ctx, utterance)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return em, nil
+	}
+	em, err := s.store.Get(ctx, s.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(s, key, em, nil)
+	return em, nil
+}
+
+// GetFloat32 gets the float32 embedding of the given text from the cache, or
+// from the store under the tenant's namespace.
+func (s *tenantStore) GetFloat32(ctx context.Context, key string) ([]float32, error) {
+	if em, ok, held := s.cache.getFloat32(s, key); ok {
+		if !held {
+			err := s.persist(ctx, key, func(utterance Utterance) error {
+				if store, ok := s.store.(Float32Store); ok {
+					return store.SetFloat32(ctx, utterance, em)
+				}
+				utterance.Embed = Convert[float64](em)
+				return s.store.Set(ctx, utterance)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return em, nil
+	}
+	var em []float32
+	var err error
+	if store, ok := s.store.(Float32Store); ok {
+		em, err = store.GetFloat32(ctx, s.prefix+key)
+	} else {
+		var em64 []float64
+		em64, err = s.store.Get(ctx, s.prefix+key)
+		em = Convert[float32](em64)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(s, key, nil, em)
+	return em, nil
+}
+
+// persist sets the utterance of the given text under the tenant's namespace
+// with the given function if the store does not hold it.
+func (s *tenantStore) persist(
+	ctx context.Context,
+	key string,
+	set func(utterance Utterance) error,
+) error {
+	_, err := s.store.Get(ctx, s.prefix+key)
+	if err == nil {
+		return nil
+	}
+	utterance, ok := s.utterances[key]
+	if !ok {
+		utterance = Utterance{Utterance: key}
+	}
+	utterance.Utterance = s.prefix + key
+	err = set(utterance)
+	if err != nil {
+		return fmt.Errorf("error storing utterance: %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetUtterance gets the given utterance from the store under the tenant's
+// namespace, if the store implements UtteranceStore.
+func (s *tenantStore) GetUtterance(ctx context.Context, key string) (Utterance, error) {
+	store, ok := s.store.(UtteranceStore)
+	if !ok {
+		return Utterance{}, fmt.Errorf("store does not implement UtteranceStore")
+	}
+	utterance, err := store.GetUtterance(ctx, s.prefix+key)
+	if err != nil {
+		return Utterance{}, err
+	}
+	utterance.Utterance = key
+	return utterance, nil
+}
+
+// Set sets the given utterance in the store under the tenant's namespace and
+// caches its embedding.
+func (s *tenantStore) Set(ctx context.Context, utterance Utterance) error {
+	key := utterance.Utterance
+	utterance.Utterance = s.prefix + key
+	err := s.store.Set(ctx, utterance)
+	if err != nil {
+		return err
+	}
+	s.cache.put(s, key, utterance.Embed, nil)
+	return nil
+}
+
+// SetFloat32 sets the given utterance with the given float32 embedding in
+// the store under the tenant's namespace and caches the embedding.
+func (s *tenantStore) SetFloat32(
+	ctx context.Context,
+	utterance Utterance,
+	embedding []float32,
+) error {
+	key := utterance.Utterance
+	utterance.Utterance = s.prefix + key
+	var err error
+	if store, ok := s.store.(Float32Store); ok {
+		err = store.SetFloat32(ctx, utterance, embedding)
+	} else {
+		utterance.Embed = Convert[float64](embedding)
+		err = s.store.Set(ctx, utterance)
+	}
+	if err != nil {
+		return err
+	}
+	s.cache.put(s, key, nil, embedding)
+	return nil
+}
+
+// Close does nothing: the store is shared by the tenants of the set.
+func (s *tenantStore) Close() error {
+	return nil
+}
+
+// release releases the texts acquired by the store in the cache.
+func (s *tenantStore) release() {
+	s.mu.Lock()
+	texts := make([]string, 0, len(s.keys))
+	for text := range s.keys {
+		texts = append(texts, text)
+	}
+	s.keys = make(map[string]bool)
+	s.mu.Unlock()
+	s.cache.release(texts)
+}
@@ -0,0 +1,42 @@
+package semanticrouter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCacheStore creates a tenant store of the given cache.
+func newCacheStore(cache *embeddingCache) *tenantStore {
+	return &tenantStore{cache: cache, keys: make(map[string]bool)}
+}
+
+// TestEmbeddingCache tests that cache hits acquire their text and that
+// entries without an embedding are not hits.
+func TestEmbeddingCache(t *testing.T) {
+	a := assert.New(t)
+	cache := newEmbeddingCache()
+	acme, globex := newCacheStore(cache), newCacheStore(cache)
+
+	cache.put(acme, "hello", []float64{1, 0}, nil)
+	em, ok, held := cache.getFloat32(globex, "hello")
+	a.True(ok)
+	a.False(held)
+	a.Equal([]float32{1, 0}, em)
+	_, _, held = cache.get(globex, "hello")
+	a.True(held)
+
+	acme.release()
+	em64, ok, _ := cache.get(globex, "hello")
+	a.True(ok, "the text acquired by a hit outlives the tenant that cached it")
+	a.Equal([]float64{1, 0}, em64)
+	globex.release()
+	_, ok, _ = cache.get(globex, "hello")
+	a.False(ok)
+
+	cache.put(acme, "empty", nil, nil)
+	_, ok, _ = cache.get(globex, "empty")
+	a.False(ok)
+	_, ok, _ = cache.getFloat32(globex, "empty")
+	a.False(ok)
+}
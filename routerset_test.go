@@ -0,0 +1,295 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantRoutes are the routes of the test tenants.
+var tenantRoutes = map[string][]semanticrouter.Route{
+	"acme":   {NoteworthyRoutes, ChitchatRoutes},
+	"globex": {NoteworthyRoutes},
+}
+
+// tenantLoader loads the routes of the test tenants, counting the loads of
+// each tenant.
+func tenantLoader(loads map[string]int) semanticrouter.TenantLoader {
+	return func(_ context.Context, tenant string) ([]semanticrouter.Route, []semanticrouter.Option, error) {
+		loads[tenant]++
+		routes, ok := tenantRoutes[tenant]
+		if !ok {
+			return nil, nil, errors.New("unknown tenant")
+		}
+		return routes, []semanticrouter.Option{semanticrouter.WithSimilarityDotMatrix(1.0)}, nil
+	}
+}
+
+// TestRouterSet tests that the tenants of a router set are loaded lazily,
+// isolated, and share the embeddings of their common utterances.
+func TestRouterSet(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	encoder := &batchEncoder{vectors: dispatchVectors}
+	store := memory.NewStore()
+	loads := make(map[string]int)
+	set := semanticrouter.NewRouterSet(encoder, store, tenantLoader(loads))
+	a.Empty(set.Tenants())
+
+	route, _, err := set.Match(ctx, "acme", "what is your favorite color?")
+	require.NoError(t, err)
+	require.NotNil(t, route)
+	a.Equal("chitchat", route.Name)
+
+	route, _, err = set.Match(ctx, "globex", "my dog is sneezing")
+	require.NoError(t, err)
+	require.NotNil(t, route)
+	a.Equal("noteworthy", route.Name)
+	route, _, err = set.Match(ctx, "globex", "what is your favorite color?")
+	require.NoError(t, err)
+	require.NotNil(t, route, "globex has no chitchat route")
+	a.Equal("noteworthy", route.Name)
+
+	a.Equal([]string{"acme", "globex"}, set.Tenants())
+	a.Equal(map[string]int{"acme": 1, "globex": 1}, loads)
+	a.Len(encoder.batchedTexts, 4)
+
+	em, err := store.Get(ctx, "acme:what is your favorite color?")
+	require.NoError(t, err)
+	a.NotEmpty(em)
+	_, err = store.Get(ctx, "globex:what is your favorite color?")
+	a.Error(err)
+	em, err = store.Get(ctx, "globex:what is the best way to treat a dog with a cold?")
+	require.NoError(t, err, "embeddings shared from the cache are stored under the tenant")
+	a.NotEmpty(em)
+	_, err = store.Get(ctx, "what is your favorite color?")
+	a.Error(err)
+
+	_, err = set.Router(ctx, "initech")
+	require.Error(t, err)
+	a.Equal(1, loads["initech"])
+	a.NotContains(set.Tenants(), "initech")
+	require.NoError(t, set.Close())
+}
+
+// TestRouterSetTenantNames tests that tenant names whose namespaces could
+// collide are rejected.
+func TestRouterSetTenantNames(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	store := memory.NewStore()
+	loads := make(map[string]int)
+	set := semanticrouter.NewRouterSet(
+		&batchEncoder{vectors: map[string][]float64{"b:c": {1, 0}, "c": {0, 1}}},
+		store,
+		func(_ context.Context, tenant string) ([]semanticrouter.Route, []semanticrouter.Option, error) {
+			loads[tenant]++
+			utterance := map[string]string{"a": "b:c", "a:b": "c"}[tenant]
+			return []semanticrouter.Route{{
+				Name:       "route",
+				Utterances: []semanticrouter.Utterance{{Utterance: utterance}},
+			}}, nil, nil
+		},
+	)
+	_, err := set.Router(ctx, "a")
+	require.NoError(t, err)
+	_, err = set.Router(ctx, "a:b")
+	a.ErrorContains(err, "tenant names cannot contain a colon")
+	a.Equal(map[string]int{"a": 1}, loads)
+
+	em, err := store.Get(ctx, "a:b:c")
+	require.NoError(t, err)
+	a.Equal([]float64{1, 0}, em)
+}
+
+// TestRouterSetEviction tests evicting the tenants of a router set.
+func TestRouterSetEviction(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	encoder := &batchEncoder{vectors: dispatchVectors}
+	loads := make(map[string]int)
+	set := semanticrouter.NewRouterSet(
+		encoder,
+		memory.NewStore(),
+		tenantLoader(loads),
+		semanticrouter.WithMaxTenants(1),
+	)
+
+	_, err := set.Router(ctx, "acme")
+	require.NoError(t, err)
+	_, err = set.Router(ctx, "globex")
+	require.NoError(t, err)
+	a.Equal([]string{"globex"}, set.Tenants())
+	a.Len(encoder.batchedTexts, 6, "evicted tenants do not share their embeddings")
+
+	_, err = set.Router(ctx, "acme")
+	require.NoError(t, err)
+	a.Equal(2, loads["acme"])
+	a.Len(encoder.batchedTexts, 6, "reloaded tenants load their embeddings from the store")
+
+	a.True(set.Evict("acme"))
+	a.False(set.Evict("acme"))
+	a.Empty(set.Tenants())
+}
+
+// TestRouterSetIdle tests evicting the idle tenants of a router set.
+func TestRouterSetIdle(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	loads := make(map[string]int)
+	set := semanticrouter.NewRouterSet(
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		tenantLoader(loads),
+		semanticrouter.WithIdleTimeout(time.Millisecond),
+	)
+	_, err := set.Router(ctx, "acme")
+	require.NoError(t, err)
+	a.Equal(0, set.EvictIdle())
+	time.Sleep(5 * time.Millisecond)
+	a.Equal(1, set.EvictIdle())
+	a.Empty(set.Tenants())
+}
+
+// TestRouterSetConcurrent tests that concurrent requests for a tenant load
+// it once.
+func TestRouterSetConcurrent(t *testing.T) {
+	var loads atomic.Int32
+	loader := func(_ context.Context, _ string) ([]semanticrouter.Route, []semanticrouter.Option, error) {
+		loads.Add(1)
+		return []semanticrouter.Route{NoteworthyRoutes}, nil, nil
+	}
+	set := semanticrouter.NewRouterSet(
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		loader,
+		semanticrouter.WithTenantOptions(semanticrouter.WithSimilarityDotMatrix(1.0)),
+	)
+	routers := make([]*semanticrouter.Router, 8)
+	var wg sync.WaitGroup
+	for i := range routers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router, err := set.Router(context.Background(), "acme")
+			assert.NoError(t, err)
+			routers[i] = router
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), loads.Load())
+	for _, router := range routers {
+		assert.Same(t, routers[0], router)
+	}
+}
+
+// TestRouterSetQuantization tests that tenants loaded concurrently fit their
+// own copy of a shared product quantizer.
+func TestRouterSetQuantization(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	pq := semanticrouter.NewProductQuantizer(1)
+	set := semanticrouter.NewRouterSet(
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		func(_ context.Context, tenant string) ([]semanticrouter.Route, []semanticrouter.Option, error) {
+			return tenantRoutes[tenant], []semanticrouter.Option{semanticrouter.WithSimilarityDotMatrix(1.0)}, nil
+		},
+		semanticrouter.WithTenantOptions(semanticrouter.WithQuantization(pq, 0)),
+	)
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"acme", "globex"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := set.Router(ctx, tenant)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	a.False(pq.Fitted())
+
+	route, _, err := set.Match(ctx, "acme", "what is your favorite color?")
+	require.NoError(t, err)
+	require.NotNil(t, route)
+	a.Equal("chitchat", route.Name)
+	route, _, err = set.Match(ctx, "globex", "my dog is sneezing")
+	require.NoError(t, err)
+	require.NotNil(t, route)
+	a.Equal("noteworthy", route.Name)
+}
+
+// TestRouterSetColdStart tests that a tenant whose embeddings were shared
+// from the cache loads them from the store once they are no longer cached.
+func TestRouterSetColdStart(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	store := memory.NewStore()
+	set := semanticrouter.NewRouterSet(
+		&batchEncoder{vectors: dispatchVectors},
+		store,
+		tenantLoader(make(map[string]int)),
+	)
+	_, err := set.Router(ctx, "acme")
+	require.NoError(t, err)
+	_, err = set.Router(ctx, "globex")
+	require.NoError(t, err)
+
+	encoder := &batchEncoder{vectors: dispatchVectors}
+	set = semanticrouter.NewRouterSet(encoder, store, tenantLoader(make(map[string]int)))
+	route, _, err := set.Match(ctx, "globex", "my dog is sneezing")
+	require.NoError(t, err)
+	require.NotNil(t, route)
+	a.Equal("noteworthy", route.Name)
+	a.Empty(encoder.batchedTexts)
+}
+
+// TestRouterSetCancel tests that a cancelled request for a tenant being
+// loaded does not fail the load for the other requests.
+func TestRouterSetCancel(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var loads atomic.Int32
+	loader := func(ctx context.Context, _ string) ([]semanticrouter.Route, []semanticrouter.Option, error) {
+		loads.Add(1)
+		close(started)
+		<-release
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		return []semanticrouter.Route{NoteworthyRoutes}, nil, nil
+	}
+	set := semanticrouter.NewRouterSet(
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		loader,
+		semanticrouter.WithTenantOptions(semanticrouter.WithSimilarityDotMatrix(1.0)),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error)
+	go func() {
+		_, err := set.Router(ctx, "acme")
+		cancelled <- err
+	}()
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+
+	waited := make(chan error)
+	go func() {
+		_, err := set.Router(context.Background(), "acme")
+		waited <- err
+	}()
+	close(release)
+	assert.NoError(t, <-waited)
+	assert.Equal(t, int32(1), loads.Load())
+	assert.Equal(t, []string{"acme"}, set.Tenants())
+}
@@ -0,0 +1,133 @@
+package semanticrouter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Rule is a deterministic condition routing the utterances it matches to its
+// route before, and instead of, the semantic match, without encoding them.
+//
+// A rule matches an utterance if all its conditions that are set hold.
+type Rule struct {
+	// Name identifies the rule in match results.
+	Name string
+	// Phrases are phrases one of which the utterance must be, ignoring case
+	// and surrounding spaces.
+	Phrases []string
+	// Keywords are words all of which the utterance must contain, ignoring
+	// case.
+	Keywords []string
+	// Pattern is a regular expression the utterance must match.
+	Pattern string
+	// Predicate is a function that must return true for the utterance.
+	Predicate func(utterance string) bool
+}
+
+// compilePatterns compiles the patterns of the rules and of the function
+// parameters of the given routes and of their descendants.
+func compilePatterns(routes []Route, patterns map[string]*regexp.Regexp) error {
+	for _, route := range routes {
+		if route.Function != nil {
+			err := route.Function.Parameters.compile(patterns)
+			if err != nil {
+				return fmt.Errorf(
+					"error compiling parameters of function %s of route %s: %w",
+					route.Function.Name,
+					route.Name,
+					err,
+				)
+			}
+		}
+		for _, rule := range route.Rules {
+			if rule.Pattern == "" || patterns[rule.Pattern] != nil {
+				continue
+			}
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return fmt.Errorf(
+					"error compiling pattern of rule %s of route %s: %w",
+					rule.Name,
+					route.Name,
+					err,
+				)
+			}
+			patterns[rule.Pattern] = re
+		}
+		err := compilePatterns(route.Children, patterns)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule matches the given utterance, given the
+// compiled patterns of the router.
+func (rule *Rule) matches(
+	utterance string,
+	patterns map[string]*regexp.Regexp,
+) bool {
+	if len(rule.Phrases) > 0 {
+		trimmed := strings.TrimSpace(utterance)
+		if !slices.ContainsFunc(rule.Phrases, func(phrase string) bool {
+			return strings.EqualFold(trimmed, strings.TrimSpace(phrase))
+		}) {
+			return false
+		}
+	}
+	if len(rule.Keywords) > 0 {
+		terms := bm25Terms(utterance)
+		for _, keyword := range rule.Keywords {
+			if !slices.Contains(terms, strings.ToLower(keyword)) {
+				return false
+			}
+		}
+	}
+	if rule.Pattern != "" && !patterns[rule.Pattern].MatchString(utterance) {
+		return false
+	}
+	if rule.Predicate != nil && !rule.Predicate(utterance) {
+		return false
+	}
+	return len(rule.Phrases) > 0 || len(rule.Keywords) > 0 ||
+		rule.Pattern != "" || rule.Predicate != nil
+}
+
+// matchRules returns the path to the first route, in depth-first order, with
+// a rule matching the given utterance, and the rule. The routes rejected by
+// the filter of the given context are skipped, with their descendants.
+func (r *Router) matchRules(
+	ctx context.Context,
+	routes []Route,
+	utterance string,
+) ([]*Route, *Rule) {
+	for i := range routes {
+		route := &routes[i]
+		if !accepts(ctx, route, nil) {
+			continue
+		}
+		if rule := r.matchRule(route, utterance); rule != nil {
+			return []*Route{route}, rule
+		}
+		path, rule := r.matchRules(ctx, route.Children, utterance)
+		if rule != nil {
+			return append([]*Route{route}, path...), rule
+		}
+	}
+	return nil, nil
+}
+
+// matchRule returns the first rule of the given route matching the given
+// utterance, or nil if none does.
+func (r *Router) matchRule(route *Route, utterance string) *Rule {
+	for i := range route.Rules {
+		if route.Rules[i].matches(utterance, r.patterns) {
+			return &route.Rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRules tests routing utterances with rules before the semantic match.
+func TestRules(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	noteworthy := NoteworthyRoutes
+	noteworthy.Rules = []semanticrouter.Rule{
+		{Name: "vet", Keywords: []string{"vet", "appointment"}},
+	}
+	routes := []semanticrouter.Route{
+		noteworthy,
+		ChitchatRoutes,
+		{
+			Name: "help",
+			Rules: []semanticrouter.Rule{
+				{Name: "help", Phrases: []string{"/help", "help me"}},
+				{Name: "order", Pattern: `\b[A-Z]{2}-\d{6}\b`},
+				{Name: "command", Predicate: func(u string) bool { return strings.HasPrefix(u, "/") }},
+				{Name: "empty"},
+			},
+		},
+	}
+	encoder := &batchEncoder{vectors: dispatchVectors}
+	router, err := semanticrouter.NewRouter(
+		routes,
+		encoder,
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	for utterance, expected := range map[string]string{
+		" /HELP ":                        "help",
+		"where is order AB-123456?":      "help",
+		"/start":                         "help",
+		"book a Vet appointment for Rex": "noteworthy",
+	} {
+		route, score, err := router.Match(ctx, utterance)
+		require.NoError(t, err)
+		a.Equal(expected, route.Name, utterance)
+		a.Equal(1.0, score)
+	}
+	a.Zero(encoder.encodeCalls)
+
+	res, err := router.Resolve(ctx, "where is order AB-123456?")
+	require.NoError(t, err)
+	require.NotNil(t, res.Rule)
+	a.Equal("order", res.Rule.Name)
+
+	res, err = router.Resolve(ctx, "my dog is sneezing")
+	require.NoError(t, err)
+	a.Equal("noteworthy", res.Route.Name)
+	a.Nil(res.Rule)
+	a.Equal(1, encoder.encodeCalls)
+
+	results, err := router.MatchAll(ctx, "help me", 0.5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	a.Equal("help", results[0].Rule.Name)
+
+	_, err = semanticrouter.NewRouter(
+		[]semanticrouter.Route{{Name: "bad", Rules: []semanticrouter.Rule{{Pattern: "("}}}},
+		encoder,
+		memory.NewStore(),
+	)
+	a.Error(err)
+}
@@ -0,0 +1,196 @@
+package semanticrouter
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+)
+
+// Schema is the subset of JSON Schema used to describe the parameters of a
+// Function and to validate the arguments extracted for them.
+type Schema struct {
+	// Type is the JSON type of the value: "object", "array", "string",
+	// "number", "integer", "boolean" or "null". Any type is allowed if it is
+	// empty.
+	Type string `json:"type,omitempty"`
+	// Description describes the value to the LLM extracting it.
+	Description string `json:"description,omitempty"`
+	// Properties holds the schemas of the properties of an object.
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// Required lists the properties an object must have.
+	Required []string `json:"required,omitempty"`
+	// Items is the schema of the elements of an array.
+	Items *Schema `json:"items,omitempty"`
+	// Enum lists the values allowed, if not empty.
+	Enum []any `json:"enum,omitempty"`
+	// Pattern is a regular expression a string must match.
+	Pattern string `json:"pattern,omitempty"`
+	// Minimum is the minimum of a number, if set.
+	Minimum *float64 `json:"minimum,omitempty"`
+	// Maximum is the maximum of a number, if set.
+	Maximum *float64 `json:"maximum,omitempty"`
+}
+
+// Validate returns an error describing the first part of the given value,
+// as decoded by encoding/json, that does not conform to the schema.
+//
+// The patterns of the schema are compiled by each call; routers validate the
+// arguments of their functions with the patterns compiled by NewRouter.
+func (s *Schema) Validate(value any) error {
+	return s.validate("$", value, nil)
+}
+
+// compile compiles the patterns of the schema and of its properties and
+// items into the given patterns, by expression.
+func (s *Schema) compile(patterns map[string]*regexp.Regexp) error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" && patterns[s.Pattern] == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		patterns[s.Pattern] = re
+	}
+	for _, property := range s.Properties {
+		err := property.compile(patterns)
+		if err != nil {
+			return err
+		}
+	}
+	return s.Items.compile(patterns)
+}
+
+// validate validates the value at the given path, with the given compiled
+// patterns if they hold the schema's pattern.
+func (s *Schema) validate(path string, value any, patterns map[string]*regexp.Regexp) error {
+	if s == nil {
+		return nil
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool {
+		return equalJSON(e, value)
+	}) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, s.Enum)
+	}
+	switch s.Type {
+	case "":
+		return nil
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			v, ok := object[name]
+			if !ok {
+				continue
+			}
+			err := s.Properties[name].validate(path+"."+name, v, patterns)
+			if err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		for i, v := range array {
+			err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), v, patterns)
+			if err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		if s.Pattern == "" {
+			return nil
+		}
+		re := patterns[s.Pattern]
+		if re == nil {
+			var err error
+			re, err = regexp.Compile(s.Pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %w", path, s.Pattern, err)
+			}
+		}
+		if !re.MatchString(str) {
+			return fmt.Errorf("%s: %q does not match %q", path, str, s.Pattern)
+		}
+	case "number", "integer":
+		n, ok := toNumber(value)
+		if !ok || (s.Type == "integer" && n != math.Trunc(n)) {
+			return typeError(path, s.Type, value)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			return fmt.Errorf("%s: %v is less than %v", path, n, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fmt.Errorf("%s: %v is greater than %v", path, n, *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(path, s.Type, value)
+		}
+	case "null":
+		if value != nil {
+			return typeError(path, s.Type, value)
+		}
+	default:
+		return fmt.Errorf("%s: unknown type %s", path, s.Type)
+	}
+	return nil
+}
+
+// typeError returns an error reporting that the value at the given path is
+// not of the expected type.
+func typeError(path, expected string, value any) error {
+	return fmt.Errorf("%s: expected %s, got %T", path, expected, value)
+}
+
+// toNumber returns the given value as a float64 if it is a number.
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// equalJSON reports whether two values are equal as JSON values, comparing
+// numbers by value whatever their Go type.
+func equalJSON(a, b any) bool {
+	x, okx := toNumber(a)
+	y, oky := toNumber(b)
+	if okx && oky {
+		return x == y
+	}
+	if okx || oky {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
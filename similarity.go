@@ -5,7 +5,6 @@ import (
 	"math"
 
 	"gonum.org/v1/gonum/floats"
-	"gonum.org/v1/gonum/mat"
 )
 
 // embedding is the embedding of some text, speech, or other data (images, videos, etc.).
@@ -19,6 +18,13 @@ type Utterance struct {
 	Utterance string
 	// Embed is the embedding of the utterance. It is a vector of floats.
 	Embed embedding
+	// Description is a free-form description of the utterance.
+	Description string
+	// Metadata holds free-form data about the utterance, such as the tool it
+	// calls or the team owning it.
+	Metadata map[string]any
+	// Tags label the utterance, such as "tenant=acme", see HasTags.
+	Tags []string
 }
 
 // normalizeScores normalizes the similarity scores to a 0-1 range.
@@ -53,7 +59,26 @@ func normalizeScores(sim []float64) []float64 {
 func WithSimilarityDotMatrix(coefficient float64) Option {
 	return func(r *Router) {
 		r.biFuncCoeffs = append(r.biFuncCoeffs, biFuncCoefficient{
-			handler:     similarityDotMatrix,
+			name:        "dot_matrix",
+			handler64:   similarityDotMatrix[float64],
+			handler32:   similarityDotMatrix[float32],
+			coefficient: coefficient,
+		})
+	}
+}
+
+// WithDotProduct sets the dot product function with a coefficient.
+//
+// $$a \cdot b=\sum_{i=1}^{n} a_{i} b_{i}$$
+//
+// It is equivalent to WithSimilarityDotMatrix, but faster, for encoders
+// returning normalized embeddings.
+func WithDotProduct(coefficient float64) Option {
+	return func(r *Router) {
+		r.biFuncCoeffs = append(r.biFuncCoeffs, biFuncCoefficient{
+			name:        "dot_product",
+			handler64:   dotProduct[float64],
+			handler32:   dotProduct[float32],
 			coefficient: coefficient,
 		})
 	}
@@ -65,7 +90,9 @@ func WithSimilarityDotMatrix(coefficient float64) Option {
 func WithEuclideanDistance(coefficient float64) Option {
 	return func(r *Router) {
 		r.biFuncCoeffs = append(r.biFuncCoeffs, biFuncCoefficient{
-			handler:     euclideanDistance,
+			name:        "euclidean",
+			handler64:   euclideanDistance[float64],
+			handler32:   euclideanDistance[float32],
 			coefficient: coefficient,
 		})
 	}
@@ -80,7 +107,9 @@ func WithEuclideanDistance(coefficient float64) Option {
 func WithManhattanDistance(coefficient float64) Option {
 	return func(r *Router) {
 		r.biFuncCoeffs = append(r.biFuncCoeffs, biFuncCoefficient{
-			handler:     manhattanDistance,
+			name:        "manhattan",
+			handler64:   manhattanDistance[float64],
+			handler32:   manhattanDistance[float32],
 			coefficient: coefficient,
 		})
 	}
@@ -95,7 +124,9 @@ func WithManhattanDistance(coefficient float64) Option {
 func WithJaccardSimilarity(coefficient float64) Option {
 	return func(r *Router) {
 		r.biFuncCoeffs = append(r.biFuncCoeffs, biFuncCoefficient{
-			handler:     jaccardSimilarity,
+			name:        "jaccard",
+			handler64:   jaccardSimilarity[float64],
+			handler32:   jaccardSimilarity[float32],
 			coefficient: coefficient,
 		})
 	}
@@ -111,12 +142,25 @@ func WithJaccardSimilarity(coefficient float64) Option {
 func WithPearsonCorrelation(coefficient float64) Option {
 	return func(r *Router) {
 		r.biFuncCoeffs = append(r.biFuncCoeffs, biFuncCoefficient{
-			handler:     pearsonCorrelation,
+			name:        "pearson",
+			handler64:   pearsonCorrelation[float64],
+			handler32:   pearsonCorrelation[float32],
 			coefficient: coefficient,
 		})
 	}
 }
 
+// dotProduct computes the dot product of two vectors.
+//
+// $$a \cdot b=\sum_{i=1}^{n} a_{i} b_{i}$$
+//
+// It equals the cosine similarity of normalized vectors without computing
+// their norms.
+func dotProduct[T Float](xq, index []T) (float64, error) {
+	index = index[:len(xq)]
+	return float64(kernelsFor[T]().dot(xq, index)), nil
+}
+
 // SimilarityDotMatrix computes the similarity scores between a query vector and
 // a set of vectors.
 //
@@ -129,16 +173,13 @@ func WithPearsonCorrelation(coefficient float64) Option {
 // The similarity matrix returned is a matrix where each element is reduced to
 // the similarity score between the query vector and the corresponding index
 // vector.
-func similarityDotMatrix(xq, index *mat.VecDense) (float64, error) {
-	// normalize the query vector
-	xqNorm := mat.Norm(xq, 2)
-	// normalize the index vector
-	indexNorm := mat.Norm(index, 2)
-	// perform dot product between query vector and index vector
-	dot := floats.Dot(xq.RawVector().Data, index.RawVector().Data)
+func similarityDotMatrix[T Float](xq, index []T) (float64, error) {
+	index = index[:len(xq)]
+	d, xx, yy := kernelsFor[T]().moments(xq, index, 0, 0)
+	dot, xqNorm, indexNorm := float64(d), float64(xx), float64(yy)
 	// return the similarity score (dot product) divided by the product of
 	// the query vector norm and the index vector norm
-	return dot / (xqNorm * indexNorm), nil
+	return dot / (math.Sqrt(xqNorm) * math.Sqrt(indexNorm)), nil
 }
 
 // EuclideanDistance calculates the Euclidean distance between two vectors.
@@ -150,10 +191,9 @@ func similarityDotMatrix(xq, index *mat.VecDense) (float64, error) {
 //
 // The function takes two vectors as input and returns the Euclidean distance
 // between them.
-func euclideanDistance(xq, index *mat.VecDense) (float64, error) {
-	diff := mat.NewVecDense(xq.Len(), nil)
-	diff.SubVec(xq, index)
-	return mat.Norm(diff, 2), nil
+func euclideanDistance[T Float](xq, index []T) (float64, error) {
+	index = index[:len(xq)]
+	return math.Sqrt(float64(kernelsFor[T]().l2(xq, index))), nil
 }
 
 // manhattanDistance calculates the Manhattan distance between two vectors.
@@ -164,14 +204,9 @@ func euclideanDistance(xq, index *mat.VecDense) (float64, error) {
 // $$d(x, y) = |x_1 - y_1| + |x_2 - y_2| + ... + |x_n - y_n|$$
 //
 // The function takes two vectors as input and returns the Manhattan distance between them.
-func manhattanDistance(xq, index *mat.VecDense) (float64, error) {
-	diff := mat.NewVecDense(xq.Len(), nil)
-	diff.SubVec(xq, index)
-	sum := 0.0
-	for i := 0; i < diff.Len(); i++ {
-		sum += math.Abs(diff.AtVec(i))
-	}
-	return mat.Norm(diff, 1), nil
+func manhattanDistance[T Float](xq, index []T) (float64, error) {
+	index = index[:len(xq)]
+	return float64(kernelsFor[T]().l1(xq, index)), nil
 }
 
 // JaccardSimilarity calculates the Jaccard similarity between two vectors.
@@ -181,14 +216,10 @@ func manhattanDistance(xq, index *mat.VecDense) (float64, error) {
 // $$J(A, B)=\frac{|A \cap B|}{|A \cup B|}$$
 //
 // The function takes two vectors as input and returns the Jaccard distance between them.
-func jaccardSimilarity(xq, index *mat.VecDense) (float64, error) {
-	minSum := 0.0
-	maxSum := 0.0
-	for i := 0; i < xq.Len(); i++ {
-		minSum += math.Min(xq.AtVec(i), index.AtVec(i))
-		maxSum += math.Max(xq.AtVec(i), index.AtVec(i))
-	}
-	return minSum / maxSum, nil
+func jaccardSimilarity[T Float](xq, index []T) (float64, error) {
+	index = index[:len(xq)]
+	minSum, maxSum := kernelsFor[T]().minMax(xq, index)
+	return float64(minSum) / float64(maxSum), nil
 }
 
 // PearsonCorrelation calculates the Pearson correlation between two vectors.
@@ -203,44 +234,28 @@ func jaccardSimilarity(xq, index *mat.VecDense) (float64, error) {
 //
 // The function takes two vectors as input and returns the Pearson correlation
 // between them.
-func pearsonCorrelation(xq, index *mat.VecDense) (float64, error) {
-	meanXq := floats.Sum(xq.RawVector().Data) / float64(xq.Len())
-	meanIndex := floats.Sum(index.RawVector().Data) / float64(index.Len())
-
-	numerator := 0.0
-	varSumXq := 0.0
-	varSumIndex := 0.0
-
-	for i := 0; i < xq.Len(); i++ {
-		diffXq := xq.AtVec(i) - meanXq
-		diffIndex := index.AtVec(i) - meanIndex
-		numerator += diffXq * diffIndex
-		varSumXq += diffXq * diffXq
-		varSumIndex += diffIndex * diffIndex
-	}
-	return numerator / (math.Sqrt(varSumXq) * math.Sqrt(varSumIndex)), nil
+func pearsonCorrelation[T Float](xq, index []T) (float64, error) {
+	index = index[:len(xq)]
+	k := kernelsFor[T]()
+	meanXq := k.sum(xq) / T(len(xq))
+	meanIndex := k.sum(index) / T(len(index))
+	numerator, varSumXq, varSumIndex := k.moments(xq, index, meanXq, meanIndex)
+	return float64(numerator) / (math.Sqrt(float64(varSumXq)) * math.Sqrt(float64(varSumIndex))), nil
 }
 
 // HammingDistance calculates the Hamming distance between two vectors.
 //
-// The Hamming distance is the number of positions at which the corresponding
-// bits are different.
+// The Hamming distance is the number of positions at which the sign bits of
+// the vectors, as packed by Binarize, are different.
 //
-// $$d(x, y)=\frac{1}{n} \sum_{n=1}^{n=n}\left|x_{i}-y_{i}\right|$$
+// $$d(x, y)=\sum_{i=1}^{n}\left[x_{i}>0\right] \oplus \left[y_{i}>0\right]$$
 //
 // The function takes two vectors as input and returns the Hamming distance between them.
-func hammingDistance(xq, index *mat.VecDense) (float64, error) {
-	if xq.Len() != index.Len() {
+func hammingDistance[T Float](xq, index []T) (float64, error) {
+	if len(xq) != len(index) {
 		return 0, fmt.Errorf("vectors must be the same length (are you mix mashing encoding models?)")
 	}
-
-	count := 0.0
-	for i := 0; i < xq.Len(); i++ {
-		if xq.AtVec(i) != index.AtVec(i) {
-			count++
-		}
-	}
-	return count, nil
+	return float64(Hamming(Binarize(xq), Binarize(index))), nil
 }
 
 // MinkowskiDistance calculates the Minkowski distance between two vectors.
@@ -251,16 +266,14 @@ func hammingDistance(xq, index *mat.VecDense) (float64, error) {
 // $$d(x, y) = \sum_{i=1}^{n} |x_i - y_i|^p$$
 //
 // where n is the length of the vectors.
-func minkowskiDistance(xq, index *mat.VecDense, p float64) (float64, error) {
+func minkowskiDistance[T Float](xq, index []T, p float64) (float64, error) {
 	if p <= 0 {
 		panic("Order p must be greater than 0")
 	}
 
-	diff := mat.NewVecDense(xq.Len(), nil)
-	diff.SubVec(xq, index)
 	sum := 0.0
-	for i := 0; i < diff.Len(); i++ {
-		sum += math.Pow(math.Abs(diff.AtVec(i)), p)
+	for i := range xq {
+		sum += math.Pow(math.Abs(float64(xq[i])-float64(index[i])), p)
 	}
 	return math.Pow(sum, 1/p), nil
 }
@@ -7,14 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"gonum.org/v1/gonum/floats"
-	"gonum.org/v1/gonum/mat"
 )
 
-// Helper function to create a VecDense from a slice
-func createVecDense(data []float64) *mat.VecDense {
-	return mat.NewVecDense(len(data), data)
-}
-
 // Test case struct to hold query, index and expected similarity value
 type testCase struct {
 	queryVec    []float64
@@ -193,11 +187,9 @@ func TestSimilarityMatrix(t *testing.T) {
 				a := assert.New(t)
 				tc := tc
 				t.Parallel()
-				query := createVecDense(tc.queryVec)
-				index := createVecDense(tc.indexVec)
 				similarity, err := similarityDotMatrix(
-					query,
-					index,
+					tc.queryVec,
+					tc.indexVec,
 				)
 				a.NoError(err)
 
@@ -231,9 +223,7 @@ func TestSimilarityDotMatrix(t *testing.T) {
 
 	for _, tt := range tests {
 		a := assert.New(t)
-		xq := mat.NewVecDense(len(tt.xq), tt.xq)
-		index := mat.NewVecDense(len(tt.index), tt.index)
-		got, err := similarityDotMatrix(xq, index)
+		got, err := similarityDotMatrix(tt.xq, tt.index)
 		a.NoError(err)
 		if math.Abs(got-tt.want) > 1e-9 {
 			t.Errorf(
@@ -260,9 +250,7 @@ func TestEuclideanDistance(t *testing.T) {
 
 	for _, tt := range tests {
 		a := assert.New(t)
-		xq := mat.NewVecDense(len(tt.xq), tt.xq)
-		index := mat.NewVecDense(len(tt.index), tt.index)
-		got, err := euclideanDistance(xq, index)
+		got, err := euclideanDistance(tt.xq, tt.index)
 		a.NoError(err)
 		if math.Abs(got-tt.want) > 1e-9 {
 			t.Errorf(
@@ -289,9 +277,7 @@ func TestManhattanDistance(t *testing.T) {
 
 	for _, tt := range tests {
 		a := assert.New(t)
-		xq := mat.NewVecDense(len(tt.xq), tt.xq)
-		index := mat.NewVecDense(len(tt.index), tt.index)
-		got, err := manhattanDistance(xq, index)
+		got, err := manhattanDistance(tt.xq, tt.index)
 		a.NoError(err)
 		if math.Abs(got-tt.want) > 1e-9 {
 			t.Errorf(
@@ -318,9 +304,7 @@ func TestJaccardSimilarity(t *testing.T) {
 
 	for _, tt := range tests {
 		a := assert.New(t)
-		xq := mat.NewVecDense(len(tt.xq), tt.xq)
-		index := mat.NewVecDense(len(tt.index), tt.index)
-		got, err := jaccardSimilarity(xq, index)
+		got, err := jaccardSimilarity(tt.xq, tt.index)
 		a.NoError(err)
 		if math.Abs(got-tt.want) > 1e-9 {
 			t.Errorf(
@@ -346,9 +330,7 @@ func TestPearsonCorrelation(t *testing.T) {
 	}
 	for _, tt := range tests {
 		a := assert.New(t)
-		xq := mat.NewVecDense(len(tt.xq), tt.xq)
-		index := mat.NewVecDense(len(tt.index), tt.index)
-		got, err := pearsonCorrelation(xq, index)
+		got, err := pearsonCorrelation(tt.xq, tt.index)
 		a.NoError(err)
 		if math.IsNaN(tt.want) {
 			if !math.IsNaN(got) {
@@ -372,16 +354,15 @@ func TestHammingDistance(t *testing.T) {
 		xq, index []float64
 		want      float64
 	}{
-		{[]float64{1, 2, 3}, []float64{4, 5, 6}, 3},
+		{[]float64{1, 2, 3}, []float64{4, 5, 6}, 0},
 		{[]float64{0, 0, 0}, []float64{1, 1, 1}, 3},
 		{[]float64{1, 1, 1}, []float64{1, 1, 1}, 0},
+		{[]float64{1, -2, 3, -4}, []float64{-1, -2, 3, 4}, 2},
 	}
 
 	for _, tt := range tests {
 		a := assert.New(t)
-		xq := mat.NewVecDense(len(tt.xq), tt.xq)
-		index := mat.NewVecDense(len(tt.index), tt.index)
-		got, err := hammingDistance(xq, index)
+		got, err := hammingDistance(tt.xq, tt.index)
 		a.NoError(err)
 		if math.Abs(got-tt.want) > 1e-9 {
 			t.Errorf(
@@ -409,9 +390,7 @@ func TestMinkowskiDistance(t *testing.T) {
 
 	for _, tt := range tests {
 		a := assert.New(t)
-		xq := mat.NewVecDense(len(tt.xq), tt.xq)
-		index := mat.NewVecDense(len(tt.index), tt.index)
-		got, err := minkowskiDistance(xq, index, tt.p)
+		got, err := minkowskiDistance(tt.xq, tt.index, tt.p)
 		a.NoError(err)
 		if math.Abs(got-tt.want) > 1e-9 {
 			t.Errorf(
@@ -473,3 +452,30 @@ func TestNormalizeScores(t *testing.T) {
 		}
 	}
 }
+
+// TestSimilarityFloat32 tests that the float32 instantiations of the
+// similarity functions agree with the float64 ones.
+func TestSimilarityFloat32(t *testing.T) {
+	xq := []float64{0.3213532863532023, 0.01524713642631278, 0.5640214803262418, 0.7471951467346923}
+	index := []float64{0.14265224091380074, 0.5373162226984148, 0.7329499385535614, 0.11489132191465051}
+	handlers := map[string]struct {
+		h64 handler[float64]
+		h32 handler[float32]
+	}{
+		"dot":       {similarityDotMatrix[float64], similarityDotMatrix[float32]},
+		"euclidean": {euclideanDistance[float64], euclideanDistance[float32]},
+		"manhattan": {manhattanDistance[float64], manhattanDistance[float32]},
+		"jaccard":   {jaccardSimilarity[float64], jaccardSimilarity[float32]},
+		"pearson":   {pearsonCorrelation[float64], pearsonCorrelation[float32]},
+	}
+	for name, h := range handlers {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			want, err := h.h64(xq, index)
+			a.NoError(err)
+			got, err := h.h32(Convert[float32](xq), Convert[float32](index))
+			a.NoError(err)
+			a.InDelta(want, got, 1e-6)
+		})
+	}
+}
@@ -9,26 +9,82 @@ import (
 )
 
 // Store is a simple key-value store for embeddings.
+//
+// It keeps each embedding in the precision it was set with, converting it
+// when it is read in the other precision.
 type Store struct {
 	mu    sync.RWMutex
-	store map[string][]float64
+	store map[string]entry
+}
+
+// entry is an utterance held by the store, with its embedding in either
+// precision.
+type entry struct {
+	utterance semanticrouter.Utterance // utterance is the utterance, without its embedding.
+	f64       []float64
+	f32       []float32
 }
 
 // NewStore creates a new Store from a redis client.
 func NewStore() *Store {
-	return &Store{store: make(map[string][]float64)}
+	return &Store{store: make(map[string]entry)}
 }
 
-// Get gets a value from the
+// Get gets a value from the in-memory store.
+//
+// It is concurrency safe.
 func (s *Store) Get(
 	_ context.Context,
 	utterance string,
 ) (embedding []float64, err error) {
-	embedding, ok := s.store[utterance]
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.store[utterance]
 	if !ok {
-		return nil, fmt.Errorf("key does not exist: %w", err)
+		return nil, fmt.Errorf("key does not exist: %s", utterance)
+	}
+	if e.f32 != nil {
+		return semanticrouter.Convert[float64](e.f32), nil
+	}
+	return e.f64, nil
+}
+
+// GetFloat32 gets a float32 value from the in-memory store.
+//
+// It is concurrency safe.
+func (s *Store) GetFloat32(
+	_ context.Context,
+	utterance string,
+) (embedding []float32, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.store[utterance]
+	if !ok {
+		return nil, fmt.Errorf("key does not exist: %s", utterance)
+	}
+	if e.f64 != nil {
+		return semanticrouter.Convert[float32](e.f64), nil
 	}
-	return embedding, nil
+	return e.f32, nil
+}
+
+// GetUtterance gets an utterance, with its embedding, from the in-memory
+// store.
+//
+// It is concurrency safe.
+func (s *Store) GetUtterance(
+	ctx context.Context,
+	key string,
+) (utterance semanticrouter.Utterance, err error) {
+	embedding, err := s.Get(ctx, key)
+	if err != nil {
+		return utterance, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	utterance = s.store[key].utterance
+	utterance.Embed = embedding
+	return utterance, nil
 }
 
 // Set sets a value in the in-memory store.
@@ -40,7 +96,24 @@ func (s *Store) Set(
 ) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.store[utterance.Utterance] = utterance.Embed
+	embedding := utterance.Embed
+	utterance.Embed = nil
+	s.store[utterance.Utterance] = entry{utterance: utterance, f64: embedding}
+	return nil
+}
+
+// SetFloat32 sets a float32 value in the in-memory store.
+//
+// It is concurrency safe.
+func (s *Store) SetFloat32(
+	_ context.Context,
+	utterance semanticrouter.Utterance,
+	embedding []float32,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	utterance.Embed = nil
+	s.store[utterance.Utterance] = entry{utterance: utterance, f32: embedding}
 	return nil
 }
 
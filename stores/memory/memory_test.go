@@ -10,7 +10,9 @@ import (
 )
 
 var (
-	_ semanticrouter.Store = (*memory.Store)(nil)
+	_ semanticrouter.Store          = (*memory.Store)(nil)
+	_ semanticrouter.Float32Store   = (*memory.Store)(nil)
+	_ semanticrouter.UtteranceStore = (*memory.Store)(nil)
 )
 
 // TestStore tests the in memory store.
@@ -38,3 +40,52 @@ func TestStore(t *testing.T) {
 		floats,
 	)
 }
+
+// TestStoreFloat32 tests that the in memory store converts embeddings between
+// precisions.
+func TestStoreFloat32(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	store := memory.NewStore()
+	err := store.SetFloat32(
+		ctx,
+		semanticrouter.Utterance{Utterance: "key"},
+		[]float32{1.5, 2.5},
+	)
+	a.NoError(err)
+
+	f32, err := store.GetFloat32(ctx, "key")
+	a.NoError(err)
+	a.Equal([]float32{1.5, 2.5}, f32)
+
+	f64, err := store.Get(ctx, "key")
+	a.NoError(err)
+	a.Equal([]float64{1.5, 2.5}, f64)
+
+	_, err = store.GetFloat32(ctx, "missing")
+	a.Error(err)
+}
+
+// TestStoreUtterance tests that the in memory store keeps the description,
+// metadata and tags of the utterances.
+func TestStoreUtterance(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	store := memory.NewStore()
+	utter := semanticrouter.Utterance{
+		Utterance:   "key",
+		Description: "a key",
+		Metadata:    map[string]any{"tool": "search"},
+		Tags:        []string{"tenant=acme"},
+	}
+	err := store.SetFloat32(ctx, utter, []float32{1.5, 2.5})
+	a.NoError(err)
+
+	got, err := store.GetUtterance(ctx, "key")
+	a.NoError(err)
+	utter.Embed = []float64{1.5, 2.5}
+	a.Equal(utter, got)
+
+	_, err = store.GetUtterance(ctx, "missing")
+	a.Error(err)
+}
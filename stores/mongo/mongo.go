@@ -3,6 +3,8 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/conneroisu/semanticrouter-go"
 	"go.mongodb.org/mongo-driver/bson"
@@ -23,23 +25,79 @@ func New(collection *mongo.Collection) *Store {
 	}
 }
 
+// record is the document stored for an utterance.
+//
+// Embeddings set with SetFloat32 are stored in embed32 as little-endian
+// float32 values rather than in the embed array of the utterance.
+type record struct {
+	semanticrouter.Utterance `bson:",inline"`
+	Embed32                  []byte `bson:"embed32,omitempty"`
+}
+
 // Get gets a value from the store.
+//
+// If the utterance is not in the store, it returns an error.
 func (s *Store) Get(ctx context.Context, utterance string) ([]float64, error) {
-	var floats []float64
-	filter := bson.M{"utterance": utterance}
-	cur, err := s.coll.Find(ctx, filter)
+	rec, err := s.get(ctx, utterance)
 	if err != nil {
 		return nil, err
 	}
-	var results []semanticrouter.Utterance
-	if err = cur.All(ctx, &results); err != nil {
-		panic(err)
+	if rec.Embed32 != nil {
+		em, err := semanticrouter.Float32sFromBytes(rec.Embed32)
+		if err != nil {
+			return nil, err
+		}
+		return semanticrouter.Convert[float64](em), nil
 	}
-	for _, result := range results {
-		floats = append(floats, result.Embed...)
+	return rec.Embed, nil
+}
+
+// GetFloat32 gets a float32 value from the store.
+//
+// If the utterance is not in the store, it returns an error.
+func (s *Store) GetFloat32(ctx context.Context, utterance string) ([]float32, error) {
+	rec, err := s.get(ctx, utterance)
+	if err != nil {
+		return nil, err
 	}
-	defer cur.Close(ctx)
-	return floats, nil
+	if rec.Embed32 != nil {
+		return semanticrouter.Float32sFromBytes(rec.Embed32)
+	}
+	return semanticrouter.Convert[float32](rec.Embed), nil
+}
+
+// GetUtterance gets an utterance, with its embedding, from the store.
+//
+// If the utterance is not in the store, it returns an error.
+func (s *Store) GetUtterance(
+	ctx context.Context,
+	key string,
+) (semanticrouter.Utterance, error) {
+	rec, err := s.get(ctx, key)
+	if err != nil {
+		return semanticrouter.Utterance{}, err
+	}
+	if rec.Embed32 != nil {
+		em, err := semanticrouter.Float32sFromBytes(rec.Embed32)
+		if err != nil {
+			return semanticrouter.Utterance{}, err
+		}
+		rec.Embed = semanticrouter.Convert[float64](em)
+	}
+	return rec.Utterance, nil
+}
+
+// get gets the document of an utterance from the store.
+func (s *Store) get(ctx context.Context, utterance string) (rec record, err error) {
+	filter := bson.M{"utterance": utterance}
+	err = s.coll.FindOne(ctx, filter).Decode(&rec)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return rec, fmt.Errorf("key does not exist: %s", utterance)
+	}
+	if err != nil {
+		return rec, fmt.Errorf("error getting embedding: %w", err)
+	}
+	return rec, nil
 }
 
 // Set stores a value in the store.
@@ -51,6 +109,23 @@ func (s *Store) Set(ctx context.Context, keyValPair semanticrouter.Utterance) er
 	return nil
 }
 
+// SetFloat32 stores a float32 value in the store.
+func (s *Store) SetFloat32(
+	ctx context.Context,
+	utterance semanticrouter.Utterance,
+	embedding []float32,
+) error {
+	utterance.Embed = nil
+	_, err := s.coll.InsertOne(ctx, record{
+		Utterance: utterance,
+		Embed32:   semanticrouter.Float32Bytes(embedding),
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // Close closes the MongoDB connection.
 func (s *Store) Close() error {
 	return nil
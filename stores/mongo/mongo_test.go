@@ -13,7 +13,9 @@ import (
 )
 
 var (
-	_ semanticrouter.Store = (*Store)(nil)
+	_ semanticrouter.Store          = (*Store)(nil)
+	_ semanticrouter.Float32Store   = (*Store)(nil)
+	_ semanticrouter.UtteranceStore = (*Store)(nil)
 )
 
 func TestStore(t *testing.T) {
@@ -51,4 +53,18 @@ func TestStore(t *testing.T) {
 	floats, err := store.Get(ctx, "key")
 	a.NoError(err)
 	a.Len(floats, 5)
+
+	err = store.SetFloat32(
+		ctx,
+		semanticrouter.Utterance{Utterance: "key32"},
+		[]float32{1.5, 2.5},
+	)
+	a.NoError(err)
+
+	f32, err := store.GetFloat32(ctx, "key32")
+	a.NoError(err)
+	a.Equal([]float32{1.5, 2.5}, f32)
+
+	_, err = store.Get(ctx, "missing")
+	a.Error(err)
 }
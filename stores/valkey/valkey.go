@@ -15,6 +15,15 @@ type Store struct {
 	rds *redis.Client
 }
 
+// record is the value stored for an utterance.
+//
+// Embeddings set with SetFloat32 are stored in Embed32 as little-endian
+// float32 values rather than in the JSON array of the utterance.
+type record struct {
+	semanticrouter.Utterance
+	Embed32 []byte `json:",omitempty"`
+}
+
 // NewStore creates a new Store from a redis client.
 func NewStore(rds *redis.Client) *Store {
 	return &Store{rds: rds}
@@ -30,22 +39,73 @@ func (s *Store) Get(
 	ctx context.Context,
 	utterance string,
 ) (embedding []float64, err error) {
+	rec, err := s.get(ctx, utterance)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Embed32 != nil {
+		em, err := semanticrouter.Float32sFromBytes(rec.Embed32)
+		if err != nil {
+			return nil, err
+		}
+		return semanticrouter.Convert[float64](em), nil
+	}
+	return rec.Embed, nil
+}
+
+// GetFloat32 gets a float32 value from the valkey store.
+func (s *Store) GetFloat32(
+	ctx context.Context,
+	utterance string,
+) (embedding []float32, err error) {
+	rec, err := s.get(ctx, utterance)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Embed32 != nil {
+		return semanticrouter.Float32sFromBytes(rec.Embed32)
+	}
+	return semanticrouter.Convert[float32](rec.Embed), nil
+}
+
+// GetUtterance gets an utterance, with its embedding, from the valkey
+// store.
+func (s *Store) GetUtterance(
+	ctx context.Context,
+	key string,
+) (utterance semanticrouter.Utterance, err error) {
+	rec, err := s.get(ctx, key)
+	if err != nil {
+		return utterance, err
+	}
+	if rec.Embed32 != nil {
+		em, err := semanticrouter.Float32sFromBytes(rec.Embed32)
+		if err != nil {
+			return utterance, err
+		}
+		rec.Embed = semanticrouter.Convert[float64](em)
+	}
+	return rec.Utterance, nil
+}
+
+// get gets the record of an utterance from the valkey store.
+func (s *Store) get(
+	ctx context.Context,
+	utterance string,
+) (rec record, err error) {
 	cmd := s.rds.Get(ctx, utterance)
 	val, err := cmd.Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			fmt.Println("key2 does not exist")
-			fmt.Println(err)
-			return nil, fmt.Errorf("key does not exist: %w", err)
+			return rec, fmt.Errorf("key does not exist: %w", err)
 		}
-		return nil, err
+		return rec, err
 	}
-	var utPr semanticrouter.Utterance
-	err = json.Unmarshal([]byte(val), &utPr)
+	err = json.Unmarshal([]byte(val), &rec)
 	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling embedding: %w", err)
+		return rec, fmt.Errorf("error unmarshaling embedding: %w", err)
 	}
-	return utPr.Embed, nil
+	return rec, nil
 }
 
 // Set sets a value in the valkey store.
@@ -53,13 +113,31 @@ func (s *Store) Set(
 	ctx context.Context,
 	utterance semanticrouter.Utterance,
 ) error {
-	val, err := json.Marshal(utterance)
+	return s.set(ctx, record{Utterance: utterance})
+}
+
+// SetFloat32 sets a float32 value in the valkey store.
+func (s *Store) SetFloat32(
+	ctx context.Context,
+	utterance semanticrouter.Utterance,
+	embedding []float32,
+) error {
+	utterance.Embed = nil
+	return s.set(ctx, record{
+		Utterance: utterance,
+		Embed32:   semanticrouter.Float32Bytes(embedding),
+	})
+}
+
+// set sets the record of an utterance in the valkey store.
+func (s *Store) set(ctx context.Context, rec record) error {
+	val, err := json.Marshal(rec)
 	if err != nil {
 		return fmt.Errorf("error marshaling embedding: %w", err)
 	}
 	cmd := s.rds.Set(
 		ctx,
-		utterance.Utterance,
+		rec.Utterance.Utterance,
 		string(val),
 		0,
 	)
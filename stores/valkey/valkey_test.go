@@ -13,7 +13,9 @@ import (
 )
 
 var (
-	_ semanticrouter.Store = (*valkey.Store)(nil)
+	_ semanticrouter.Store          = (*valkey.Store)(nil)
+	_ semanticrouter.Float32Store   = (*valkey.Store)(nil)
+	_ semanticrouter.UtteranceStore = (*valkey.Store)(nil)
 )
 
 // TestStore is a test for the redis/valkey store.
@@ -62,4 +64,19 @@ func TestStore(t *testing.T) {
 		[]float64{1.0, 2.0, 3.0, 4.0, 5.0},
 		floats,
 	)
+
+	err = store.SetFloat32(
+		ctx,
+		semanticrouter.Utterance{Utterance: "key32"},
+		[]float32{1.5, 2.5},
+	)
+	assert.NoError(t, err)
+
+	f32, err := store.GetFloat32(ctx, "key32")
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1.5, 2.5}, f32)
+
+	floats, err = store.Get(ctx, "key32")
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{1.5, 2.5}, floats)
 }
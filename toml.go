@@ -0,0 +1,260 @@
+package semanticrouter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// parseTOML parses the given TOML document into the tree of YAML nodes it is
+// equivalent to, so that it is decoded as a YAML document.
+//
+// The values are decoded by go-toml, and the positions of their keys and of
+// themselves are read from the expressions of its parser.
+func parseTOML(data []byte, file string) (*yaml.Node, error) {
+	positions := newTOMLPositions(data)
+	var doc map[string]any
+	err := toml.Unmarshal(data, &doc)
+	if err != nil {
+		message := strings.TrimPrefix(err.Error(), "toml: ")
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			line, column := decodeErr.Position()
+			return nil, ErrConfig{Message: message, File: file, Line: line, Column: column}
+		}
+		// The errors of redefined keys have no position.
+		p := positions.duplicate
+		return nil, ErrConfig{Message: message, File: file, Line: p.line, Column: p.column}
+	}
+	return positions.node(doc, ""), nil
+}
+
+// tomlPositions holds the positions of the keys and values of a TOML
+// document, by path: the keys of the tables and the indexes of the arrays
+// leading to a value, separated by NUL characters.
+type tomlPositions struct {
+	data      []byte
+	parser    unstable.Parser
+	keys      map[string]position // keys holds the position of the key of each value.
+	values    map[string]position // values holds the position of each value.
+	tables    map[string]int      // tables holds the number of tables of each array of tables.
+	defined   map[string]bool     // defined holds the paths of the values and tables defined explicitly.
+	duplicate position            // duplicate is the position of the first key defined twice.
+}
+
+// newTOMLPositions reads the positions of the keys and values of the given
+// TOML document. It stops at the first syntax error.
+func newTOMLPositions(data []byte) *tomlPositions {
+	t := &tomlPositions{
+		data:    data,
+		keys:    make(map[string]position),
+		values:  make(map[string]position),
+		tables:  make(map[string]int),
+		defined: make(map[string]bool),
+	}
+	t.parser.Reset(data)
+	table := ""
+	for t.parser.NextExpression() {
+		expr := t.parser.Expression()
+		switch expr.Kind {
+		case unstable.Table:
+			table = t.table(expr, false)
+		case unstable.ArrayTable:
+			table = t.table(expr, true)
+		case unstable.KeyValue:
+			t.keyValue(table, expr)
+		}
+	}
+	return t
+}
+
+// table records the position of the table, or table of an array of tables,
+// of the given expression and returns its path.
+func (t *tomlPositions) table(expr *unstable.Node, array bool) string {
+	path := ""
+	it := expr.Key()
+	for it.Next() {
+		key := it.Node()
+		path = tomlPath(path, string(key.Data))
+		at := t.at(key.Raw.Offset)
+		if _, ok := t.keys[path]; !ok {
+			t.keys[path] = at
+			t.values[path] = at
+		}
+		n, ok := t.tables[path]
+		switch {
+		case it.IsLast() && array:
+			t.tables[path] = n + 1
+			path = tomlPath(path, strconv.Itoa(n))
+			t.keys[path] = at
+			t.values[path] = at
+		case ok:
+			path = tomlPath(path, strconv.Itoa(n-1))
+		case it.IsLast():
+			t.define(path, at)
+		}
+	}
+	return path
+}
+
+// keyValue records the positions of the given key-value expression in the
+// table at the given path.
+func (t *tomlPositions) keyValue(table string, expr *unstable.Node) {
+	path := table
+	var at position
+	var end uint32
+	it := expr.Key()
+	for it.Next() {
+		key := it.Node()
+		path = tomlPath(path, string(key.Data))
+		at = t.at(key.Raw.Offset)
+		if _, ok := t.keys[path]; !ok {
+			t.keys[path] = at
+		}
+		end = key.Raw.Offset + key.Raw.Length
+	}
+	t.define(path, at)
+	// The value follows the key, blanks and the equal sign.
+	offset := int(end)
+	for offset < len(t.data) && strings.IndexByte(" \t=", t.data[offset]) >= 0 {
+		offset++
+	}
+	t.value(path, expr.Value(), t.at(uint32(offset)))
+}
+
+// value records the position of the given value at the given path, at the
+// given position, and the positions of its elements or key-values.
+func (t *tomlPositions) value(path string, node *unstable.Node, at position) {
+	t.values[path] = at
+	i := 0
+	it := node.Children()
+	for it.Next() {
+		child := it.Node()
+		switch node.Kind {
+		case unstable.Array:
+			if child.Kind != unstable.Comment {
+				t.value(tomlPath(path, strconv.Itoa(i)), child, t.start(child, at))
+				i++
+			}
+		case unstable.InlineTable:
+			t.keyValue(path, child)
+		}
+	}
+}
+
+// start returns the position of the start of the given value, or the given
+// position if the parser does not keep its range.
+func (t *tomlPositions) start(node *unstable.Node, at position) position {
+	switch {
+	case node.Raw.Length > 0:
+		return t.at(node.Raw.Offset)
+	case node.Kind == unstable.Bool || node.Kind == unstable.DateTime ||
+		node.Kind == unstable.LocalDate || node.Kind == unstable.LocalTime ||
+		node.Kind == unstable.LocalDateTime:
+		// The data of these values is a slice of the document.
+		return t.at(t.parser.Range(node.Data).Offset)
+	default:
+		return at
+	}
+}
+
+// define records the explicit definition of the value or table at the given
+// path, and the position of the first one defined twice.
+func (t *tomlPositions) define(path string, at position) {
+	if t.defined[path] && t.duplicate.line == 0 {
+		t.duplicate = at
+	}
+	t.defined[path] = true
+}
+
+// at returns the position of the given offset in the document.
+func (t *tomlPositions) at(offset uint32) position {
+	before := t.data[:min(int(offset), len(t.data))]
+	return position{
+		line:   bytes.Count(before, []byte("\n")) + 1,
+		column: len(before) - bytes.LastIndexByte(before, '\n'),
+	}
+}
+
+// tomlPath returns the path of the given key or index under the given path.
+func tomlPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "\x00" + key
+}
+
+// node returns the YAML node of the given value decoded from TOML at the
+// given path, with the tag of its TOML type and its position.
+func (t *tomlPositions) node(v any, path string) *yaml.Node {
+	var node *yaml.Node
+	switch v := v.(type) {
+	case map[string]any:
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range sortedKeys(v) {
+			child := tomlPath(path, key)
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			keyNode.Line, keyNode.Column = t.keys[child].line, t.keys[child].column
+			node.Content = append(node.Content, keyNode, t.node(v[key], child))
+		}
+	case []any:
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for i, item := range v {
+			node.Content = append(node.Content, t.node(item, tomlPath(path, strconv.Itoa(i))))
+		}
+	case string:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case bool:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	case int64:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(v, 10)}
+	case float64:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: yamlFloat(v)}
+	case time.Time:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: v.Format(time.RFC3339Nano)}
+	default:
+		// Local dates and times have no YAML equivalent.
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+	}
+	node.Line, node.Column = t.values[path].line, t.values[path].column
+	return node
+}
+
+// yamlFloat returns the YAML representation of the given float.
+func yamlFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// marshalTOML returns the given configuration in TOML, through the values of
+// its YAML representation so that utterances with only a text are written as
+// strings. The keys of the tables are sorted.
+func marshalTOML(c *Config) ([]byte, error) {
+	var node yaml.Node
+	err := node.Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	err = node.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	return toml.Marshal(v)
+}
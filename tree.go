@@ -0,0 +1,275 @@
+package semanticrouter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// treeNode is a node of the tree of a router's routes and their children.
+type treeNode[T Float] struct {
+	route    *Route         // route is the route of the node, nil for the root.
+	centroid []T            // centroid is the mean embedding of the utterances of the subtree.
+	children []*treeNode[T] // children are the nodes of the children of the route.
+	routes   []Route        // routes are the children of the route.
+	// utterances indexes the embeddings of the children's own utterances, by
+	// position of the child.
+	utterances *index[T]
+}
+
+// hierarchical reports whether any of the given routes has children.
+func hierarchical(routes []Route) bool {
+	return slices.ContainsFunc(routes, func(r Route) bool {
+		return len(r.Children) > 0
+	})
+}
+
+// flatten returns the given routes and their descendants, depth first.
+func flatten(routes []Route) []*Route {
+	var flat []*Route
+	var walk func(routes []Route)
+	walk = func(routes []Route) {
+		for i := range routes {
+			flat = append(flat, &routes[i])
+			walk(routes[i].Children)
+		}
+	}
+	walk(routes)
+	return flat
+}
+
+// buildTree builds the tree of the given routes from the index entries of
+// the own utterances of each route and descendant. The index of the root
+// holds the entries of the given routes.
+func buildTree[T Float](
+	r *Router,
+	routes []Route,
+	own map[*Route][]indexEntry[T],
+) *treeNode[T] {
+	root, _, _ := newTreeNode(r, nil, routes, own)
+	return root
+}
+
+// quantize compresses the embeddings of the indexes of the node and of its
+// descendants with the given fitted quantizer, see index.quantize.
+func (n *treeNode[T]) quantize(quantizer Quantizer, candidates int) error {
+	if len(n.utterances.entries) > 0 {
+		err := n.utterances.quantize(quantizer, candidates)
+		if err != nil {
+			return err
+		}
+	}
+	for _, child := range n.children {
+		err := child.quantize(quantizer, candidates)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newTreeNode builds the node of the given route and children, returning it
+// with the sum and number of the embeddings of the utterances of its
+// subtree. The negative utterances are only used to score the children's
+// utterances.
+func newTreeNode[T Float](
+	r *Router,
+	route *Route,
+	children []Route,
+	own map[*Route][]indexEntry[T],
+) (*treeNode[T], []T, int) {
+	node := &treeNode[T]{
+		route:      route,
+		routes:     children,
+		utterances: &index[T]{routes: children},
+	}
+	var sum []T
+	count := 0
+	accumulate := func(embedding []T, n int) {
+		if len(embedding) == 0 {
+			return
+		}
+		if sum == nil {
+			sum = make([]T, len(embedding))
+		}
+		if len(embedding) != len(sum) {
+			return
+		}
+		for i, v := range embedding {
+			sum[i] += v
+		}
+		count += n
+	}
+	if route != nil {
+		for _, entry := range own[route] {
+			if !entry.negative {
+				accumulate(entry.embedding, 1)
+			}
+		}
+	}
+	for i := range children {
+		child, childSum, childCount := newTreeNode(r, &children[i], children[i].Children, own)
+		node.children = append(node.children, child)
+		accumulate(childSum, childCount)
+		for _, entry := range own[&children[i]] {
+			entry.route = i
+			node.utterances.entries = append(node.utterances.entries, entry)
+		}
+	}
+	prepareIndex(r, node.utterances, children)
+	if count > 0 {
+		node.centroid = make([]T, len(sum))
+		for i, v := range sum {
+			node.centroid[i] = v / T(count)
+		}
+	}
+	return node, sum, count
+}
+
+// path descends the tree from the given node towards the given query and
+// returns the routes of the path with the score of its last route, and the
+// utterance of the last route scoring best if it was scored by its
+// utterances.
+//
+// At each level, the children are scored by their centroids, unless none of
+// them has children of its own, in which case they are scored by their
+// utterances, and the child is chosen by the given function. The children
+// rejected by the filter of the context are not chosen. The descent stops
+// when no child is chosen.
+func (n *treeNode[T]) path(
+	ctx context.Context,
+	query []T,
+	text string,
+	coeffs []biFuncCoefficient,
+	pick func(c biFuncCoefficient) handler[T],
+	workers int,
+	choose func(scores []float64, routes []Route) (int, float64, error),
+) ([]*Route, float64, *Utterance, error) {
+	var path []*Route
+	var matched *Utterance
+	score := 0.0
+	for node := n; len(node.children) > 0; {
+		leaves := !slices.ContainsFunc(node.children, func(c *treeNode[T]) bool {
+			return len(c.children) > 0
+		})
+		var scores []float64
+		var hits []*Utterance
+		var err error
+		if leaves {
+			scores, hits, err = node.utterances.scores(ctx, query, text, coeffs, pick, workers, len(node.children))
+		} else {
+			scores, err = centroidScores(ctx, node.children, query, coeffs, pick)
+		}
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		best, bestScore, err := choose(scores, node.routes)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if best < 0 {
+			break
+		}
+		node = node.children[best]
+		path = append(path, node.route)
+		score = bestScore
+		matched = nil
+		if hits != nil {
+			matched = hits[best]
+		}
+	}
+	return path, score, matched, nil
+}
+
+// centroidScores returns the scores of the centroids of the given nodes
+// against the given query. The nodes whose route is rejected by the filter
+// of the given context score negative infinity.
+func centroidScores[T Float](
+	ctx context.Context,
+	nodes []*treeNode[T],
+	query []T,
+	coeffs []biFuncCoefficient,
+	pick func(c biFuncCoefficient) handler[T],
+) ([]float64, error) {
+	scores := emptyScores(len(nodes))
+	for i, node := range nodes {
+		if len(node.centroid) != len(query) || !accepts(ctx, node.route, nil) {
+			continue
+		}
+		score, err := computeScore(coeffs, pick, query, node.centroid)
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+// MatchPath returns the path of routes, from a top-level route to one of its
+// descendants, that matches the given utterance.
+//
+// Matching is coarse to fine: the top-level routes are scored by the
+// centroids of the utterances of their subtrees, then only the children of
+// the best one are scored, and so on. Routes whose children have no children
+// of their own are descended into by scoring their children's utterances.
+// The score is the score of the last route of the path, and the path is
+// empty if no route matches. If a rule of a route matches the utterance, the
+// path to the route is returned with a score of 1.
+func (r *Router) MatchPath(
+	ctx context.Context,
+	utterance string,
+) ([]*Route, float64, error) {
+	if path, rule := r.matchRules(ctx, r.Routes, utterance); rule != nil {
+		return path, 1, nil
+	}
+	if r.tree32 == nil && r.tree64 == nil {
+		result, err := r.match(ctx, utterance)
+		if err != nil || result.Route == nil {
+			return nil, result.Score, err
+		}
+		return []*Route{result.Route}, result.Score, nil
+	}
+	path, score, _, err := r.matchPath(ctx, utterance)
+	return path, score, err
+}
+
+// matchPath descends the router's tree towards the given utterance, without
+// matching rules, and returns the path with the utterance matched by its
+// last route, if any.
+func (r *Router) matchPath(
+	ctx context.Context,
+	utterance string,
+) ([]*Route, float64, *Utterance, error) {
+	switch {
+	case r.tree32 != nil:
+		return descend(ctx, r, r.tree32, r.codec32(), utterance)
+	case r.tree64 != nil:
+		return descend(ctx, r, r.tree64, r.codec64(), utterance)
+	default:
+		return nil, 0, nil, fmt.Errorf("router is not hierarchical")
+	}
+}
+
+// descend encodes the given utterance as a query and descends the given tree
+// towards it.
+func descend[T Float](
+	ctx context.Context,
+	r *Router,
+	tree *treeNode[T],
+	codec embeddingCodec[T],
+	utterance string,
+) ([]*Route, float64, *Utterance, error) {
+	encoding, err := codec.query(ctx, utterance)
+	if err != nil {
+		return nil, 0, nil, ErrEncoding{
+			Message: fmt.Sprintf(
+				"error encoding utterance: %s",
+				utterance,
+			),
+		}
+	}
+	choose := func(scores []float64, routes []Route) (int, float64, error) {
+		return r.pickRoute(scores, routes, utterance)
+	}
+	return tree.path(ctx, encoding, utterance, r.biFuncCoeffs, codec.pick, r.workers, choose)
+}
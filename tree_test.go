@@ -0,0 +1,131 @@
+package semanticrouter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conneroisu/semanticrouter-go"
+	"github.com/conneroisu/semanticrouter-go/stores/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMatchPath tests coarse-to-fine matching of hierarchical routes.
+func TestMatchPath(t *testing.T) {
+	vectors := map[string][]float64{
+		"my dog is sneezing":               {1, 0, 0, 0},
+		"my dog won't eat":                 {0.9, 0.1, 0, 0},
+		"my cat is limping":                {0.1, 0.9, 0, 0},
+		"my cat is hiding":                 {0, 1, 0, 0},
+		"my pet is sick":                   {0.7, 0.7, 0, 0},
+		"cancel my order":                  {0, 0, 1, 0},
+		"cancel the pet food subscription": {0.6, 0, 0.8, 0},
+		"where is my order":                {0, 0, 0, 1},
+		"track my package":                 {0, 0, 0.1, 0.9},
+		"my dog keeps coughing":            {0.95, 0.05, 0, 0.1},
+		"stop my dog food delivery":        {0.65, 0, 0.76, 0},
+		"how do I file my taxes?":          {0, 0, 0, 0},
+	}
+	utterances := func(texts ...string) []semanticrouter.Utterance {
+		result := make([]semanticrouter.Utterance, len(texts))
+		for i, text := range texts {
+			result[i] = semanticrouter.Utterance{Utterance: text}
+		}
+		return result
+	}
+	routes := []semanticrouter.Route{
+		{
+			Name:       "pets",
+			Utterances: utterances("my pet is sick"),
+			Children: []semanticrouter.Route{
+				{Name: "dogs", Utterances: utterances("my dog is sneezing", "my dog won't eat")},
+				{Name: "cats", Utterances: utterances("my cat is limping", "my cat is hiding")},
+			},
+		},
+		{
+			Name: "orders",
+			Children: []semanticrouter.Route{
+				{Name: "cancel", Utterances: utterances("cancel my order", "cancel the pet food subscription")},
+				{Name: "track", Utterances: utterances("where is my order", "track my package")},
+			},
+		},
+	}
+	for name, opts := range map[string][]semanticrouter.Option{
+		"float32":   {semanticrouter.WithPrecision(semanticrouter.Float32)},
+		"float64":   {semanticrouter.WithPrecision(semanticrouter.Float64)},
+		"quantized": {semanticrouter.WithQuantization(semanticrouter.NewBinaryQuantizer(), 4)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+			ctx := context.Background()
+			store := &countingStore{Store: memory.NewStore(), gets: make(map[string]int)}
+			router, err := semanticrouter.NewRouter(
+				routes,
+				&batchEncoder{vectors: vectors},
+				store,
+				append(opts, semanticrouter.WithSimilarityDotMatrix(1.0))...,
+			)
+			require.NoError(t, err)
+			for key, gets := range store.gets {
+				a.Equal(1, gets, "the embedding of %q is loaded once", key)
+			}
+			names := func(path []*semanticrouter.Route) []string {
+				var result []string
+				for _, route := range path {
+					result = append(result, route.Name)
+				}
+				return result
+			}
+			for utterance, expected := range map[string][]string{
+				"my dog keeps coughing":     {"pets", "dogs"},
+				"stop my dog food delivery": {"orders", "cancel"},
+				"track my package":          {"orders", "track"},
+				"how do I file my taxes?":   nil,
+			} {
+				path, score, err := router.MatchPath(ctx, utterance)
+				require.NoError(t, err)
+				a.Equal(expected, names(path), utterance)
+				if expected != nil {
+					a.Greater(score, 0.9, utterance)
+				}
+			}
+			route, _, err := router.Match(ctx, "my cat is hiding")
+			require.NoError(t, err)
+			a.Equal("cats", route.Name)
+
+			noDogs := semanticrouter.WithFilter(ctx, func(route *semanticrouter.Route, _ *semanticrouter.Utterance) bool {
+				return route.Name != "dogs"
+			})
+			path, _, err := router.MatchPath(noDogs, "my dog keeps coughing")
+			require.NoError(t, err)
+			a.NotContains(names(path), "dogs")
+		})
+	}
+}
+
+// countingStore is a store counting the gets of each key.
+type countingStore struct {
+	semanticrouter.Store
+	gets map[string]int
+}
+
+// Get counts the get of the given key and gets it from the store.
+func (s *countingStore) Get(ctx context.Context, key string) ([]float64, error) {
+	s.gets[key]++
+	return s.Store.Get(ctx, key)
+}
+
+// TestMatchPathFlat tests that the path of a flat router is its match.
+func TestMatchPathFlat(t *testing.T) {
+	router, err := semanticrouter.NewRouter(
+		[]semanticrouter.Route{NoteworthyRoutes, ChitchatRoutes},
+		&batchEncoder{vectors: dispatchVectors},
+		memory.NewStore(),
+		semanticrouter.WithSimilarityDotMatrix(1.0),
+	)
+	require.NoError(t, err)
+	path, _, err := router.MatchPath(context.Background(), "my dog is sneezing")
+	require.NoError(t, err)
+	require.Len(t, path, 1)
+	assert.Equal(t, "noteworthy", path[0].Name)
+}